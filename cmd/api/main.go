@@ -1,19 +1,80 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"strings"
+	"time"
 
+	"github.com/brianantony456/go-doc/internal/infrastructure/gin_handler"
+	"github.com/brianantony456/go-doc/internal/infrastructure/sip2"
 	"github.com/brianantony456/go-doc/internal/router"
 )
 
 func main() {
-	r, _, err := router.SetupRouter()
+	sip2Addr := flag.String("sip2", "", "address for the SIP2 self-check listener, e.g. :6001 (disabled when empty)")
+	sip2User := flag.String("sip2-user", "", "login user SIP2 kiosks must send (no login required when empty)")
+	sip2Password := flag.String("sip2-password", "", "login password for SIP2 kiosks")
+	institution := flag.String("institution", "go-doc", "institution id reported to SIP2 kiosks")
+	queryTimeout := flag.Duration("query-timeout", 5*time.Second, "longest a database query may run (0 for no limit)")
+	cacheTTL := flag.Duration("cache-ttl", 30*time.Second, "how long book reads are cached (0 disables the cache)")
+	cacheSize := flag.Int("cache-size", 1000, "most books kept in the cache")
+	tenantsFile := flag.String("tenants", "", "JSON file listing the libraries served (single library when empty)")
+	sip2Tenant := flag.String("sip2-tenant", "", "library whose books SIP2 kiosks circulate (default tenant when empty)")
+	maxBody := flag.Int64("max-body", 4<<20, "largest request body accepted, in bytes (0 for no limit)")
+	idempotencyWindow := flag.Duration("idempotency-window", 24*time.Hour, "how long responses are kept for retries sent with the same Idempotency-Key (0 ignores the header)")
+	unversionedSunset := flag.String("unversioned-sunset", "", "date (YYYY-MM-DD) after which the unversioned /api routes answer 410 (kept when empty)")
+	adminKeys := flag.String("admin-keys", "", "comma-separated bearer tokens that open /api/admin (admin routes closed when empty)")
+	flag.Parse()
+
+	var sunset time.Time
+	if *unversionedSunset != "" {
+		var err error
+		if sunset, err = time.Parse("2006-01-02", *unversionedSunset); err != nil {
+			log.Fatal("Invalid -unversioned-sunset:", err)
+		}
+	}
+
+	var tenants []gin_handler.Tenant
+	if *tenantsFile != "" {
+		var err error
+		if tenants, err = router.LoadTenants(*tenantsFile); err != nil {
+			log.Fatal("Failed to load tenants:", err)
+		}
+	}
+
+	r, services, err := router.SetupRouter(router.Config{
+		QueryTimeout:      *queryTimeout,
+		CacheTTL:          *cacheTTL,
+		CacheSize:         *cacheSize,
+		Tenants:           tenants,
+		MaxBodyBytes:      *maxBody,
+		IdempotencyWindow: *idempotencyWindow,
+		UnversionedSunset: sunset,
+		AdminKeys:         strings.FieldsFunc(*adminKeys, func(r rune) bool { return r == ',' }),
+	})
 	if err != nil {
 		log.Fatal("Failed to set up router and connect to database:", err)
 	}
 
 	// Defer closing the database connection if necessary (depending on the database driver)
-	// defer db.Close()
+	// defer services.DB.Close()
+
+	if *sip2Addr != "" {
+		sipServer := sip2.NewServer(services.Books, services.LoanService, sip2.Config{
+			Username:      *sip2User,
+			Password:      *sip2Password,
+			InstitutionID: *institution,
+			LibraryName:   "go-doc library",
+			TenantID:      *sip2Tenant,
+		})
+		go func() {
+			log.Printf("SIP2 listening on %s", *sip2Addr)
+			if err := sipServer.ListenAndServe(*sip2Addr); err != nil {
+				log.Fatalf("Failed to run SIP2 server: %v", err)
+			}
+		}()
+	}
 
 	if err := r.Run("localhost:8000"); err != nil {
 		log.Fatalf("Failed to run server: %v", err)
@@ -1 +1,49 @@
 package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+	"github.com/brianantony456/go-doc/internal/infrastructure/gin_handler"
+	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+	"github.com/brianantony456/go-doc/internal/router"
+	"github.com/gin-gonic/gin"
+)
+
+// Sandbox API backed by the in-memory repository: nothing is written to
+// books.db and every run starts from the same few books.
+func main() {
+	bookRepo := persistence.NewMemoryBookRepository()
+	ledger := persistence.NewMemoryLedgerRepository()
+	auditLog := persistence.NewMemoryAuditRepository()
+	audit := service.NewAuditService(auditLog)
+	merges := persistence.NewMemoryMergeRepository()
+	loans := persistence.NewMemoryLoanRepository()
+	bookService := service.NewBookService(bookRepo, ledger, audit, persistence.NewMemoryUnitOfWork(bookRepo, ledger, auditLog, merges, loans))
+	for _, book := range []model.Book{
+		{ID: "1", Title: "The Go Programming Language", Author: "Alan Donovan", Quantity: 3},
+		{ID: "2", Title: "Concurrency in Go", Author: "Katherine Cox-Buday", Quantity: 1},
+		{ID: "3", Title: "Learning Go", Author: "Jon Bodner", Quantity: 0},
+	} {
+		if err := bookService.Create(service.WithActor(context.Background(), "seed"), book); err != nil {
+			log.Fatalf("Failed to seed book %s: %v", book.ID, err)
+		}
+	}
+
+	r := gin.Default()
+	r.NoRoute(gin_handler.RouteNotFound)
+	apiRoutes := r.Group("/api")
+	apiRoutes.Use(gin_handler.Idempotency(service.NewIdempotencyService(persistence.NewMemoryIdempotencyRepository(), 24*time.Hour), apiRoutes.BasePath()))
+	router.RegisterAPIRoutes(apiRoutes, gin_handler.NewBookHandler(bookRepo, bookService, service.NewHistoryService(bookRepo)))
+	router.RegisterLoanRoutes(apiRoutes, gin_handler.NewLoanHandler(service.NewLoanService(bookService, loans)))
+	router.RegisterMergeRoutes(apiRoutes, gin_handler.NewMergeHandler(service.NewMergeService(bookService, merges, loans)))
+	// The admin routes take Authorization: Bearer sandbox
+	router.RegisterAdminRoutes(r.Group("/api/admin"), []string{"sandbox"}, gin_handler.NewAuditHandler(audit))
+
+	if err := r.Run("localhost:8001"); err != nil {
+		log.Fatalf("Failed to run server: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+// Command migrate applies, reverts and lists schema migrations:
+//
+//	migrate [-db books.db] up
+//	migrate [-db books.db] down [steps]
+//	migrate [-db books.db] status
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+	"gorm.io/gorm"
+)
+
+func main() {
+	dsn := flag.String("db", "books.db", "SQLite database to migrate")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-db path] up|down [steps]|status\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, err := gorm.Open(persistence.OpenSQLite(*dsn), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *dsn, err)
+	}
+
+	switch flag.Arg(0) {
+	case "up":
+		err = persistence.MigrateUp(db)
+	case "down":
+		steps := 1
+		if flag.NArg() > 1 {
+			if steps, err = strconv.Atoi(flag.Arg(1)); err != nil || steps < 1 {
+				log.Fatalf("Invalid number of steps %q", flag.Arg(1))
+			}
+		}
+		err = persistence.MigrateDown(db, steps)
+	case "status":
+		err = printStatus(db)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func printStatus(db *gorm.DB) error {
+	version, err := persistence.SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	statuses, err := persistence.MigrationStatuses(db)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("schema version %d (latest %d)\n", version, persistence.LatestSchemaVersion())
+	for _, s := range statuses {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%4d  %-24s %s\n", s.Version, s.Name, applied)
+	}
+	return nil
+}
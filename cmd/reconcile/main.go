@@ -0,0 +1,52 @@
+// Command reconcile recomputes every book's quantity from the inventory
+// ledger and lists the books that have drifted from it:
+//
+//	reconcile [-db books.db] [-tenant default] [-fix]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+	"gorm.io/gorm"
+)
+
+func main() {
+	dsn := flag.String("db", "books.db", "SQLite database to reconcile")
+	fix := flag.Bool("fix", false, "reset drifted quantities to the ledger balance")
+	tenant := flag.String("tenant", repository.DefaultTenant, "library whose books are reconciled")
+	flag.Parse()
+
+	db, err := gorm.Open(persistence.OpenSQLite(*dsn), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *dsn, err)
+	}
+	if err := persistence.MigrateUp(db); err != nil {
+		log.Fatal(err)
+	}
+
+	books := service.NewBookService(persistence.NewGormBookRepository(db), persistence.NewGormLedgerRepository(db), service.NewAuditService(persistence.NewGormAuditRepository(db)), persistence.NewGormUnitOfWork(db))
+	drifts, err := books.Reconcile(repository.WithTenant(service.WithActor(context.Background(), "reconcile"), *tenant), *fix)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(drifts) == 0 {
+		fmt.Println("all quantities match the ledger")
+		return
+	}
+
+	for _, d := range drifts {
+		fmt.Printf("%s: quantity %d, ledger %d (drift %+d)\n", d.BookID, d.Quantity, d.LedgerBalance, d.Quantity-d.LedgerBalance)
+	}
+	if *fix {
+		fmt.Printf("reset %d books to their ledger balance\n", len(drifts))
+		return
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// RawJSON is a JSON document stored as text and written out as is.
+type RawJSON string
+
+func (j RawJSON) MarshalJSON() ([]byte, error) {
+	if j == "" {
+		return []byte("null"), nil
+	}
+	return []byte(j), nil
+}
+
+func (j *RawJSON) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*j = ""
+		return nil
+	}
+	*j = RawJSON(data)
+	return nil
+}
+
+// AuditEntry records one change made through the API. Entries form a hash
+// chain: each Hash covers the entry and the Hash of the entry before it, so
+// editing or deleting an entry breaks every hash after it. Each tenant has a
+// chain of its own.
+type AuditEntry struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	TenantID   string    `json:"-"`
+	Actor      string    `json:"actor"`
+	Credential string    `json:"credential,omitempty"`
+	Action     string    `json:"action"`
+	EntityType string    `json:"entity_type"`
+	EntityID   string    `json:"entity_id"`
+	Before     RawJSON   `json:"before" gorm:"column:before_json"`
+	After      RawJSON   `json:"after" gorm:"column:after_json"`
+	Diff       RawJSON   `json:"diff"`
+	ClientIP   string    `json:"client_ip,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	PrevHash   string    `json:"prev_hash"`
+	Hash       string    `json:"hash"`
+}
+
+// ComputeHash returns the hash the entry should have given its PrevHash.
+// The credential is only covered when there is one, so entries written
+// before credentials were recorded keep their hashes.
+func (e AuditEntry) ComputeHash() string {
+	fields := []string{
+		e.Actor, e.Action, e.EntityType, e.EntityID,
+		string(e.Before), string(e.After), string(e.Diff),
+		e.ClientIP, e.RequestID, e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+	if e.Credential != "" {
+		fields = append(fields, e.Credential)
+	}
+	content, _ := json.Marshal(fields)
+	sum := sha256.Sum256(append([]byte(e.PrevHash+"\n"), content...))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,8 +1,27 @@
 package model
 
+import "time"
+
+// Book is a title in the catalogue. The binding rules apply to books sent
+// to the API; imported records are taken as they come. Read-only fields are
+// ignored in requests.
 type Book struct {
-	ID       string `json:"id" gorm:"primaryKey"`
-	Title    string `json:"title"`
-	Author   string `json:"author"`
-	Quantity int    `json:"quantity"`
+	// TenantID is set by the repository from the caller's context
+	TenantID  string `json:"-" gorm:"primaryKey"`
+	ID        string `json:"id" gorm:"primaryKey" binding:"max=64"`
+	Title     string `json:"title" binding:"required,max=500"`
+	Author    string `json:"author" binding:"required,max=200"`
+	Quantity  int    `json:"quantity" binding:"min=0"`
+	ISBN      string `json:"isbn,omitempty" binding:"omitempty,isbn"`
+	Publisher string `json:"publisher,omitempty" binding:"max=200"`
+	Year      string `json:"year,omitempty" binding:"omitempty,numeric,len=4"`
+
+	// CreatedAt and UpdatedAt are set by the repository. UpdatedAt is the
+	// OAI-PMH datestamp, so clients must not be able to backdate it.
+	CreatedAt time.Time `json:"created_at" readonly:"true"`
+	UpdatedAt time.Time `json:"updated_at" readonly:"true"`
+
+	// MarcRecord holds the original MARCXML record of an imported book so
+	// fields we don't map are kept on export.
+	MarcRecord string `json:"-"`
 }
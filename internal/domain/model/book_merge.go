@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// BookMerge records a duplicate book folded into the book that survived,
+// keeping the duplicate as it was so the merge can be undone.
+type BookMerge struct {
+	ID          uint    `json:"id" gorm:"primaryKey"`
+	TenantID    string  `json:"-"`
+	SurvivorID  string  `json:"survivor_id"`
+	DuplicateID string  `json:"duplicate_id"`
+	Duplicate   RawJSON `json:"duplicate"`
+	// DuplicateMarcRecord is kept apart since books leave it out of JSON
+	DuplicateMarcRecord string     `json:"-"`
+	Copies              int        `json:"copies"`
+	Actor               string     `json:"actor,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UndoneAt            *time.Time `json:"undone_at,omitempty"`
+}
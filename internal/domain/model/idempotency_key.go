@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// IdempotencyKey is a client's Idempotency-Key with the first response sent
+// for it, replayed when the request is retried. Status is zero while that
+// first request is still running.
+type IdempotencyKey struct {
+	TenantID        string `gorm:"primaryKey"`
+	Key             string `gorm:"primaryKey"`
+	Fingerprint     string
+	Status          int
+	ContentType     string
+	Location        string
+	ContentLocation string
+	Body            []byte
+	CreatedAt       time.Time
+	ExpiresAt       time.Time
+}
@@ -0,0 +1,40 @@
+package model
+
+import "strings"
+
+// ValidISBN reports whether s is an ISBN-10 or ISBN-13 with a correct check
+// digit. Hyphens and spaces between the digits are ignored.
+func ValidISBN(s string) bool {
+	digits := strings.NewReplacer("-", "", " ", "").Replace(s)
+	switch len(digits) {
+	case 10:
+		sum := 0
+		for i, r := range digits {
+			var d int
+			switch {
+			case r >= '0' && r <= '9':
+				d = int(r - '0')
+			case (r == 'X' || r == 'x') && i == 9:
+				d = 10
+			default:
+				return false
+			}
+			sum += (10 - i) * d
+		}
+		return sum%11 == 0
+	case 13:
+		sum := 0
+		for i, r := range digits {
+			if r < '0' || r > '9' {
+				return false
+			}
+			weight := 1
+			if i%2 == 1 {
+				weight = 3
+			}
+			sum += weight * int(r-'0')
+		}
+		return sum%10 == 0
+	}
+	return false
+}
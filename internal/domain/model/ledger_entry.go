@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// Reasons a book's quantity changed.
+const (
+	ReasonCreate         = "create"
+	ReasonImport         = "import"
+	ReasonCheckout       = "checkout"
+	ReasonReturn         = "return"
+	ReasonAdjustment     = "adjustment"
+	ReasonOpeningBalance = "opening_balance"
+	ReasonMerge          = "merge"
+	ReasonUnmerge        = "unmerge"
+)
+
+// LedgerEntry records one change to a book's quantity. Entries are only
+// ever appended, so summing a book's deltas gives the quantity it should
+// have. The one exception is a merge, which moves the duplicate's entries to
+// the survivor along with its copies.
+type LedgerEntry struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	TenantID  string `json:"-"`
+	BookID    string `json:"book_id"`
+	Delta     int    `json:"delta"`
+	Reason    string `json:"reason"`
+	Actor     string `json:"actor,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	// MergeID is the merge that moved the entry from the duplicate to the
+	// survivor, so undoing it can move the entry back.
+	MergeID   *uint     `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Loan is one copy of a book lent out. It stays open until ReturnedAt is
+// set.
+type Loan struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	TenantID string `json:"-"`
+	BookID   string `json:"book_id"`
+	Actor    string `json:"actor,omitempty"`
+	// Patron is who borrowed the copy, when the desk that lent it knows.
+	Patron string `json:"patron,omitempty"`
+	// MergeID is the merge that moved the loan from the duplicate to the
+	// survivor, so undoing it can move the loan back.
+	MergeID    *uint      `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RenewedAt  *time.Time `json:"renewed_at,omitempty"`
+	ReturnedAt *time.Time `json:"returned_at,omitempty"`
+}
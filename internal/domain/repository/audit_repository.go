@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+)
+
+// AuditFilter selects audit entries; zero fields match everything.
+type AuditFilter struct {
+	Actor      string
+	Action     string
+	EntityType string
+	EntityID   string
+	RequestID  string
+	From       time.Time
+	To         time.Time
+	// AfterID continues a listing after the entry with that ID.
+	AfterID uint
+	Limit   int
+}
+
+// AuditRepository is the append-only audit log.
+type AuditRepository interface {
+	// Append sets the entry's time and chains it to the last entry.
+	Append(ctx context.Context, entry model.AuditEntry) error
+	// List returns matching entries, oldest first.
+	List(ctx context.Context, filter AuditFilter) ([]model.AuditEntry, error)
+}
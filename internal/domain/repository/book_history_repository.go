@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+)
+
+// BookHistoryRepository reconstructs books as they were at an earlier
+// instant from the versions saved on every write.
+type BookHistoryRepository interface {
+	// GetAllAsOf returns the books that existed at t, in insertion order.
+	GetAllAsOf(ctx context.Context, t time.Time) ([]model.Book, error)
+	// GetByIDAsOf returns ErrBookNotFound if the book didn't exist yet at t.
+	GetByIDAsOf(ctx context.Context, id string, t time.Time) (*model.Book, error)
+	// Deletions returns the books that were deleted and haven't been created
+	// again since, oldest deletion first.
+	Deletions(ctx context.Context) ([]Deletion, error)
+}
+
+// Deletion is the tombstone of a deleted book.
+type Deletion struct {
+	BookID    string
+	DeletedAt time.Time
+}
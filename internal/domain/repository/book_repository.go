@@ -1,10 +1,26 @@
 package repository
 
-import "github.com/brianantony456/go-doc/internal/domain/model"
+import (
+	"context"
+	"errors"
 
+	"github.com/brianantony456/go-doc/internal/domain/model"
+)
+
+var (
+	ErrBookNotFound = errors.New("book not found")
+	ErrBookExists   = errors.New("book already exists")
+)
+
+// BookRepository stores books. Every method gives up with the context's
+// error once ctx is cancelled or its deadline passes.
 type BookRepository interface {
-	GetAll() ([]model.Book, error)
-	GetByID(id string) (*model.Book, error)
-	Create(book model.Book) error
-	Update(book model.Book) error
+	GetAll(ctx context.Context) ([]model.Book, error)
+	GetByID(ctx context.Context, id string) (*model.Book, error)
+	// Create stamps the book's CreatedAt and UpdatedAt with the current
+	// time, whatever the book had.
+	Create(ctx context.Context, book model.Book) error
+	Update(ctx context.Context, book model.Book) error
+	// Delete returns ErrBookNotFound if there is no such book.
+	Delete(ctx context.Context, id string) error
 }
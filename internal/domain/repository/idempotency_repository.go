@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+)
+
+var (
+	ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+	ErrIdempotencyKeyExists   = errors.New("idempotency key already used")
+)
+
+type IdempotencyRepository interface {
+	// Create fails with ErrIdempotencyKeyExists when the key is taken.
+	Create(ctx context.Context, key model.IdempotencyKey) error
+	Get(ctx context.Context, key string) (*model.IdempotencyKey, error)
+	Update(ctx context.Context, key model.IdempotencyKey) error
+	Delete(ctx context.Context, key string) error
+	// DeleteExpired removes the keys of every tenant that expired by now.
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
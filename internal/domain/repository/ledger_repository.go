@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+)
+
+// LedgerRepository is the append-only inventory ledger. Entries only change
+// book when a merge moves them.
+type LedgerRepository interface {
+	Append(ctx context.Context, entry model.LedgerEntry) error
+	// MoveBook moves every entry of one book to another, tagged with the
+	// merge that moved it.
+	MoveBook(ctx context.Context, fromID, toID string, mergeID uint) error
+	// MoveBack returns the entries a merge moved to the book they came from.
+	MoveBack(ctx context.Context, mergeID uint, bookID string) error
+	// ListByBook returns a book's entries, oldest first.
+	ListByBook(ctx context.Context, bookID string) ([]model.LedgerEntry, error)
+	// Balances sums the deltas of every book with entries.
+	Balances(ctx context.Context) (map[string]int, error)
+}
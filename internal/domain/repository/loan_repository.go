@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+)
+
+var ErrLoanNotFound = errors.New("loan not found")
+
+type LoanRepository interface {
+	// Create sets the ID of the new loan.
+	Create(ctx context.Context, loan *model.Loan) error
+	GetByID(ctx context.Context, id uint) (*model.Loan, error)
+	// ListByBook returns the loans of a book, oldest first.
+	ListByBook(ctx context.Context, bookID string) ([]model.Loan, error)
+	// ListByMerge returns the loans a merge moved, oldest first.
+	ListByMerge(ctx context.Context, mergeID uint) ([]model.Loan, error)
+	Update(ctx context.Context, loan model.Loan) error
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+)
+
+var ErrMergeNotFound = errors.New("merge not found")
+
+type MergeRepository interface {
+	// Create sets the ID of the new merge.
+	Create(ctx context.Context, merge *model.BookMerge) error
+	GetByID(ctx context.Context, id uint) (*model.BookMerge, error)
+	// List returns every merge, oldest first.
+	List(ctx context.Context) ([]model.BookMerge, error)
+	Update(ctx context.Context, merge model.BookMerge) error
+}
@@ -0,0 +1,303 @@
+// Package repositorytest holds the behaviour every repository.BookRepository
+// implementation must share, so a new backend can prove it is a drop-in
+// replacement for GormBookRepository.
+package repositorytest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory returns a new, empty repository for each subtest.
+type Factory func(t *testing.T) repository.BookRepository
+
+// TestBookRepository runs the conformance suite against the repositories
+// made by newRepo.
+func TestBookRepository(t *testing.T, newRepo Factory) {
+	t.Run("CreateAndGetByID", func(t *testing.T) { testCreateAndGetByID(t, newRepo(t)) })
+	t.Run("GetByIDNotFound", func(t *testing.T) { testGetByIDNotFound(t, newRepo(t)) })
+	t.Run("CreateDuplicate", func(t *testing.T) { testCreateDuplicate(t, newRepo(t)) })
+	t.Run("GetAllEmpty", func(t *testing.T) { testGetAllEmpty(t, newRepo(t)) })
+	t.Run("GetAllInsertionOrder", func(t *testing.T) { testGetAllInsertionOrder(t, newRepo(t)) })
+	t.Run("Update", func(t *testing.T) { testUpdate(t, newRepo(t)) })
+	t.Run("UpdateInsertsMissing", func(t *testing.T) { testUpdateInsertsMissing(t, newRepo(t)) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, newRepo(t)) })
+	t.Run("Timestamps", func(t *testing.T) { testTimestamps(t, newRepo(t)) })
+	t.Run("ReturnsCopies", func(t *testing.T) { testReturnsCopies(t, newRepo(t)) })
+	t.Run("ConcurrentCreates", func(t *testing.T) { testConcurrentCreates(t, newRepo(t)) })
+	t.Run("ConcurrentDuplicateCreates", func(t *testing.T) { testConcurrentDuplicateCreates(t, newRepo(t)) })
+	t.Run("TenantIsolation", func(t *testing.T) { testTenantIsolation(t, newRepo(t)) })
+	t.Run("CancelledContext", func(t *testing.T) { testCancelledContext(t, newRepo(t)) })
+}
+
+func sampleBook(id string) model.Book {
+	return model.Book{
+		ID:        id,
+		Title:     "Title " + id,
+		Author:    "Author " + id,
+		Quantity:  3,
+		ISBN:      "9780000000" + id,
+		Publisher: "Publisher",
+		Year:      "2001",
+	}
+}
+
+func testCreateAndGetByID(t *testing.T, repo repository.BookRepository) {
+	ctx := context.Background()
+	book := sampleBook("1")
+	require.NoError(t, repo.Create(ctx, book))
+
+	found, err := repo.GetByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, book.ID, found.ID)
+	assert.Equal(t, book.Title, found.Title)
+	assert.Equal(t, book.Author, found.Author)
+	assert.Equal(t, book.Quantity, found.Quantity)
+	assert.Equal(t, book.ISBN, found.ISBN)
+	assert.Equal(t, book.Publisher, found.Publisher)
+	assert.Equal(t, book.Year, found.Year)
+}
+
+func testGetByIDNotFound(t *testing.T, repo repository.BookRepository) {
+	ctx := context.Background()
+	book, err := repo.GetByID(ctx, "missing")
+	assert.Nil(t, book)
+	assert.ErrorIs(t, err, repository.ErrBookNotFound)
+	assert.Equal(t, "book not found", err.Error())
+}
+
+func testCreateDuplicate(t *testing.T, repo repository.BookRepository) {
+	ctx := context.Background()
+	require.NoError(t, repo.Create(ctx, sampleBook("1")))
+
+	duplicate := sampleBook("1")
+	duplicate.Title = "Other"
+	assert.ErrorIs(t, repo.Create(ctx, duplicate), repository.ErrBookExists)
+
+	found, err := repo.GetByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "Title 1", found.Title, "a rejected create must not change the stored book")
+}
+
+func testGetAllEmpty(t *testing.T, repo repository.BookRepository) {
+	ctx := context.Background()
+	books, err := repo.GetAll(ctx)
+	require.NoError(t, err)
+	assert.NotNil(t, books, "an empty catalogue is an empty list, not nil")
+	assert.Empty(t, books)
+}
+
+func testGetAllInsertionOrder(t *testing.T, repo repository.BookRepository) {
+	ctx := context.Background()
+	ids := []string{"b", "c", "a", "10", "2"}
+	for _, id := range ids {
+		require.NoError(t, repo.Create(ctx, sampleBook(id)))
+	}
+	// Updating must not move a book
+	book, err := repo.GetByID(ctx, "c")
+	require.NoError(t, err)
+	book.Quantity = 0
+	require.NoError(t, repo.Update(ctx, *book))
+
+	books, err := repo.GetAll(ctx)
+	require.NoError(t, err)
+	var got []string
+	for _, b := range books {
+		got = append(got, b.ID)
+	}
+	assert.Equal(t, ids, got)
+}
+
+func testUpdate(t *testing.T, repo repository.BookRepository) {
+	ctx := context.Background()
+	require.NoError(t, repo.Create(ctx, sampleBook("1")))
+
+	book, err := repo.GetByID(ctx, "1")
+	require.NoError(t, err)
+	book.Title = "New title"
+	book.Quantity = 0
+	require.NoError(t, repo.Update(ctx, *book))
+
+	updated, err := repo.GetByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "New title", updated.Title)
+	assert.Equal(t, 0, updated.Quantity)
+
+	books, err := repo.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, books, 1)
+}
+
+func testUpdateInsertsMissing(t *testing.T, repo repository.BookRepository) {
+	ctx := context.Background()
+	require.NoError(t, repo.Update(ctx, sampleBook("1")))
+
+	found, err := repo.GetByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "Title 1", found.Title)
+	assert.False(t, found.CreatedAt.IsZero())
+}
+
+func testDelete(t *testing.T, repo repository.BookRepository) {
+	ctx := context.Background()
+	require.NoError(t, repo.Create(ctx, sampleBook("1")))
+	require.NoError(t, repo.Create(ctx, sampleBook("2")))
+
+	require.NoError(t, repo.Delete(ctx, "1"))
+	_, err := repo.GetByID(ctx, "1")
+	assert.ErrorIs(t, err, repository.ErrBookNotFound)
+	books, err := repo.GetAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	assert.Equal(t, "2", books[0].ID)
+
+	assert.ErrorIs(t, repo.Delete(ctx, "1"), repository.ErrBookNotFound)
+	require.NoError(t, repo.Create(ctx, sampleBook("1")), "a deleted ID can be used again")
+}
+
+func testTimestamps(t *testing.T, repo repository.BookRepository) {
+	ctx := context.Background()
+	before := time.Now().Add(-time.Second)
+	require.NoError(t, repo.Create(ctx, sampleBook("1")))
+
+	created, err := repo.GetByID(ctx, "1")
+	require.NoError(t, err)
+	assert.True(t, created.CreatedAt.After(before))
+	assert.True(t, created.UpdatedAt.Equal(created.CreatedAt))
+
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, repo.Update(ctx, *created))
+	updated, err := repo.GetByID(ctx, "1")
+	require.NoError(t, err)
+	assert.True(t, updated.CreatedAt.Equal(created.CreatedAt), "update keeps CreatedAt")
+	assert.True(t, updated.UpdatedAt.After(created.UpdatedAt), "update moves UpdatedAt")
+}
+
+func testReturnsCopies(t *testing.T, repo repository.BookRepository) {
+	ctx := context.Background()
+	require.NoError(t, repo.Create(ctx, sampleBook("1")))
+
+	found, err := repo.GetByID(ctx, "1")
+	require.NoError(t, err)
+	found.Title = "changed"
+	books, err := repo.GetAll(ctx)
+	require.NoError(t, err)
+	books[0].Quantity = 99
+
+	again, err := repo.GetByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "Title 1", again.Title)
+	assert.Equal(t, 3, again.Quantity)
+}
+
+func testConcurrentCreates(t *testing.T, repo repository.BookRepository) {
+	ctx := context.Background()
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("book-%d", i)
+			assert.NoError(t, repo.Create(ctx, sampleBook(id)))
+			_, err := repo.GetByID(ctx, id)
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	books, err := repo.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, books, n)
+}
+
+func testConcurrentDuplicateCreates(t *testing.T, repo repository.BookRepository) {
+	ctx := context.Background()
+	const n = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- repo.Create(ctx, sampleBook("1"))
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		if err == nil {
+			created++
+			continue
+		}
+		assert.ErrorIs(t, err, repository.ErrBookExists)
+	}
+	assert.Equal(t, 1, created, "exactly one concurrent create of the same ID wins")
+}
+
+func testTenantIsolation(t *testing.T, repo repository.BookRepository) {
+	north := repository.WithTenant(context.Background(), "north")
+	south := repository.WithTenant(context.Background(), "south")
+	require.NoError(t, repo.Create(north, sampleBook("1")))
+	require.NoError(t, repo.Create(north, sampleBook("2")))
+
+	_, err := repo.GetByID(south, "1")
+	assert.ErrorIs(t, err, repository.ErrBookNotFound)
+	books, err := repo.GetAll(south)
+	require.NoError(t, err)
+	assert.Empty(t, books)
+	assert.ErrorIs(t, repo.Delete(south, "2"), repository.ErrBookNotFound)
+
+	// The same ID can be used by each tenant, and writing one leaves the
+	// other alone
+	southern := sampleBook("1")
+	southern.Title = "Southern"
+	require.NoError(t, repo.Create(south, southern))
+	southern.Quantity = 9
+	require.NoError(t, repo.Update(south, southern))
+
+	found, err := repo.GetByID(north, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "Title 1", found.Title)
+	assert.Equal(t, 3, found.Quantity)
+	books, err = repo.GetAll(north)
+	require.NoError(t, err)
+	assert.Len(t, books, 2)
+	books, err = repo.GetAll(south)
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	assert.Equal(t, "Southern", books[0].Title)
+
+	_, err = repo.GetByID(context.Background(), "1")
+	assert.ErrorIs(t, err, repository.ErrBookNotFound, "no tenant means the default tenant")
+}
+
+func testCancelledContext(t *testing.T, repo repository.BookRepository) {
+	require.NoError(t, repo.Create(context.Background(), sampleBook("1")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.GetAll(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.GetByID(ctx, "1")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, repo.Create(ctx, sampleBook("2")), context.Canceled)
+	assert.ErrorIs(t, repo.Update(ctx, sampleBook("1")), context.Canceled)
+	assert.ErrorIs(t, repo.Delete(ctx, "1"), context.Canceled)
+
+	books, err := repo.GetAll(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, books, 1, "cancelled writes must not be stored")
+}
@@ -0,0 +1,150 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// UnitOfWorkFactory returns a unit of work over an empty store together
+// with a book repository that joins it.
+type UnitOfWorkFactory func(t *testing.T) (repository.UnitOfWork, repository.BookRepository)
+
+// TestUnitOfWork checks commit, rollback and nesting of the units of work
+// made by newUnit.
+func TestUnitOfWork(t *testing.T, newUnit UnitOfWorkFactory) {
+	run := func(name string, test func(*testing.T, repository.UnitOfWork, repository.BookRepository)) {
+		t.Run(name, func(t *testing.T) {
+			uow, repo := newUnit(t)
+			test(t, uow, repo)
+		})
+	}
+	run("Commit", testUnitCommit)
+	run("RollbackOnError", testUnitRollbackOnError)
+	run("RollbackOnPanic", testUnitRollbackOnPanic)
+	run("NestedInnerFailure", testUnitNestedInnerFailure)
+	run("NestedOuterFailure", testUnitNestedOuterFailure)
+}
+
+var errAbort = errors.New("abort")
+
+func assertBookIDs(t *testing.T, repo repository.BookRepository, ids ...string) {
+	t.Helper()
+	books, err := repo.GetAll(context.Background())
+	require.NoError(t, err)
+	got := []string{}
+	for _, b := range books {
+		got = append(got, b.ID)
+	}
+	if ids == nil {
+		ids = []string{}
+	}
+	assert.Equal(t, ids, got)
+}
+
+func testUnitCommit(t *testing.T, uow repository.UnitOfWork, repo repository.BookRepository) {
+	err := uow.Do(context.Background(), func(ctx context.Context) error {
+		if err := repo.Create(ctx, sampleBook("1")); err != nil {
+			return err
+		}
+		book, err := repo.GetByID(ctx, "1")
+		if err != nil {
+			return err
+		}
+		book.Quantity = 1
+		if err := repo.Update(ctx, *book); err != nil {
+			return err
+		}
+		return repo.Create(ctx, sampleBook("2"))
+	})
+	require.NoError(t, err)
+
+	assertBookIDs(t, repo, "1", "2")
+	book, err := repo.GetByID(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, book.Quantity)
+}
+
+func testUnitRollbackOnError(t *testing.T, uow repository.UnitOfWork, repo repository.BookRepository) {
+	require.NoError(t, repo.Create(context.Background(), sampleBook("1")))
+
+	err := uow.Do(context.Background(), func(ctx context.Context) error {
+		book, err := repo.GetByID(ctx, "1")
+		if err != nil {
+			return err
+		}
+		book.Quantity = 0
+		if err := repo.Update(ctx, *book); err != nil {
+			return err
+		}
+		if err := repo.Create(ctx, sampleBook("2")); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	assert.ErrorIs(t, err, errAbort)
+
+	assertBookIDs(t, repo, "1")
+	book, err := repo.GetByID(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, 3, book.Quantity)
+}
+
+func testUnitRollbackOnPanic(t *testing.T, uow repository.UnitOfWork, repo repository.BookRepository) {
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = uow.Do(context.Background(), func(ctx context.Context) error {
+			if err := repo.Create(ctx, sampleBook("1")); err != nil {
+				return err
+			}
+			panic("boom")
+		})
+	})
+	assertBookIDs(t, repo)
+
+	// The unit must be usable again after a panic
+	require.NoError(t, uow.Do(context.Background(), func(ctx context.Context) error {
+		return repo.Create(ctx, sampleBook("2"))
+	}))
+	assertBookIDs(t, repo, "2")
+}
+
+func testUnitNestedInnerFailure(t *testing.T, uow repository.UnitOfWork, repo repository.BookRepository) {
+	err := uow.Do(context.Background(), func(ctx context.Context) error {
+		if err := repo.Create(ctx, sampleBook("1")); err != nil {
+			return err
+		}
+		inner := uow.Do(ctx, func(ctx context.Context) error {
+			if err := repo.Create(ctx, sampleBook("2")); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(inner, errAbort) {
+			return inner
+		}
+		return repo.Create(ctx, sampleBook("3"))
+	})
+	require.NoError(t, err)
+	assertBookIDs(t, repo, "1", "3")
+}
+
+func testUnitNestedOuterFailure(t *testing.T, uow repository.UnitOfWork, repo repository.BookRepository) {
+	err := uow.Do(context.Background(), func(ctx context.Context) error {
+		if err := repo.Create(ctx, sampleBook("1")); err != nil {
+			return err
+		}
+		if err := uow.Do(ctx, func(ctx context.Context) error {
+			return repo.Create(ctx, sampleBook("2"))
+		}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	assert.ErrorIs(t, err, errAbort)
+	assertBookIDs(t, repo)
+}
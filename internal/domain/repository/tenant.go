@@ -0,0 +1,23 @@
+package repository
+
+import "context"
+
+// DefaultTenant owns the data of calls made without a tenant, such as those
+// of the command line tools and single-library deployments.
+const DefaultTenant = "default"
+
+type tenantKey struct{}
+
+// WithTenant scopes every repository call made with ctx to the tenant's
+// data: reads only see it and writes are stamped with it.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// Tenant returns the tenant ctx is scoped to.
+func Tenant(ctx context.Context) string {
+	if tenant, _ := ctx.Value(tenantKey{}).(string); tenant != "" {
+		return tenant
+	}
+	return DefaultTenant
+}
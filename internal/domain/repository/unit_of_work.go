@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"sync"
+)
+
+// UnitOfWork runs several repository operations atomically. Repositories
+// join the unit through the ctx handed to fn, so fn must pass that ctx on.
+type UnitOfWork interface {
+	// Do commits everything fn did when it returns nil and rolls it back
+	// when it returns an error or panics; the panic is re-raised. Calling Do
+	// with a ctx that is already inside a unit nests: only the inner work is
+	// rolled back when the inner fn fails.
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type unitKey struct{}
+
+// unit is what the units of work nested in one outermost unit share.
+type unit struct {
+	mu          sync.Mutex
+	afterCommit []func()
+}
+
+// WithinUnit marks ctx as belonging to a unit of work. UnitOfWork
+// implementations call it on the ctx they hand to fn, and Committed on the
+// same ctx once the outermost unit has committed.
+func WithinUnit(ctx context.Context) context.Context {
+	if InUnit(ctx) {
+		return ctx
+	}
+	return context.WithValue(ctx, unitKey{}, &unit{})
+}
+
+// InUnit reports whether ctx belongs to a unit of work, whose reads may see
+// data that is never committed.
+func InUnit(ctx context.Context) bool {
+	_, in := ctx.Value(unitKey{}).(*unit)
+	return in
+}
+
+// AfterCommit runs fn once the outermost unit of work ctx belongs to has
+// committed, or right away outside a unit. fn is dropped if that unit rolls
+// back, but not if only a nested unit does, so it must be safe to run when
+// nothing changed.
+func AfterCommit(ctx context.Context, fn func()) {
+	u, ok := ctx.Value(unitKey{}).(*unit)
+	if !ok {
+		fn()
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.afterCommit = append(u.afterCommit, fn)
+}
+
+// Committed runs the functions registered with AfterCommit in the unit of
+// ctx.
+func Committed(ctx context.Context) {
+	u, ok := ctx.Value(unitKey{}).(*unit)
+	if !ok {
+		return
+	}
+	u.mu.Lock()
+	fns := u.afterCommit
+	u.afterCommit = nil
+	u.mu.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+)
+
+const EntityBook = "book"
+
+// Audited actions.
+const (
+	ActionBookCreate    = "book.create"
+	ActionBookImport    = "book.import"
+	ActionBookCheckout  = "book.checkout"
+	ActionBookReturn    = "book.return"
+	ActionBookAdjust    = "book.adjust"
+	ActionBookReconcile = "book.reconcile"
+	ActionBookRevert    = "book.revert"
+	ActionBookUndo      = "book.undo"
+	ActionBookMerge     = "book.merge"
+	ActionBookUnmerge   = "book.unmerge"
+)
+
+// Fields left out of audit diffs because every write changes them.
+var unauditedFields = map[string]bool{"created_at": true, "updated_at": true}
+
+type AuditService struct {
+	repo repository.AuditRepository
+}
+
+func NewAuditService(repo repository.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// Record logs that the actor and credential in ctx changed an entity from before to after,
+// either of which may be nil.
+func (s *AuditService) Record(ctx context.Context, action, entityType, entityID string, before, after interface{}) error {
+	beforeJSON, err := marshalState(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := marshalState(after)
+	if err != nil {
+		return err
+	}
+	diff, err := jsonDiff(beforeJSON, afterJSON)
+	if err != nil {
+		return err
+	}
+	return s.repo.Append(ctx, model.AuditEntry{
+		Actor:      Actor(ctx),
+		Credential: Credential(ctx),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Before:     beforeJSON,
+		After:      afterJSON,
+		Diff:       diff,
+		ClientIP:   ClientIP(ctx),
+		RequestID:  RequestID(ctx),
+	})
+}
+
+func (s *AuditService) List(ctx context.Context, filter repository.AuditFilter) ([]model.AuditEntry, error) {
+	return s.repo.List(ctx, filter)
+}
+
+// Verification is the result of checking the audit hash chain. BrokenAt is
+// the first entry whose hash doesn't match.
+type Verification struct {
+	Valid    bool `json:"valid"`
+	Entries  int  `json:"entries"`
+	BrokenAt uint `json:"broken_at,omitempty"`
+}
+
+// Verify walks the whole audit log checking every hash.
+func (s *AuditService) Verify(ctx context.Context) (Verification, error) {
+	const pageSize = 500
+	result := Verification{Valid: true}
+	prevHash := ""
+	filter := repository.AuditFilter{Limit: pageSize}
+	for {
+		entries, err := s.repo.List(ctx, filter)
+		if err != nil {
+			return Verification{}, err
+		}
+		for _, e := range entries {
+			if e.PrevHash != prevHash || e.Hash != e.ComputeHash() {
+				return Verification{Valid: false, Entries: result.Entries, BrokenAt: e.ID}, nil
+			}
+			prevHash = e.Hash
+			result.Entries++
+			filter.AfterID = e.ID
+		}
+		if len(entries) < pageSize {
+			return result, nil
+		}
+	}
+}
+
+func marshalState(v interface{}) (model.RawJSON, error) {
+	if v == nil {
+		return "", nil
+	}
+	if book, ok := v.(*model.Book); ok && book == nil {
+		return "", nil
+	}
+	out, err := json.Marshal(v)
+	return model.RawJSON(out), err
+}
+
+// jsonDiff compares two JSON objects field by field, giving
+// {"field": {"before": ..., "after": ...}} for every field that changed.
+func jsonDiff(before, after model.RawJSON) (model.RawJSON, error) {
+	var a, b map[string]json.RawMessage
+	if before != "" {
+		if err := json.Unmarshal([]byte(before), &a); err != nil {
+			return "", err
+		}
+	}
+	if after != "" {
+		if err := json.Unmarshal([]byte(after), &b); err != nil {
+			return "", err
+		}
+	}
+
+	type change struct {
+		Before json.RawMessage `json:"before"`
+		After  json.RawMessage `json:"after"`
+	}
+	diff := map[string]change{}
+	for _, fields := range []map[string]json.RawMessage{a, b} {
+		for field := range fields {
+			if unauditedFields[field] || bytes.Equal(a[field], b[field]) {
+				continue
+			}
+			diff[field] = change{Before: orNull(a[field]), After: orNull(b[field])}
+		}
+	}
+	out, err := json.Marshal(diff)
+	return model.RawJSON(out), err
+}
+
+func orNull(raw json.RawMessage) json.RawMessage {
+	if raw == nil {
+		return json.RawMessage("null")
+	}
+	return raw
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+)
+
+var (
+	ErrBookNotAvailable = errors.New("book not available")
+	ErrInvalidQuantity  = errors.New("quantity cannot go below zero")
+)
+
+// BookService holds the circulation rules shared by the HTTP API and the
+// SIP2 server. Every change to a book's quantity goes through it, so it can
+// be written to the inventory ledger and the audit log in the same unit of
+// work.
+type BookService struct {
+	repo   repository.BookRepository
+	ledger repository.LedgerRepository
+	audit  *AuditService
+	uow    repository.UnitOfWork
+}
+
+func NewBookService(repo repository.BookRepository, ledger repository.LedgerRepository, audit *AuditService, uow repository.UnitOfWork) *BookService {
+	return &BookService{repo: repo, ledger: ledger, audit: audit, uow: uow}
+}
+
+// Create adds a book, recording its copies as the opening ledger entry.
+func (s *BookService) Create(ctx context.Context, book model.Book) error {
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.repo.Create(ctx, book); err != nil {
+			return err
+		}
+		if err := s.record(ctx, book.ID, book.Quantity, model.ReasonCreate); err != nil {
+			return err
+		}
+		return s.recordAudit(ctx, ActionBookCreate, book.ID, nil)
+	})
+}
+
+// Import creates an imported book, or updates the catalogue data of an
+// existing one while keeping its quantity and creation time.
+func (s *BookService) Import(ctx context.Context, book model.Book) (*model.Book, error) {
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		existing, err := s.repo.GetByID(ctx, book.ID)
+		if err == nil {
+			book.Quantity = existing.Quantity
+			book.CreatedAt = existing.CreatedAt
+			if err := s.repo.Update(ctx, book); err != nil {
+				return err
+			}
+			return s.recordAudit(ctx, ActionBookImport, book.ID, existing)
+		}
+		if !errors.Is(err, repository.ErrBookNotFound) {
+			return err
+		}
+		if err := s.repo.Create(ctx, book); err != nil {
+			return err
+		}
+		if err := s.record(ctx, book.ID, book.Quantity, model.ReasonImport); err != nil {
+			return err
+		}
+		return s.recordAudit(ctx, ActionBookImport, book.ID, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &book, nil
+}
+
+// Checkout takes one copy of the book off the shelf.
+func (s *BookService) Checkout(ctx context.Context, id string) (*model.Book, error) {
+	return s.change(ctx, id, -1, model.ReasonCheckout, ActionBookCheckout)
+}
+
+// Return puts one copy of the book back on the shelf.
+func (s *BookService) Return(ctx context.Context, id string) (*model.Book, error) {
+	return s.change(ctx, id, 1, model.ReasonReturn, ActionBookReturn)
+}
+
+// Adjust corrects the number of copies, e.g. after a stocktake.
+func (s *BookService) Adjust(ctx context.Context, id string, delta int) (*model.Book, error) {
+	return s.change(ctx, id, delta, model.ReasonAdjustment, ActionBookAdjust)
+}
+
+func (s *BookService) change(ctx context.Context, id string, delta int, reason, action string) (*model.Book, error) {
+	var book *model.Book
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		if book, err = s.repo.GetByID(ctx, id); err != nil {
+			return err
+		}
+
+		if book.Quantity+delta < 0 {
+			if reason == model.ReasonCheckout {
+				return ErrBookNotAvailable
+			}
+			return ErrInvalidQuantity
+		}
+
+		before := *book
+		book.Quantity += delta
+		if err := s.repo.Update(ctx, *book); err != nil {
+			return err
+		}
+		if err := s.record(ctx, id, delta, reason); err != nil {
+			return err
+		}
+		return s.recordAudit(ctx, action, id, &before)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+func (s *BookService) record(ctx context.Context, bookID string, delta int, reason string) error {
+	if delta == 0 {
+		return nil
+	}
+	return s.ledger.Append(ctx, model.LedgerEntry{
+		BookID:    bookID,
+		Delta:     delta,
+		Reason:    reason,
+		Actor:     Actor(ctx),
+		RequestID: RequestID(ctx),
+	})
+}
+
+// recordAudit logs the change of a book from before to how it is now.
+func (s *BookService) recordAudit(ctx context.Context, action, id string, before *model.Book) error {
+	after, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, action, EntityBook, id, before, after)
+}
+
+// Ledger returns the quantity changes of a book, oldest first.
+func (s *BookService) Ledger(ctx context.Context, id string) ([]model.LedgerEntry, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.ledger.ListByBook(ctx, id)
+}
+
+// Drift is a book whose quantity doesn't match the sum of its ledger.
+type Drift struct {
+	BookID        string
+	Quantity      int
+	LedgerBalance int
+}
+
+// Reconcile recomputes every book's quantity from the ledger and reports
+// the books that disagree. With fix set their quantity is reset to the
+// ledger balance.
+func (s *BookService) Reconcile(ctx context.Context, fix bool) ([]Drift, error) {
+	var drifts []Drift
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		books, err := s.repo.GetAll(ctx)
+		if err != nil {
+			return err
+		}
+		balances, err := s.ledger.Balances(ctx)
+		if err != nil {
+			return err
+		}
+		for _, book := range books {
+			if balance := balances[book.ID]; book.Quantity != balance {
+				drifts = append(drifts, Drift{BookID: book.ID, Quantity: book.Quantity, LedgerBalance: balance})
+				if fix {
+					before := book
+					book.Quantity = balance
+					if err := s.repo.Update(ctx, book); err != nil {
+						return err
+					}
+					if err := s.recordAudit(ctx, ActionBookReconcile, book.ID, &before); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	})
+	return drifts, err
+}
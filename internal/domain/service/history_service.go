@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+)
+
+const (
+	ChangeAdded   = "added"
+	ChangeChanged = "changed"
+	ChangeRemoved = "removed"
+)
+
+// Change is how a book differs between two instants. Fields lists the
+// changed fields by their JSON names.
+type Change struct {
+	ID     string      `json:"id"`
+	Change string      `json:"change"`
+	Fields []string    `json:"fields,omitempty"`
+	Before *model.Book `json:"before,omitempty"`
+	After  *model.Book `json:"after,omitempty"`
+}
+
+// HistoryService answers questions about the catalogue at earlier instants.
+type HistoryService struct {
+	history repository.BookHistoryRepository
+}
+
+func NewHistoryService(history repository.BookHistoryRepository) *HistoryService {
+	return &HistoryService{history: history}
+}
+
+func (s *HistoryService) BooksAsOf(ctx context.Context, t time.Time) ([]model.Book, error) {
+	return s.history.GetAllAsOf(ctx, t)
+}
+
+func (s *HistoryService) BookAsOf(ctx context.Context, id string, t time.Time) (*model.Book, error) {
+	return s.history.GetByIDAsOf(ctx, id, t)
+}
+
+// Diff lists the books added or changed between from and to, in the order
+// they were added to the catalogue.
+func (s *HistoryService) Diff(ctx context.Context, from, to time.Time) ([]Change, error) {
+	before, err := s.history.GetAllAsOf(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.history.GetAllAsOf(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+
+	beforeByID := make(map[string]model.Book, len(before))
+	for _, book := range before {
+		beforeByID[book.ID] = book
+	}
+	changes := []Change{}
+	for _, book := range after {
+		book := book
+		old, ok := beforeByID[book.ID]
+		delete(beforeByID, book.ID)
+		if !ok {
+			changes = append(changes, Change{ID: book.ID, Change: ChangeAdded, After: &book})
+			continue
+		}
+		if fields := changedFields(old, book); len(fields) > 0 {
+			changes = append(changes, Change{ID: book.ID, Change: ChangeChanged, Fields: fields, Before: &old, After: &book})
+		}
+	}
+	// Books deleted by then, or added after to when from is the later one
+	for _, book := range before {
+		book := book
+		if _, ok := beforeByID[book.ID]; ok {
+			changes = append(changes, Change{ID: book.ID, Change: ChangeRemoved, Before: &book})
+		}
+	}
+	return changes, nil
+}
+
+func changedFields(a, b model.Book) []string {
+	var fields []string
+	for _, f := range []struct {
+		name    string
+		changed bool
+	}{
+		{"title", a.Title != b.Title},
+		{"author", a.Author != b.Author},
+		{"quantity", a.Quantity != b.Quantity},
+		{"isbn", a.ISBN != b.ISBN},
+		{"publisher", a.Publisher != b.Publisher},
+		{"year", a.Year != b.Year},
+	} {
+		if f.changed {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+)
+
+var (
+	ErrIdempotencyKeyReused   = errors.New("idempotency key was used for a different request")
+	ErrIdempotencyKeyInFlight = errors.New("a request with this idempotency key is still running")
+)
+
+// DefaultIdempotencyLease is how long a request may hold its key before a
+// retry may run it again, in case it died without releasing it.
+const DefaultIdempotencyLease = time.Minute
+
+// IdempotencyService remembers the first response to each Idempotency-Key
+// for a window, so retried requests are answered without running again.
+// Keys belong to the tenant and, for requests made with an API key, to that
+// key, so one client can't replay another's responses by guessing its keys.
+type IdempotencyService struct {
+	keys   repository.IdempotencyRepository
+	window time.Duration
+	lease  time.Duration
+}
+
+func NewIdempotencyService(keys repository.IdempotencyRepository, window time.Duration) *IdempotencyService {
+	return &IdempotencyService{keys: keys, window: window, lease: DefaultIdempotencyLease}
+}
+
+// WithLease sets how long a running request holds its key. It should be
+// longer than any request takes.
+func (s *IdempotencyService) WithLease(lease time.Duration) *IdempotencyService {
+	s.lease = lease
+	return s
+}
+
+// Begin claims key for the request with the given fingerprint and returns
+// nil, or returns the stored response if the same request was sent with key
+// before. A key used for a different request fails with
+// ErrIdempotencyKeyReused, one whose request is still running with
+// ErrIdempotencyKeyInFlight. A claimed key expires after the lease, so one
+// whose request died is free again; once the response is stored it is kept
+// for the window.
+func (s *IdempotencyService) Begin(ctx context.Context, key, fingerprint string) (*model.IdempotencyKey, error) {
+	now := time.Now().UTC()
+	if err := s.keys.DeleteExpired(ctx, now); err != nil {
+		return nil, err
+	}
+	key = scopedKey(ctx, key)
+	err := s.keys.Create(ctx, model.IdempotencyKey{
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.lease),
+	})
+	if !errors.Is(err, repository.ErrIdempotencyKeyExists) {
+		return nil, err
+	}
+
+	stored, err := s.keys.Get(ctx, key)
+	switch {
+	case errors.Is(err, repository.ErrIdempotencyKeyNotFound):
+		// The first request failed and released the key just now
+		return nil, ErrIdempotencyKeyInFlight
+	case err != nil:
+		return nil, err
+	case stored.Fingerprint != fingerprint:
+		return nil, ErrIdempotencyKeyReused
+	case stored.Status == 0:
+		return nil, ErrIdempotencyKeyInFlight
+	}
+	return stored, nil
+}
+
+// Finish stores the response to the request that claimed the key and keeps
+// it for the window. If the lease ran out and a retry stored its response
+// first, that one is kept.
+func (s *IdempotencyService) Finish(ctx context.Context, response model.IdempotencyKey) error {
+	response.Key = scopedKey(ctx, response.Key)
+	stored, err := s.keys.Get(ctx, response.Key)
+	if err != nil {
+		return err
+	}
+	if stored.Status != 0 {
+		return nil
+	}
+	response.Fingerprint, response.CreatedAt = stored.Fingerprint, stored.CreatedAt
+	response.ExpiresAt = stored.CreatedAt.Add(s.window)
+	return s.keys.Update(ctx, response)
+}
+
+// Release frees the key of a request that failed, so it can be retried.
+func (s *IdempotencyService) Release(ctx context.Context, key string) error {
+	return s.keys.Delete(ctx, scopedKey(ctx, key))
+}
+
+// scopedKey prefixes key with the credential in ctx, if any. The repository
+// scopes it to the tenant.
+func scopedKey(ctx context.Context, key string) string {
+	if credential := Credential(ctx); credential != "" {
+		return credential + " " + key
+	}
+	return key
+}
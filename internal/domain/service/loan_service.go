@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+)
+
+var ErrNoOpenLoan = errors.New("no open loan")
+
+// LoanService lends copies of books out as loans, so each checkout can be
+// returned, and looked up, by its own ID.
+type LoanService struct {
+	books *BookService
+	loans repository.LoanRepository
+}
+
+func NewLoanService(books *BookService, loans repository.LoanRepository) *LoanService {
+	return &LoanService{books: books, loans: loans}
+}
+
+// Lend checks a copy of the book out and opens a loan for it.
+func (s *LoanService) Lend(ctx context.Context, bookID string) (*model.Loan, error) {
+	_, loan, err := s.lend(ctx, bookID, "")
+	return loan, err
+}
+
+// Checkout lends a copy of the book like Lend, for callers that deal in
+// books rather than loans. It returns the book.
+func (s *LoanService) Checkout(ctx context.Context, bookID string) (*model.Book, error) {
+	book, _, err := s.lend(ctx, bookID, "")
+	return book, err
+}
+
+// CheckoutTo is Checkout for desks that know the patron, who is recorded on
+// the loan so only they can renew it.
+func (s *LoanService) CheckoutTo(ctx context.Context, bookID, patron string) (*model.Book, error) {
+	book, _, err := s.lend(ctx, bookID, patron)
+	return book, err
+}
+
+func (s *LoanService) lend(ctx context.Context, bookID, patron string) (*model.Book, *model.Loan, error) {
+	var book *model.Book
+	var loan model.Loan
+	err := s.books.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		if book, err = s.books.Checkout(ctx, bookID); err != nil {
+			return err
+		}
+		loan = model.Loan{BookID: bookID, Actor: Actor(ctx), Patron: patron, CreatedAt: time.Now().UTC()}
+		return s.loans.Create(ctx, &loan)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return book, &loan, nil
+}
+
+// Return brings the loan's copy back. Returning a loan twice is harmless:
+// the second time it is returned as it is.
+func (s *LoanService) Return(ctx context.Context, id uint) (*model.Loan, error) {
+	var loan *model.Loan
+	err := s.books.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		if loan, err = s.loans.GetByID(ctx, id); err != nil {
+			return err
+		}
+		_, err = s.close(ctx, loan)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loan, nil
+}
+
+// ReturnBook brings back a copy of the book, for callers that deal in books
+// rather than loans. It closes the book's oldest open loan; copies checked
+// out before there were loans have none, and are returned without one.
+func (s *LoanService) ReturnBook(ctx context.Context, bookID string) (*model.Book, error) {
+	var book *model.Book
+	err := s.books.uow.Do(ctx, func(ctx context.Context) error {
+		loans, err := s.loans.ListByBook(ctx, bookID)
+		if err != nil {
+			return err
+		}
+		for i := range loans {
+			if loans[i].ReturnedAt == nil {
+				book, err = s.close(ctx, &loans[i])
+				return err
+			}
+		}
+		book, err = s.books.Return(ctx, bookID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// Renew marks the patron's open loan of the book renewed. It fails with
+// ErrNoOpenLoan if the patron has no copy of the book out.
+func (s *LoanService) Renew(ctx context.Context, bookID, patron string) (*model.Book, *model.Loan, error) {
+	var book *model.Book
+	var loan *model.Loan
+	err := s.books.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		if book, err = s.books.repo.GetByID(ctx, bookID); err != nil {
+			return err
+		}
+		loans, err := s.loans.ListByBook(ctx, bookID)
+		if err != nil {
+			return err
+		}
+		for i := range loans {
+			if loans[i].ReturnedAt == nil && patron != "" && loans[i].Patron == patron {
+				loan = &loans[i]
+				break
+			}
+		}
+		if loan == nil {
+			return ErrNoOpenLoan
+		}
+		now := time.Now().UTC()
+		loan.RenewedAt = &now
+		return s.loans.Update(ctx, *loan)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return book, loan, nil
+}
+
+// close returns the copy of an open loan and marks the loan returned. A
+// loan already returned is left alone and no book is returned.
+func (s *LoanService) close(ctx context.Context, loan *model.Loan) (*model.Book, error) {
+	if loan.ReturnedAt != nil {
+		return nil, nil
+	}
+	book, err := s.books.Return(ctx, loan.BookID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	loan.ReturnedAt = &now
+	return book, s.loans.Update(ctx, *loan)
+}
+
+// BookLoans lists every loan of the book, open or returned, oldest first.
+func (s *LoanService) BookLoans(ctx context.Context, bookID string) ([]model.Loan, error) {
+	if _, err := s.books.repo.GetByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+	return s.loans.ListByBook(ctx, bookID)
+}
+
+func (s *LoanService) Loan(ctx context.Context, id uint) (*model.Loan, error) {
+	return s.loans.GetByID(ctx, id)
+}
@@ -0,0 +1,342 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+)
+
+var (
+	ErrSameBook     = errors.New("cannot merge a book into itself")
+	ErrMergeUndone  = errors.New("merge already undone")
+	ErrCopiesOnLoan = errors.New("the merged copies are no longer all on the shelf")
+)
+
+// DefaultMinScore is the lowest score reported as a duplicate by default.
+const DefaultMinScore = 0.85
+
+// Why two books were matched.
+const (
+	MatchISBN        = "isbn"
+	MatchTitleAuthor = "title_author"
+)
+
+// DuplicateCandidate is a pair of books that may be the same title. Score
+// runs from 0 to 1, where 1 is a matching ISBN.
+type DuplicateCandidate struct {
+	Score  float64      `json:"score"`
+	Reason string       `json:"reason"`
+	Books  []model.Book `json:"books"`
+}
+
+// MergeService finds duplicate books and merges them. The duplicate's copies
+// move to the survivor through merge entries, and its loans and ledger
+// entries move with them, so the survivor's circulation covers both. Its
+// audit log and versions stay under its own ID, linked to the survivor by the
+// merge record.
+type MergeService struct {
+	books  *BookService
+	merges repository.MergeRepository
+	loans  repository.LoanRepository
+}
+
+func NewMergeService(books *BookService, merges repository.MergeRepository, loans repository.LoanRepository) *MergeService {
+	return &MergeService{books: books, merges: merges, loans: loans}
+}
+
+// Duplicates scores pairs of books and returns those scoring at least
+// minScore, best first. Books with the same ISBN always match; books with
+// different ISBNs are different editions and never do. Only books that share
+// an ISBN or the start of their title are compared, so the catalogue isn't
+// compared pair by pair.
+func (s *MergeService) Duplicates(ctx context.Context, minScore float64) ([]DuplicateCandidate, error) {
+	books, err := s.books.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := map[string][]int{}
+	for i, book := range books {
+		if isbn := normalizeISBN(book.ISBN); isbn != "" {
+			blocks["isbn:"+isbn] = append(blocks["isbn:"+isbn], i)
+		}
+		prefix := "title:" + titlePrefix(book.Title)
+		blocks[prefix] = append(blocks[prefix], i)
+	}
+	pairs := map[[2]int]bool{}
+	for _, block := range blocks {
+		for x := range block {
+			for _, j := range block[x+1:] {
+				pairs[[2]int{block[x], j}] = true
+			}
+		}
+	}
+	sorted := make([][2]int, 0, len(pairs))
+	for pair := range pairs {
+		sorted = append(sorted, pair)
+	}
+	sort.Slice(sorted, func(a, b int) bool {
+		if sorted[a][0] != sorted[b][0] {
+			return sorted[a][0] < sorted[b][0]
+		}
+		return sorted[a][1] < sorted[b][1]
+	})
+
+	candidates := []DuplicateCandidate{}
+	for _, pair := range sorted {
+		a, b := books[pair[0]], books[pair[1]]
+		score, reason, ok := duplicateScore(a, b)
+		if ok && score >= minScore {
+			candidates = append(candidates, DuplicateCandidate{Score: score, Reason: reason, Books: []model.Book{a, b}})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+	return candidates, nil
+}
+
+// Merge folds the duplicate into the survivor: the survivor gets its copies
+// and the duplicate is deleted. The survivor's catalogue data is kept.
+func (s *MergeService) Merge(ctx context.Context, survivorID, duplicateID string) (*model.BookMerge, error) {
+	if survivorID == duplicateID {
+		return nil, ErrSameBook
+	}
+	var merge model.BookMerge
+	err := s.books.uow.Do(ctx, func(ctx context.Context) error {
+		survivor, err := s.books.repo.GetByID(ctx, survivorID)
+		if err != nil {
+			return err
+		}
+		duplicate, err := s.books.repo.GetByID(ctx, duplicateID)
+		if err != nil {
+			return err
+		}
+		snapshot, err := json.Marshal(duplicate)
+		if err != nil {
+			return err
+		}
+
+		before := *survivor
+		survivor.Quantity += duplicate.Quantity
+		if err := s.books.repo.Update(ctx, *survivor); err != nil {
+			return err
+		}
+		if err := s.books.repo.Delete(ctx, duplicateID); err != nil {
+			return err
+		}
+		if err := s.books.record(ctx, duplicateID, -duplicate.Quantity, model.ReasonMerge); err != nil {
+			return err
+		}
+
+		merge = model.BookMerge{
+			SurvivorID:          survivorID,
+			DuplicateID:         duplicateID,
+			Duplicate:           model.RawJSON(snapshot),
+			DuplicateMarcRecord: duplicate.MarcRecord,
+			Copies:              duplicate.Quantity,
+			Actor:               Actor(ctx),
+			CreatedAt:           time.Now().UTC(),
+		}
+		if err := s.merges.Create(ctx, &merge); err != nil {
+			return err
+		}
+		// The duplicate's entries sum to zero after its merge entry, so
+		// moving them leaves the survivor's balance to its own merge entry
+		if err := s.books.ledger.MoveBook(ctx, duplicateID, survivorID, merge.ID); err != nil {
+			return err
+		}
+		if err := s.books.record(ctx, survivorID, duplicate.Quantity, model.ReasonMerge); err != nil {
+			return err
+		}
+		loans, err := s.loans.ListByBook(ctx, duplicateID)
+		if err != nil {
+			return err
+		}
+		for _, loan := range loans {
+			loan.BookID, loan.MergeID = survivorID, &merge.ID
+			if err := s.loans.Update(ctx, loan); err != nil {
+				return err
+			}
+		}
+		if err := s.books.recordAudit(ctx, ActionBookMerge, survivorID, &before); err != nil {
+			return err
+		}
+		return s.books.audit.Record(ctx, ActionBookMerge, EntityBook, duplicateID, duplicate, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &merge, nil
+}
+
+// UndoMerge brings the duplicate back with the copies it had and takes
+// them off the survivor again, along with the copies of its loans returned
+// since. Its loans and ledger entries move back to it.
+func (s *MergeService) UndoMerge(ctx context.Context, id uint) (*model.BookMerge, error) {
+	var merge *model.BookMerge
+	err := s.books.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		if merge, err = s.merges.GetByID(ctx, id); err != nil {
+			return err
+		}
+		if merge.UndoneAt != nil {
+			return ErrMergeUndone
+		}
+		survivor, err := s.books.repo.GetByID(ctx, merge.SurvivorID)
+		if err != nil {
+			return err
+		}
+		loans, err := s.loans.ListByMerge(ctx, merge.ID)
+		if err != nil {
+			return err
+		}
+		copies := merge.Copies
+		for _, loan := range loans {
+			if loan.ReturnedAt != nil && loan.ReturnedAt.After(merge.CreatedAt) {
+				copies++
+			}
+		}
+		if survivor.Quantity < copies {
+			return ErrCopiesOnLoan
+		}
+		var duplicate model.Book
+		if err := json.Unmarshal([]byte(merge.Duplicate), &duplicate); err != nil {
+			return err
+		}
+		duplicate.MarcRecord = merge.DuplicateMarcRecord
+		duplicate.Quantity = copies
+
+		before := *survivor
+		survivor.Quantity -= copies
+		if err := s.books.repo.Update(ctx, *survivor); err != nil {
+			return err
+		}
+		if err := s.books.repo.Create(ctx, duplicate); err != nil {
+			return err
+		}
+		if err := s.books.ledger.MoveBack(ctx, merge.ID, merge.DuplicateID); err != nil {
+			return err
+		}
+		if err := s.books.record(ctx, merge.SurvivorID, -copies, model.ReasonUnmerge); err != nil {
+			return err
+		}
+		if err := s.books.record(ctx, merge.DuplicateID, copies, model.ReasonUnmerge); err != nil {
+			return err
+		}
+		for _, loan := range loans {
+			loan.BookID, loan.MergeID = merge.DuplicateID, nil
+			if err := s.loans.Update(ctx, loan); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now().UTC()
+		merge.UndoneAt = &now
+		if err := s.merges.Update(ctx, *merge); err != nil {
+			return err
+		}
+		if err := s.books.recordAudit(ctx, ActionBookUnmerge, merge.SurvivorID, &before); err != nil {
+			return err
+		}
+		return s.books.recordAudit(ctx, ActionBookUnmerge, merge.DuplicateID, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merge, nil
+}
+
+func (s *MergeService) Merges(ctx context.Context) ([]model.BookMerge, error) {
+	return s.merges.List(ctx)
+}
+
+// duplicateScore compares two books by ISBN, or else by the similarity of
+// their titles and authors ignoring case, punctuation and word order. It
+// reports false for books with different ISBNs.
+func duplicateScore(a, b model.Book) (float64, string, bool) {
+	isbnA, isbnB := normalizeISBN(a.ISBN), normalizeISBN(b.ISBN)
+	if isbnA != "" && isbnB != "" {
+		return 1, MatchISBN, isbnA == isbnB
+	}
+	score := (similarity(a.Title, b.Title) + similarity(a.Author, b.Author)) / 2
+	return math.Round(score*100) / 100, MatchTitleAuthor, true
+}
+
+// titlePrefixLength is how many letters and digits of their titles books
+// must share to be compared by title and author.
+const titlePrefixLength = 4
+
+// titlePrefix is the start of the title's letters and digits, lowercased, so
+// "Pride & prejudice" and "Pride and Prejudice" share one.
+func titlePrefix(title string) string {
+	var prefix []rune
+	for _, r := range strings.ToLower(title) {
+		if len(prefix) == titlePrefixLength {
+			break
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			prefix = append(prefix, r)
+		}
+	}
+	return string(prefix)
+}
+
+func normalizeISBN(isbn string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) || r == 'X' || r == 'x' {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, isbn)
+}
+
+// similarity is one minus the edit distance between the normalised strings
+// over the length of the longer one.
+func similarity(a, b string) float64 {
+	ra, rb := []rune(normalizeWords(a)), []rune(normalizeWords(b))
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// normalizeWords lowercases s, drops punctuation and sorts its words, so
+// "Austen, Jane" and "Jane Austen" compare equal.
+func normalizeWords(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
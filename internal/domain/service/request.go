@@ -0,0 +1,52 @@
+package service
+
+import "context"
+
+type actorKey struct{}
+type requestIDKey struct{}
+
+// WithActor records who makes the changes done with ctx.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+type credentialKey struct{}
+
+// WithCredential records the credential that authenticated the changes done
+// with ctx. Unlike the actor, which the client names itself, it can't be
+// claimed without holding the credential.
+func WithCredential(ctx context.Context, credential string) context.Context {
+	return context.WithValue(ctx, credentialKey{}, credential)
+}
+
+func Credential(ctx context.Context) string {
+	credential, _ := ctx.Value(credentialKey{}).(string)
+	return credential
+}
+
+// WithRequestID records the request the changes done with ctx belong to.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+type clientIPKey struct{}
+
+// WithClientIP records the address the changes done with ctx came from.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+)
+
+var (
+	ErrRevisionNotFound = errors.New("revision not found")
+	ErrEditConflict     = errors.New("book was changed since")
+	ErrNothingToUndo    = errors.New("nothing to undo")
+	ErrUndoNeedsAPIKey  = errors.New("undo needs an API key")
+)
+
+// UndoWindow is how long after a catalogue change its actor can undo it.
+const UndoWindow = 15 * time.Minute
+
+// Catalogue changes that can be undone. Circulation is corrected with
+// adjustments instead, so the ledger stays the record of every copy.
+var undoableActions = map[string]bool{
+	ActionBookImport: true,
+	ActionBookRevert: true,
+	ActionBookUndo:   true,
+}
+
+// Changes that only move copies. They leave the catalogue data a revert
+// restores alone, so they don't count as a newer revision.
+var circulationActions = map[string]bool{
+	ActionBookCheckout:  true,
+	ActionBookReturn:    true,
+	ActionBookAdjust:    true,
+	ActionBookReconcile: true,
+}
+
+// Revisions lists the audited changes of a book, oldest first. The state
+// after each is in its After snapshot, and its ID identifies the revision.
+func (s *BookService) Revisions(ctx context.Context, id string) ([]model.AuditEntry, error) {
+	entries, err := s.audit.List(ctx, repository.AuditFilter{EntityType: EntityBook, EntityID: id})
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		if _, err := s.repo.GetByID(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// Revert restores the catalogue data of a book to how it was after the
+// given revision, leaving its quantity alone. When expected isn't zero it
+// must be the book's latest catalogue revision, so a client can't overwrite
+// changes it hasn't seen; checkouts and returns since then don't matter.
+func (s *BookService) Revert(ctx context.Context, id string, revision, expected uint) (*model.Book, error) {
+	var book *model.Book
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		current, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		entries, err := s.audit.List(ctx, repository.AuditFilter{EntityType: EntityBook, EntityID: id})
+		if err != nil {
+			return err
+		}
+		var target *model.AuditEntry
+		for i := range entries {
+			if entries[i].ID == revision {
+				target = &entries[i]
+			}
+		}
+		if target == nil || target.After == "" {
+			return ErrRevisionNotFound
+		}
+		if expected != 0 && latestCatalogueRevision(entries) != expected {
+			return ErrEditConflict
+		}
+		book, err = s.restore(ctx, ActionBookRevert, *current, target.After)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// latestCatalogueRevision is the ID of the latest entry that changed more
+// than the book's copies, zero when there is none.
+func latestCatalogueRevision(entries []model.AuditEntry) uint {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !circulationActions[entries[i].Action] {
+			return entries[i].ID
+		}
+	}
+	return 0
+}
+
+// Undo reverts the latest catalogue change the actor in ctx made within
+// UndoWindow with the credential in ctx, so naming someone else as the actor
+// isn't enough to undo their changes. Without a credential nobody can tell
+// callers apart, so it fails with ErrUndoNeedsAPIKey. Undoing an undo redoes
+// the change. It fails with ErrEditConflict if the book was edited again
+// after that change.
+func (s *BookService) Undo(ctx context.Context) (*model.Book, error) {
+	if Credential(ctx) == "" {
+		return nil, ErrUndoNeedsAPIKey
+	}
+	var book *model.Book
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		entries, err := s.audit.List(ctx, repository.AuditFilter{
+			Actor:      Actor(ctx),
+			EntityType: EntityBook,
+			From:       time.Now().Add(-UndoWindow),
+		})
+		if err != nil {
+			return err
+		}
+		var last *model.AuditEntry
+		for i := len(entries) - 1; i >= 0; i-- {
+			if undoableActions[entries[i].Action] && entries[i].Before != "" && entries[i].Credential == Credential(ctx) {
+				last = &entries[i]
+				break
+			}
+		}
+		if last == nil {
+			return ErrNothingToUndo
+		}
+
+		current, err := s.repo.GetByID(ctx, last.EntityID)
+		if err != nil {
+			return err
+		}
+		after, err := snapshotBook(last.After)
+		if err != nil {
+			return err
+		}
+		if !sameCatalogue(*current, after) {
+			return ErrEditConflict
+		}
+		book, err = s.restore(ctx, ActionBookUndo, *current, last.Before)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// restore copies the catalogue fields of a snapshot onto the book.
+func (s *BookService) restore(ctx context.Context, action string, current model.Book, snapshot model.RawJSON) (*model.Book, error) {
+	from, err := snapshotBook(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	book := current
+	book.Title = from.Title
+	book.Author = from.Author
+	book.ISBN = from.ISBN
+	book.Publisher = from.Publisher
+	book.Year = from.Year
+	if err := s.repo.Update(ctx, book); err != nil {
+		return nil, err
+	}
+	if err := s.recordAudit(ctx, action, book.ID, &current); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, book.ID)
+}
+
+func snapshotBook(snapshot model.RawJSON) (model.Book, error) {
+	var book model.Book
+	err := json.Unmarshal([]byte(snapshot), &book)
+	return book, err
+}
+
+func sameCatalogue(a, b model.Book) bool {
+	return a.Title == b.Title && a.Author == b.Author && a.ISBN == b.ISBN &&
+		a.Publisher == b.Publisher && a.Year == b.Year
+}
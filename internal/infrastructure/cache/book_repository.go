@@ -0,0 +1,259 @@
+// Package cache holds a read-through cache in front of a BookRepository.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	DefaultTTL        = 30 * time.Second
+	DefaultMaxEntries = 1000
+)
+
+type Config struct {
+	// TTL is how long a read is served from the cache.
+	TTL time.Duration
+	// MaxEntries bounds the books cached, whether by ID or in a GetAll
+	// result, which counts as many as it holds. The least recently used
+	// reads are dropped first, and a GetAll result larger than the whole
+	// cache isn't cached.
+	MaxEntries int
+}
+
+type Stats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	// Entries is the number of books cached, counted as for MaxEntries.
+	Entries int `json:"entries"`
+}
+
+// BookRepository caches GetByID and GetAll of the repository it wraps.
+// Writes go through and invalidate what they touch once their unit of work
+// commits, so reads in between keep seeing the committed book. Concurrent
+// misses for the same key share one query. Reads inside a unit
+// of work always go to the wrapped repository. Books are cached per tenant.
+type BookRepository struct {
+	next   repository.BookRepository
+	config Config
+	now    func() time.Time
+	group  singleflight.Group
+	hits   atomic.Uint64
+	misses atomic.Uint64
+
+	mu sync.Mutex
+	// generation changes on every write; loads started under an older
+	// generation are not stored.
+	generation uint64
+	entries    map[key]*list.Element
+	lru        *list.List // of *entry, most recently used first
+	// size is the number of books in entries, which MaxEntries bounds.
+	size int
+}
+
+type key struct {
+	tenant, id string
+	// all marks the tenant's GetAll result rather than one book.
+	all bool
+}
+
+type entry struct {
+	key     key
+	book    model.Book
+	books   []model.Book // the GetAll result, for an all key
+	expires time.Time
+}
+
+// size is how many books the entry counts as against MaxEntries.
+func (e *entry) size() int {
+	if e.key.all {
+		return len(e.books)
+	}
+	return 1
+}
+
+func NewBookRepository(next repository.BookRepository, config Config) *BookRepository {
+	if config.TTL <= 0 {
+		config.TTL = DefaultTTL
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = DefaultMaxEntries
+	}
+	return &BookRepository{
+		next:    next,
+		config:  config,
+		now:     time.Now,
+		entries: map[key]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+func (r *BookRepository) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Stats{Hits: r.hits.Load(), Misses: r.misses.Load(), Entries: r.size}
+}
+
+func (r *BookRepository) GetAll(ctx context.Context) ([]model.Book, error) {
+	if repository.InUnit(ctx) {
+		return r.next.GetAll(ctx)
+	}
+
+	k := key{tenant: repository.Tenant(ctx), all: true}
+	r.mu.Lock()
+	if elem, ok := r.entries[k]; ok {
+		e := elem.Value.(*entry)
+		if r.now().Before(e.expires) {
+			r.lru.MoveToFront(elem)
+			books := make([]model.Book, len(e.books))
+			copy(books, e.books)
+			r.mu.Unlock()
+			r.hits.Add(1)
+			return books, nil
+		}
+		r.remove(k)
+	}
+	generation := r.generation
+	r.mu.Unlock()
+	r.misses.Add(1)
+
+	v, err := r.load(ctx, fmt.Sprintf("%d:%s:all", generation, k.tenant), func(ctx context.Context) (interface{}, error) {
+		books, err := r.next.GetAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		r.mu.Lock()
+		if r.generation == generation {
+			r.store(&entry{key: k, books: append(make([]model.Book, 0, len(books)), books...)})
+		}
+		r.mu.Unlock()
+		return books, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]model.Book{}, v.([]model.Book)...), nil
+}
+
+func (r *BookRepository) GetByID(ctx context.Context, id string) (*model.Book, error) {
+	if repository.InUnit(ctx) {
+		return r.next.GetByID(ctx, id)
+	}
+
+	k := key{tenant: repository.Tenant(ctx), id: id}
+	r.mu.Lock()
+	if elem, ok := r.entries[k]; ok {
+		e := elem.Value.(*entry)
+		if r.now().Before(e.expires) {
+			r.lru.MoveToFront(elem)
+			book := e.book
+			r.mu.Unlock()
+			r.hits.Add(1)
+			return &book, nil
+		}
+		r.remove(k)
+	}
+	generation := r.generation
+	r.mu.Unlock()
+	r.misses.Add(1)
+
+	v, err := r.load(ctx, fmt.Sprintf("%d:%s:id:%s", generation, k.tenant, id), func(ctx context.Context) (interface{}, error) {
+		book, err := r.next.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		r.mu.Lock()
+		if r.generation == generation {
+			r.store(&entry{key: k, book: *book})
+		}
+		r.mu.Unlock()
+		return *book, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	book := v.(model.Book)
+	return &book, nil
+}
+
+func (r *BookRepository) Create(ctx context.Context, book model.Book) error {
+	defer r.invalidateAfterCommit(ctx, key{tenant: repository.Tenant(ctx), id: book.ID})
+	return r.next.Create(ctx, book)
+}
+
+func (r *BookRepository) Update(ctx context.Context, book model.Book) error {
+	defer r.invalidateAfterCommit(ctx, key{tenant: repository.Tenant(ctx), id: book.ID})
+	return r.next.Update(ctx, book)
+}
+
+func (r *BookRepository) Delete(ctx context.Context, id string) error {
+	defer r.invalidateAfterCommit(ctx, key{tenant: repository.Tenant(ctx), id: id})
+	return r.next.Delete(ctx, id)
+}
+
+// load runs fn once for all concurrent callers of key. A caller that gives
+// up doesn't cancel the query for the others, but its deadline still
+// applies.
+func (r *BookRepository) load(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	ch := r.group.DoChan(key, func() (interface{}, error) {
+		shared := context.WithoutCancel(ctx)
+		if deadline, ok := ctx.Deadline(); ok {
+			var cancel context.CancelFunc
+			shared, cancel = context.WithDeadline(shared, deadline)
+			defer cancel()
+		}
+		return fn(shared)
+	})
+	select {
+	case res := <-ch:
+		return res.Val, res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// invalidateAfterCommit invalidates k once the write is committed. Doing it
+// earlier would let a read racing the commit cache the old book under the
+// new generation.
+func (r *BookRepository) invalidateAfterCommit(ctx context.Context, k key) {
+	repository.AfterCommit(ctx, func() { r.invalidate(k) })
+}
+
+func (r *BookRepository) invalidate(k key) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+	r.remove(k)
+	r.remove(key{tenant: k.tenant, all: true})
+}
+
+// store and remove expect r.mu to be held.
+func (r *BookRepository) store(e *entry) {
+	r.remove(e.key)
+	if e.size() > r.config.MaxEntries {
+		return
+	}
+	e.expires = r.now().Add(r.config.TTL)
+	r.entries[e.key] = r.lru.PushFront(e)
+	r.size += e.size()
+	for r.size > r.config.MaxEntries {
+		r.remove(r.lru.Back().Value.(*entry).key)
+	}
+}
+
+func (r *BookRepository) remove(k key) {
+	if elem, ok := r.entries[k]; ok {
+		r.size -= elem.Value.(*entry).size()
+		r.lru.Remove(elem)
+		delete(r.entries, k)
+	}
+}
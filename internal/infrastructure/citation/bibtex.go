@@ -0,0 +1,46 @@
+package citation
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+)
+
+var bibtexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`#`, `\#`,
+	`$`, `\$`,
+	`%`, `\%`,
+	`&`, `\&`,
+	`_`, `\_`,
+	`~`, `\textasciitilde{}`,
+	`^`, `\textasciicircum{}`,
+)
+
+func renderBibTeX(books []model.Book, keys []string) []byte {
+	var out bytes.Buffer
+	for i, book := range books {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		fmt.Fprintf(&out, "@book{%s,\n", keys[i])
+		writeBibTeXField(&out, "author", book.Author)
+		writeBibTeXField(&out, "title", book.Title)
+		writeBibTeXField(&out, "publisher", book.Publisher)
+		writeBibTeXField(&out, "year", book.Year)
+		writeBibTeXField(&out, "isbn", book.ISBN)
+		out.WriteString("}\n")
+	}
+	return out.Bytes()
+}
+
+func writeBibTeXField(out *bytes.Buffer, field, value string) {
+	if value = strings.Join(strings.Fields(value), " "); value == "" {
+		return
+	}
+	fmt.Fprintf(out, "  %s = {%s},\n", field, bibtexEscaper.Replace(value))
+}
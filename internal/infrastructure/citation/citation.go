@@ -0,0 +1,122 @@
+package citation
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"golang.org/x/text/unicode/norm"
+)
+
+type Format string
+
+const (
+	BibTeX  Format = "bibtex"
+	RIS     Format = "ris"
+	CSLJSON Format = "csl-json"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported citation format")
+
+// ContentType returns the media type a citation format is served as.
+func (f Format) ContentType() string {
+	switch f {
+	case BibTeX:
+		return "application/x-bibtex; charset=utf-8"
+	case RIS:
+		return "application/x-research-info-systems; charset=utf-8"
+	case CSLJSON:
+		return "application/vnd.citationstyles.csl+json; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+func ParseFormat(value string) (Format, error) {
+	switch f := Format(strings.ToLower(value)); f {
+	case "":
+		return BibTeX, nil
+	case BibTeX, RIS, CSLJSON:
+		return f, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// Render writes the citations for books in the given format.
+func Render(format Format, books []model.Book) ([]byte, error) {
+	keys := Keys(books)
+	switch format {
+	case BibTeX:
+		return renderBibTeX(books, keys), nil
+	case RIS:
+		return renderRIS(books, keys), nil
+	case CSLJSON:
+		return renderCSLJSON(books, keys)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+var stopWords = map[string]bool{"a": true, "an": true, "the": true, "on": true, "of": true, "and": true}
+
+// Key builds a citation key from the book alone: the author, year and first
+// title word, then a suffix from a hash of the ID, e.g. austen2003pride_356a19
+// for book 1. The suffix keeps editions of the same book apart, and since it
+// never depends on which other books are cited with it, the same book always
+// gets the same key.
+func Key(book model.Book) string {
+	var b strings.Builder
+	b.WriteString(asciiWord(splitName(book.Author).Family))
+	b.WriteString(book.Year)
+	for _, word := range strings.Fields(book.Title) {
+		if w := asciiWord(word); w != "" && !stopWords[w] {
+			b.WriteString(w)
+			break
+		}
+	}
+	if b.Len() == 0 {
+		b.WriteString("book")
+	}
+	sum := sha1.Sum([]byte(book.ID))
+	return b.String() + "_" + hex.EncodeToString(sum[:])[:6]
+}
+
+// Keys returns the key of each book.
+func Keys(books []model.Book) []string {
+	keys := make([]string, len(books))
+	for i, book := range books {
+		keys[i] = Key(book)
+	}
+	return keys
+}
+
+type name struct {
+	Family string
+	Given  string
+}
+
+// splitName understands both "Austen, Jane" and "Jane Austen".
+func splitName(author string) name {
+	author = strings.TrimSpace(author)
+	if family, given, ok := strings.Cut(author, ","); ok {
+		return name{Family: strings.TrimSpace(family), Given: strings.TrimSpace(given)}
+	}
+	parts := strings.Fields(author)
+	if len(parts) < 2 {
+		return name{Family: author}
+	}
+	return name{Family: parts[len(parts)-1], Given: strings.Join(parts[:len(parts)-1], " ")}
+}
+
+// asciiWord lowercases a word and drops accents and anything that isn't a
+// letter or digit, so keys are safe in every reference manager.
+func asciiWord(word string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(word) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
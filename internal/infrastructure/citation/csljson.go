@@ -0,0 +1,49 @@
+package citation
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+)
+
+type cslItem struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Title     string    `json:"title,omitempty"`
+	Author    []cslName `json:"author,omitempty"`
+	Publisher string    `json:"publisher,omitempty"`
+	Issued    *cslDate  `json:"issued,omitempty"`
+	ISBN      string    `json:"ISBN,omitempty"`
+}
+
+type cslName struct {
+	Family string `json:"family,omitempty"`
+	Given  string `json:"given,omitempty"`
+}
+
+type cslDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+func renderCSLJSON(books []model.Book, keys []string) ([]byte, error) {
+	items := make([]cslItem, 0, len(books))
+	for i, book := range books {
+		item := cslItem{
+			ID:        keys[i],
+			Type:      "book",
+			Title:     book.Title,
+			Publisher: book.Publisher,
+			ISBN:      book.ISBN,
+		}
+		if book.Author != "" {
+			n := splitName(book.Author)
+			item.Author = []cslName{{Family: n.Family, Given: n.Given}}
+		}
+		if year, err := strconv.Atoi(book.Year); err == nil {
+			item.Issued = &cslDate{DateParts: [][]int{{year}}}
+		}
+		items = append(items, item)
+	}
+	return json.MarshalIndent(items, "", "    ")
+}
@@ -0,0 +1,32 @@
+package citation
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+)
+
+func renderRIS(books []model.Book, keys []string) []byte {
+	var out bytes.Buffer
+	for i, book := range books {
+		writeRISField(&out, "TY", "BOOK")
+		writeRISField(&out, "ID", keys[i])
+		writeRISField(&out, "AU", book.Author)
+		writeRISField(&out, "TI", book.Title)
+		writeRISField(&out, "PB", book.Publisher)
+		writeRISField(&out, "PY", book.Year)
+		writeRISField(&out, "SN", book.ISBN)
+		out.WriteString("ER  - \r\n")
+	}
+	return out.Bytes()
+}
+
+// RIS is line based, so values are flattened onto a single line.
+func writeRISField(out *bytes.Buffer, tag, value string) {
+	if value = strings.Join(strings.Fields(value), " "); value == "" {
+		return
+	}
+	fmt.Fprintf(out, "%s  - %s\r\n", tag, value)
+}
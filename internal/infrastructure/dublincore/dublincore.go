@@ -0,0 +1,56 @@
+package dublincore
+
+import (
+	"encoding/xml"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+)
+
+const (
+	OAIDCNamespace = "http://www.openarchives.org/OAI/2.0/oai_dc/"
+	OAIDCSchema    = "http://www.openarchives.org/OAI/2.0/oai_dc.xsd"
+	DCNamespace    = "http://purl.org/dc/elements/1.1/"
+	XSINamespace   = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// Record is an oai_dc:dc element, the simple Dublin Core container shared by
+// OAI-PMH and SRU.
+type Record struct {
+	XMLName        xml.Name `xml:"oai_dc:dc"`
+	XmlnsOAIDC     string   `xml:"xmlns:oai_dc,attr"`
+	XmlnsDC        string   `xml:"xmlns:dc,attr"`
+	XmlnsXSI       string   `xml:"xmlns:xsi,attr"`
+	SchemaLocation string   `xml:"xsi:schemaLocation,attr"`
+
+	Titles      []string `xml:"dc:title"`
+	Creators    []string `xml:"dc:creator"`
+	Publishers  []string `xml:"dc:publisher"`
+	Dates       []string `xml:"dc:date"`
+	Types       []string `xml:"dc:type"`
+	Identifiers []string `xml:"dc:identifier"`
+}
+
+func FromBook(book model.Book) *Record {
+	record := &Record{
+		XmlnsOAIDC:     OAIDCNamespace,
+		XmlnsDC:        DCNamespace,
+		XmlnsXSI:       XSINamespace,
+		SchemaLocation: OAIDCNamespace + " " + OAIDCSchema,
+		Types:          []string{"Text"},
+		Identifiers:    []string{"urn:go-doc:book:" + book.ID},
+	}
+	appendIf(&record.Titles, book.Title)
+	appendIf(&record.Creators, book.Author)
+	appendIf(&record.Publishers, book.Publisher)
+	appendIf(&record.Dates, book.Year)
+	if book.ISBN != "" {
+		record.Identifiers = append(record.Identifiers, "urn:isbn:"+book.ISBN)
+	}
+	return record
+}
+
+func appendIf(values *[]string, value string) {
+	if value != "" {
+		*values = append(*values, value)
+	}
+}
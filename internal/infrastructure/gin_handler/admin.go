@@ -0,0 +1,27 @@
+package gin_handler
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdminKey lets through requests that send one of keys as
+// Authorization: Bearer <key>. With no keys configured the routes behind it
+// are closed to everyone.
+func RequireAdminKey(keys []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if ok && given != "" {
+			for _, key := range keys {
+				if subtle.ConstantTimeCompare([]byte(given), []byte(key)) == 1 {
+					c.Next()
+					return
+				}
+			}
+		}
+		c.Header("WWW-Authenticate", "Bearer")
+		writeProblem(c, problemAdminKeyRequired, "")
+	}
+}
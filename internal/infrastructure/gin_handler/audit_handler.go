@@ -0,0 +1,91 @@
+package gin_handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// AuditPage is one page of ListAudit.
+type AuditPage struct {
+	Entries []model.AuditEntry `json:"entries"`
+}
+
+type AuditHandler struct {
+	audit *service.AuditService
+}
+
+func NewAuditHandler(audit *service.AuditService) *AuditHandler {
+	return &AuditHandler{audit: audit}
+}
+
+// ListAudit returns audit entries oldest first, filtered by actor, action,
+// entity_type, entity_id, request_id and the from/to time range. Pass the
+// last ID seen as after_id to get the next page.
+func (h *AuditHandler) ListAudit(c *gin.Context) {
+	filter := repository.AuditFilter{
+		Actor:      c.Query("actor"),
+		Action:     c.Query("action"),
+		EntityType: c.Query("entity_type"),
+		EntityID:   c.Query("entity_id"),
+		RequestID:  c.Query("request_id"),
+		Limit:      defaultAuditLimit,
+	}
+	var err error
+	if value, ok := c.GetQuery("from"); ok {
+		if filter.From, err = parseTimestamp(value); err != nil {
+			writeValidationError(c, FieldError{Field: "from", Message: "must be an RFC 3339 timestamp or a date"})
+			return
+		}
+	}
+	if value, ok := c.GetQuery("to"); ok {
+		if filter.To, err = parseTimestamp(value); err != nil {
+			writeValidationError(c, FieldError{Field: "to", Message: "must be an RFC 3339 timestamp or a date"})
+			return
+		}
+	}
+	if value, ok := c.GetQuery("after_id"); ok {
+		afterID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			writeValidationError(c, FieldError{Field: "after_id", Message: "must be a positive integer"})
+			return
+		}
+		filter.AfterID = uint(afterID)
+	}
+	if value, ok := c.GetQuery("limit"); ok {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			writeValidationError(c, FieldError{Field: "limit", Message: "must be between 1 and 1000"})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := h.audit.List(c.Request.Context(), filter)
+	if err != nil {
+		writeServerError(c, err, "")
+		return
+	}
+	c.IndentedJSON(http.StatusOK, AuditPage{Entries: entries})
+}
+
+// VerifyAudit recomputes the audit hash chain and reports the first entry
+// that was altered or removed.
+func (h *AuditHandler) VerifyAudit(c *gin.Context) {
+	result, err := h.audit.Verify(c.Request.Context())
+	if err != nil {
+		writeServerError(c, err, "")
+		return
+	}
+	c.IndentedJSON(http.StatusOK, result)
+}
@@ -0,0 +1,198 @@
+package gin_handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	// Name fields in validation errors the way clients spell them
+	v.RegisterTagNameFunc(jsonFieldName)
+	// The built-in isbn rule refuses the hyphens most ISBNs are written with
+	_ = v.RegisterValidation("isbn", func(fl validator.FieldLevel) bool {
+		return model.ValidISBN(fl.Field().String())
+	})
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// LimitBody refuses request bodies larger than max bytes with 413.
+func LimitBody(max int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > max {
+			writeProblem(c, problemTooLarge, fmt.Sprintf("Request body is larger than %d bytes", max))
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, max)
+		c.Next()
+	}
+}
+
+// readBody reads the whole request body, answering the request with a
+// problem and returning false if it can't.
+func readBody(c *gin.Context) ([]byte, bool) {
+	body, err := io.ReadAll(c.Request.Body)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeProblem(c, problemTooLarge, fmt.Sprintf("Request body is larger than %d bytes", tooLarge.Limit))
+		return nil, false
+	case err != nil:
+		writeProblem(c, problemInvalidRequest, "Could not read request body")
+		return nil, false
+	}
+	return body, true
+}
+
+// bindJSON decodes the JSON object in the request body into v, a pointer to
+// a struct, and checks the struct's binding rules. It answers the request
+// with a problem and returns false if anything is wrong. Properties are
+// decoded one by one so unknown properties, values of the wrong type and
+// broken rules are all reported together. Properties of fields tagged
+// readonly are ignored, so a client can send back what it was given.
+func bindJSON(c *gin.Context, v interface{}) bool {
+	body, ok := readBody(c)
+	if !ok {
+		return false
+	}
+	var properties map[string]json.RawMessage
+	if err := json.Unmarshal(body, &properties); err != nil || properties == nil {
+		writeProblem(c, problemInvalidRequest, "Request body is not a JSON object")
+		return false
+	}
+
+	target := reflect.ValueOf(v).Elem()
+	fields := map[string]int{}
+	readOnly := map[string]bool{}
+	for i := 0; i < target.NumField(); i++ {
+		if field := target.Type().Field(i); field.IsExported() {
+			if name := jsonFieldName(field); name != "" {
+				fields[name] = i
+				readOnly[name] = field.Tag.Get("readonly") == "true"
+			}
+		}
+	}
+
+	var problems []FieldError
+	badType := map[string]bool{}
+	for name, value := range properties {
+		i, ok := fields[name]
+		if !ok {
+			problems = append(problems, FieldError{Field: name, Message: "is not a known field"})
+			continue
+		}
+		if readOnly[name] {
+			continue
+		}
+		decoded := reflect.New(target.Field(i).Type())
+		if err := json.Unmarshal(value, decoded.Interface()); err != nil {
+			problems = append(problems, FieldError{Field: name, Message: "must be " + jsonTypeName(decoded.Elem().Type())})
+			badType[name] = true
+			continue
+		}
+		target.Field(i).Set(decoded.Elem())
+	}
+
+	var validationErrs validator.ValidationErrors
+	if err := binding.Validator.ValidateStruct(v); errors.As(err, &validationErrs) {
+		for _, fe := range validationErrs {
+			if !badType[fe.Field()] {
+				problems = append(problems, FieldError{Field: fe.Field(), Message: validationMessage(fe)})
+			}
+		}
+	} else if err != nil {
+		writeServerError(c, err, "Could not validate request")
+		return false
+	}
+	if len(problems) == 0 {
+		return true
+	}
+
+	// Fields in the order the struct declares them, unknown ones last
+	sort.SliceStable(problems, func(a, b int) bool {
+		ia, knownA := fields[problems[a].Field]
+		ib, knownB := fields[problems[b].Field]
+		if knownA != knownB {
+			return knownA
+		}
+		if !knownA {
+			return problems[a].Field < problems[b].Field
+		}
+		return ia < ib
+	})
+	writeValidationError(c, problems...)
+	return false
+}
+
+func validationMessage(fe validator.FieldError) string {
+	unit := ""
+	if fe.Kind() == reflect.String {
+		unit = " characters"
+	}
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min", "gte":
+		return "must be at least " + fe.Param() + unit
+	case "max", "lte":
+		return "must be at most " + fe.Param() + unit
+	case "len":
+		return "must be " + fe.Param() + unit + " long"
+	case "ne":
+		return "must not be " + fe.Param()
+	case "oneof":
+		return "must be one of " + fe.Param()
+	case "numeric":
+		return "must contain only digits"
+	case "isbn":
+		return "must be a valid ISBN-10 or ISBN-13"
+	}
+	return "is invalid"
+}
+
+func jsonTypeName(t reflect.Type) string {
+	if t == reflect.TypeOf(time.Time{}) {
+		return "an RFC 3339 timestamp"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a non-negative integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
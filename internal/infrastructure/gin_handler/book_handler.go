@@ -1,24 +1,25 @@
 package gin_handler
 
 import (
-	"bytes"
-	"fmt"
-	"io/ioutil"
+	"errors"
 	"net/http"
 
 	"github.com/brianantony456/go-doc/internal/domain/model"
 	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/domain/service"
 	"github.com/google/uuid"
 
 	"github.com/gin-gonic/gin"
 )
 
 type BookHandler struct {
-	repo repository.BookRepository
+	repo    repository.BookRepository
+	books   *service.BookService
+	history *service.HistoryService
 }
 
-func NewBookHandler(repo repository.BookRepository) *BookHandler {
-	return &BookHandler{repo: repo}
+func NewBookHandler(repo repository.BookRepository, books *service.BookService, history *service.HistoryService) *BookHandler {
+	return &BookHandler{repo: repo, books: books, history: history}
 }
 
 func generateID() string {
@@ -26,111 +27,111 @@ func generateID() string {
 }
 
 func (h *BookHandler) GetBooks(c *gin.Context) {
-	books, err := h.repo.GetAll()
-	if err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal Server Error"})
+	if asOf, ok := c.GetQuery("as_of"); ok {
+		h.booksAsOf(c, asOf)
 		return
 	}
-	c.IndentedJSON(http.StatusOK, books)
-}
 
-func (h *BookHandler) CreateBook(c *gin.Context) {
-	// Read the raw data from the request body
-	body, err := ioutil.ReadAll(c.Request.Body)
+	books, err := h.repo.GetAll(c.Request.Context())
 	if err != nil {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Invalid request"})
+		writeServerError(c, err, "")
 		return
 	}
+	c.IndentedJSON(http.StatusOK, books)
+}
 
-	// Print the raw JSON body for debugging purposes
-	fmt.Println("Provided JSON: ", string(body))
-
-	// Reset the request body
-	c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
-
+func (h *BookHandler) CreateBook(c *gin.Context) {
 	// Bind the JSON to newBook struct
 	var newBook model.Book
-	if err := c.BindJSON(&newBook); err != nil {
-		fmt.Println("create book, bind error: ", err)
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "error": err.Error()})
+	if !bindJSON(c, &newBook) {
 		return
 	}
 
-	// Log the values to ensure they are captured correctly
-	fmt.Printf("Title: %s, Author: %s, Quantity: %d\n", newBook.Title, newBook.Author, newBook.Quantity)
-
 	// Assign a new ID if not provided
 	if newBook.ID == "" {
 		newBook.ID = generateID()
 	}
 
 	// Create the book entry in the repository
-	if err := h.repo.Create(newBook); err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Could not create book"})
+	err := h.books.Create(c.Request.Context(), newBook)
+	switch {
+	case errors.Is(err, repository.ErrBookExists):
+		writeProblem(c, problemBookExists, "")
+		return
+	case err != nil:
+		writeServerError(c, err, "Could not create book")
+		return
+	}
+
+	// Answer with the stored book, which has its timestamps
+	book, err := h.repo.GetByID(c.Request.Context(), newBook.ID)
+	if err != nil {
+		writeServerError(c, err, "")
 		return
 	}
-	c.IndentedJSON(http.StatusCreated, newBook)
+	c.IndentedJSON(http.StatusCreated, book)
 }
 
 func (h *BookHandler) BookById(c *gin.Context) {
+	if asOf, ok := c.GetQuery("as_of"); ok {
+		h.bookAsOf(c, asOf)
+		return
+	}
+
 	id := c.Param("id")
-	book, err := h.repo.GetByID(id)
+	book, err := h.repo.GetByID(c.Request.Context(), id)
 
+	if errors.Is(err, repository.ErrBookNotFound) {
+		writeProblem(c, problemBookNotFound, "")
+		return
+	}
 	if err != nil {
-		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Book not found"})
+		writeServerError(c, err, "")
 		return
 	}
 
 	c.IndentedJSON(http.StatusOK, book)
 }
 
-func (h *BookHandler) CheckoutBook(c *gin.Context) {
-	id, ok := c.GetQuery("id")
+// AdjustmentRequest is the body of POST /books/:id/adjustments.
+type AdjustmentRequest struct {
+	Delta int `json:"delta" binding:"ne=0"`
+}
 
-	if !ok {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Missing id parameter"})
+// AdjustBook corrects a book's quantity by the delta in the body, which is
+// recorded in the ledger as an adjustment.
+func (h *BookHandler) AdjustBook(c *gin.Context) {
+	var body AdjustmentRequest
+	if !bindJSON(c, &body) {
 		return
 	}
 
-	book, err := h.repo.GetByID(id)
-	if err != nil {
-		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Book not Found"})
+	book, err := h.books.Adjust(c.Request.Context(), c.Param("id"), body.Delta)
+	switch {
+	case errors.Is(err, repository.ErrBookNotFound):
+		writeProblem(c, problemBookNotFound, "")
 		return
-	}
-
-	if book.Quantity <= 0 {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Book not available"})
+	case errors.Is(err, service.ErrInvalidQuantity):
+		writeProblem(c, problemInvalidQuantity, "")
 		return
-	}
-
-	book.Quantity -= 1
-	if err := h.repo.Update(*book); err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Could not update book"})
+	case err != nil:
+		writeServerError(c, err, "Could not update book")
 		return
 	}
 	c.IndentedJSON(http.StatusOK, book)
 }
 
-func (h *BookHandler) ReturnBook(c *gin.Context) {
-	id, ok := c.GetQuery("id")
-
-	if !ok {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Missing id parameter"})
+func (h *BookHandler) BookLedger(c *gin.Context) {
+	entries, err := h.books.Ledger(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, repository.ErrBookNotFound) {
+		writeProblem(c, problemBookNotFound, "")
 		return
 	}
-
-	book, err := h.repo.GetByID(id)
 	if err != nil {
-		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Book not Found"})
+		writeServerError(c, err, "")
 		return
 	}
-
-	book.Quantity += 1
-	if err := h.repo.Update(*book); err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Could not update book"})
-		return
-	}
-	c.IndentedJSON(http.StatusOK, book)
+	c.IndentedJSON(http.StatusOK, entries)
 }
 
 func ServeBooksPage(c *gin.Context) {
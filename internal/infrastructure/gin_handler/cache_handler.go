@@ -0,0 +1,28 @@
+package gin_handler
+
+import (
+	"net/http"
+
+	"github.com/brianantony456/go-doc/internal/infrastructure/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheReport is the body of GET /admin/cache.
+type CacheReport struct {
+	Enabled bool `json:"enabled"`
+	cache.Stats
+}
+
+// CacheStats reports the hits and misses of the book cache, counted over
+// every tenant since the server started. stats is nil when the cache is
+// disabled.
+func CacheStats(stats func() cache.Stats) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if stats == nil {
+			c.IndentedJSON(http.StatusOK, CacheReport{})
+			return
+		}
+		c.IndentedJSON(http.StatusOK, CacheReport{Enabled: true, Stats: stats()})
+	}
+}
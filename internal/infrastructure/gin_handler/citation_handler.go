@@ -0,0 +1,88 @@
+package gin_handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/infrastructure/citation"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *BookHandler) BookCitation(c *gin.Context) {
+	format, err := citation.ParseFormat(c.Query("format"))
+	if err != nil {
+		writeProblem(c, problemUnsupportedType, "Unsupported citation format")
+		return
+	}
+
+	book, err := h.repo.GetByID(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, repository.ErrBookNotFound) {
+		writeProblem(c, problemBookNotFound, "")
+		return
+	}
+	if err != nil {
+		writeServerError(c, err, "")
+		return
+	}
+
+	h.renderCitations(c, format, []model.Book{*book})
+}
+
+// BulkCitations cites several books at once, taking ids either as
+// ?ids=1,2,3 or as repeated ?ids= parameters.
+func (h *BookHandler) BulkCitations(c *gin.Context) {
+	format, err := citation.ParseFormat(c.Query("format"))
+	if err != nil {
+		writeProblem(c, problemUnsupportedType, "Unsupported citation format")
+		return
+	}
+
+	var ids []string
+	for _, value := range c.QueryArray("ids") {
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		writeValidationError(c, FieldError{Field: "ids", Message: "is required"})
+		return
+	}
+
+	books := make([]model.Book, 0, len(ids))
+	var missing []string
+	for _, id := range ids {
+		book, err := h.repo.GetByID(c.Request.Context(), id)
+		if errors.Is(err, repository.ErrBookNotFound) {
+			missing = append(missing, id)
+			continue
+		}
+		if err != nil {
+			writeServerError(c, err, "")
+			return
+		}
+		books = append(books, *book)
+	}
+	if len(missing) > 0 {
+		p := newProblem(c, problemBookNotFound, "")
+		p.IDs = missing
+		renderProblem(c, p)
+		return
+	}
+
+	h.renderCitations(c, format, books)
+}
+
+func (h *BookHandler) renderCitations(c *gin.Context, format citation.Format, books []model.Book) {
+	out, err := citation.Render(format, books)
+	if err != nil {
+		writeServerError(c, err, "Could not render citations")
+		return
+	}
+	c.Data(http.StatusOK, format.ContentType(), out)
+}
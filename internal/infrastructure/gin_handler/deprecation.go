@@ -0,0 +1,111 @@
+package gin_handler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecation marks routes that clients should move off.
+type Deprecation struct {
+	// Since is when the routes were deprecated.
+	Since time.Time
+	// Sunset is when they stop answering; zero until that is decided.
+	Sunset time.Time
+	// Successor is the path prefix of the routes that replace them.
+	Successor string
+}
+
+// DeprecatedUse counts the requests one tenant made to one deprecated
+// route, so we know who still has to move before the sunset.
+type DeprecatedUse struct {
+	Method   string    `json:"method"`
+	Route    string    `json:"route"`
+	Tenant   string    `json:"tenant"`
+	Count    uint64    `json:"count"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+type useKey struct {
+	method, route, tenant string
+}
+
+// DeprecationUsage holds the use counters of the deprecated routes. It is
+// kept in memory, so counts start again when the server restarts.
+type DeprecationUsage struct {
+	mu   sync.Mutex
+	uses map[useKey]*DeprecatedUse
+}
+
+func NewDeprecationUsage() *DeprecationUsage {
+	return &DeprecationUsage{uses: map[useKey]*DeprecatedUse{}}
+}
+
+func (u *DeprecationUsage) record(method, route, tenant string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	key := useKey{method, route, tenant}
+	use, ok := u.uses[key]
+	if !ok {
+		use = &DeprecatedUse{Method: method, Route: route, Tenant: tenant}
+		u.uses[key] = use
+	}
+	use.Count++
+	use.LastUsed = time.Now().UTC()
+}
+
+// Uses lists the counters of a tenant, by route and method.
+func (u *DeprecationUsage) Uses(tenant string) []DeprecatedUse {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	uses := []DeprecatedUse{}
+	for _, use := range u.uses {
+		if use.Tenant == tenant {
+			uses = append(uses, *use)
+		}
+	}
+	sort.Slice(uses, func(i, j int) bool {
+		if uses[i].Route != uses[j].Route {
+			return uses[i].Route < uses[j].Route
+		}
+		return uses[i].Method < uses[j].Method
+	})
+	return uses
+}
+
+// Deprecated answers the routes under prefix with Deprecation (RFC 9745),
+// Sunset (RFC 8594) and a Link to the successor, and counts their use. Past
+// the sunset they answer 410 Gone.
+func Deprecated(prefix string, d Deprecation, usage *DeprecationUsage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", fmt.Sprintf("@%d", d.Since.Unix()))
+		if !d.Sunset.IsZero() {
+			c.Header("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if d.Successor != "" {
+			successor := d.Successor + strings.TrimPrefix(c.Request.URL.Path, prefix)
+			c.Header("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		}
+		usage.record(c.Request.Method, c.FullPath(), repository.Tenant(c.Request.Context()))
+
+		if !d.Sunset.IsZero() && !time.Now().Before(d.Sunset) {
+			writeProblem(c, problemSunset, "Use "+d.Successor+" instead")
+			return
+		}
+		c.Next()
+	}
+}
+
+// ListDeprecatedUses reports how often the tenant used deprecated routes.
+func ListDeprecatedUses(usage *DeprecationUsage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.IndentedJSON(http.StatusOK, usage.Uses(repository.Tenant(c.Request.Context())))
+	}
+}
@@ -0,0 +1,149 @@
+package gin_handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/brianantony456/go-doc/internal/domain/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	problemContentType = "application/problem+json"
+
+	// problemTypeBase prefixes every problem type. The fragments are the
+	// headings of docs/problems.md, so the URIs must not change once
+	// published.
+	problemTypeBase = "https://github.com/brianantony456/go-doc/blob/main/docs/problems.md#"
+)
+
+// Problem is an RFC 7807 problem details body. Every error the API returns
+// is one, served as application/problem+json.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+
+	// IDs lists the books a request for several books could not find.
+	IDs []string `json:"ids,omitempty"`
+}
+
+// FieldError says what is wrong with one field of the request, named as the
+// client sent it: a JSON property or a query parameter.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type problemKind struct {
+	slug   string
+	title  string
+	status int
+}
+
+func (k problemKind) Type() string {
+	return problemTypeBase + k.slug
+}
+
+var (
+	problemInvalidRequest       = problemKind{"invalid-request", "Invalid request", http.StatusBadRequest}
+	problemTooLarge             = problemKind{"request-too-large", "Request body too large", http.StatusRequestEntityTooLarge}
+	problemValidation           = problemKind{"validation-failed", "Validation failed", http.StatusBadRequest}
+	problemRouteNotFound        = problemKind{"route-not-found", "Route not found", http.StatusNotFound}
+	problemSunset               = problemKind{"sunset", "Route retired", http.StatusGone}
+	problemBookNotFound         = problemKind{"book-not-found", "Book not found", http.StatusNotFound}
+	problemPageNotFound         = problemKind{"page-not-found", "Page not found", http.StatusNotFound}
+	problemBookNotAvailable     = problemKind{"book-not-available", "Book not available", http.StatusBadRequest}
+	problemInvalidQuantity      = problemKind{"invalid-quantity", "Quantity cannot go below zero", http.StatusBadRequest}
+	problemUnsupportedType      = problemKind{"unsupported-format", "Unsupported format", http.StatusBadRequest}
+	problemInvalidMARC          = problemKind{"invalid-marc", "Invalid MARC data", http.StatusBadRequest}
+	problemRevisionNotFound     = problemKind{"revision-not-found", "Revision not found", http.StatusNotFound}
+	problemNothingToUndo        = problemKind{"nothing-to-undo", "Nothing to undo", http.StatusNotFound}
+	problemUndoNeedsAPIKey      = problemKind{"undo-needs-api-key", "Undo needs an API key", http.StatusUnauthorized}
+	problemEditConflict         = problemKind{"edit-conflict", "Book was changed since", http.StatusConflict}
+	problemSameBook             = problemKind{"same-book", "Cannot merge a book into itself", http.StatusBadRequest}
+	problemMergeNotFound        = problemKind{"merge-not-found", "Merge not found", http.StatusNotFound}
+	problemMergeUndone          = problemKind{"merge-undone", "Merge already undone", http.StatusConflict}
+	problemCopiesOnLoan         = problemKind{"copies-on-loan", "Merged copies are on loan", http.StatusConflict}
+	problemBookExists           = problemKind{"book-exists", "Book already exists", http.StatusConflict}
+	problemLoanNotFound         = problemKind{"loan-not-found", "Loan not found", http.StatusNotFound}
+	problemIdempotencyKeyReused = problemKind{"idempotency-key-reused", "Idempotency key used for a different request", http.StatusUnprocessableEntity}
+	problemIdempotencyKeyInUse  = problemKind{"idempotency-key-in-use", "Request with this idempotency key still running", http.StatusConflict}
+	problemInvalidAPIKey        = problemKind{"invalid-api-key", "Invalid API key", http.StatusUnauthorized}
+	problemAdminKeyRequired     = problemKind{"admin-key-required", "Admin key required", http.StatusUnauthorized}
+	problemTenantConflict       = problemKind{"tenant-conflict", "Request names more than one tenant", http.StatusForbidden}
+	problemUnknownTenant        = problemKind{"unknown-tenant", "Unknown tenant", http.StatusNotFound}
+	problemTimeout              = problemKind{"timeout", "Request timed out", http.StatusGatewayTimeout}
+	problemCancelled            = problemKind{"cancelled", "Request cancelled", http.StatusServiceUnavailable}
+	problemInternal             = problemKind{"internal", "Internal Server Error", http.StatusInternalServerError}
+)
+
+// newProblem fills in the parts of a problem every response shares.
+func newProblem(c *gin.Context, kind problemKind, detail string) *Problem {
+	requestID := service.RequestID(c.Request.Context())
+	if requestID == "" {
+		requestID = c.GetHeader(requestIDHeader)
+	}
+	return &Problem{
+		Type:      kind.Type(),
+		Title:     kind.title,
+		Status:    kind.status,
+		Detail:    detail,
+		Instance:  c.Request.URL.RequestURI(),
+		RequestID: requestID,
+	}
+}
+
+// renderProblem aborts the request with p. Setting the content type first
+// keeps gin from replacing it with plain application/json.
+func renderProblem(c *gin.Context, p *Problem) {
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// writeProblem answers the request with a problem of the given kind.
+func writeProblem(c *gin.Context, kind problemKind, detail string) {
+	renderProblem(c, newProblem(c, kind, detail))
+}
+
+// writeValidationError answers the request with the fields that are wrong.
+func writeValidationError(c *gin.Context, fields ...FieldError) {
+	p := newProblem(c, problemValidation, "")
+	p.Errors = fields
+	renderProblem(c, p)
+}
+
+// contextError maps a request that ran out of time to 504 and one that was
+// cancelled, usually because the client went away, to 503.
+func contextError(err error) (problemKind, bool) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return problemTimeout, true
+	case errors.Is(err, context.Canceled):
+		return problemCancelled, true
+	}
+	return problemKind{}, false
+}
+
+// writeServerError answers an unexpected err with a 500 whose detail says
+// what failed, unless it is a timeout or cancellation. The error itself is
+// not shown to clients.
+func writeServerError(c *gin.Context, err error, detail string) {
+	if kind, ok := contextError(err); ok {
+		writeProblem(c, kind, "")
+		return
+	}
+	_ = c.Error(err)
+	writeProblem(c, problemInternal, detail)
+}
+
+// RouteNotFound answers requests no route matches.
+func RouteNotFound(c *gin.Context) {
+	writeProblem(c, problemRouteNotFound, "")
+}
@@ -0,0 +1,86 @@
+package gin_handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseTimestamp accepts RFC 3339 timestamps and plain dates, which mean
+// midnight UTC at the start of that day.
+func parseTimestamp(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// booksAsOf answers GET /books?as_of= from the book history.
+func (h *BookHandler) booksAsOf(c *gin.Context, asOf string) {
+	t, err := parseTimestamp(asOf)
+	if err != nil {
+		writeValidationError(c, FieldError{Field: "as_of", Message: "must be an RFC 3339 timestamp or a date"})
+		return
+	}
+	books, err := h.history.BooksAsOf(c.Request.Context(), t)
+	if err != nil {
+		writeServerError(c, err, "")
+		return
+	}
+	c.IndentedJSON(http.StatusOK, books)
+}
+
+// bookAsOf answers GET /books/:id?as_of= from the book history.
+func (h *BookHandler) bookAsOf(c *gin.Context, asOf string) {
+	t, err := parseTimestamp(asOf)
+	if err != nil {
+		writeValidationError(c, FieldError{Field: "as_of", Message: "must be an RFC 3339 timestamp or a date"})
+		return
+	}
+	book, err := h.history.BookAsOf(c.Request.Context(), c.Param("id"), t)
+	if errors.Is(err, repository.ErrBookNotFound) {
+		writeProblem(c, problemBookNotFound, "")
+		return
+	}
+	if err != nil {
+		writeServerError(c, err, "")
+		return
+	}
+	c.IndentedJSON(http.StatusOK, book)
+}
+
+// CatalogueDiff is the result of BooksDiff.
+type CatalogueDiff struct {
+	From    time.Time        `json:"from"`
+	To      time.Time        `json:"to"`
+	Changes []service.Change `json:"changes"`
+}
+
+// BooksDiff lists the books that were added or changed between ?from= and
+// ?to=, which defaults to now.
+func (h *BookHandler) BooksDiff(c *gin.Context) {
+	from, err := parseTimestamp(c.Query("from"))
+	if err != nil {
+		writeValidationError(c, FieldError{Field: "from", Message: "must be an RFC 3339 timestamp or a date"})
+		return
+	}
+	to := time.Now()
+	if value, ok := c.GetQuery("to"); ok {
+		if to, err = parseTimestamp(value); err != nil {
+			writeValidationError(c, FieldError{Field: "to", Message: "must be an RFC 3339 timestamp or a date"})
+			return
+		}
+	}
+
+	changes, err := h.history.Diff(c.Request.Context(), from, to)
+	if err != nil {
+		writeServerError(c, err, "")
+		return
+	}
+	c.IndentedJSON(http.StatusOK, CatalogueDiff{From: from, To: to, Changes: changes})
+}
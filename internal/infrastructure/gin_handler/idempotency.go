@@ -0,0 +1,160 @@
+package gin_handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader marks a response replayed for a retry.
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// Idempotency makes POST, PUT, PATCH and DELETE requests sent with an
+// Idempotency-Key safe to retry: the first response for a key is stored and
+// replayed for every retry, without running the request again. Responses
+// of 500 and over, and panics, are not stored, so those requests can be
+// retried for real. basePath is where the routes are mounted; requests are
+// told apart by their route under it, so a retry through another mount of
+// the same API, like the unversioned alias, is replayed too.
+func Idempotency(keys *service.IdempotencyService, basePath string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyKeyHeader)
+		if key == "" || !mutating(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			writeValidationError(c, FieldError{Field: idempotencyKeyHeader, Message: "must be at most 255 characters"})
+			return
+		}
+		body, ok := readBody(c)
+		if !ok {
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		stored, err := keys.Begin(ctx, key, fingerprint(c, basePath, body))
+		switch {
+		case errors.Is(err, service.ErrIdempotencyKeyReused):
+			writeProblem(c, problemIdempotencyKeyReused, "")
+			return
+		case errors.Is(err, service.ErrIdempotencyKeyInFlight):
+			writeProblem(c, problemIdempotencyKeyInUse, "")
+			return
+		case err != nil:
+			writeServerError(c, err, "Could not check the idempotency key")
+			return
+		case stored != nil:
+			replay(c, stored)
+			return
+		}
+
+		// The request may have been cancelled, but the key must still be
+		// settled
+		ctx = context.WithoutCancel(ctx)
+		finished := false
+		defer func() {
+			// A handler panicked: free the key for a retry and let the
+			// panic go on to the recovery middleware
+			if !finished {
+				if err := keys.Release(ctx, key); err != nil {
+					_ = c.Error(err)
+				}
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		finished = true
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			if err := keys.Release(ctx, key); err != nil {
+				_ = c.Error(err)
+			}
+			return
+		}
+		header := recorder.Header()
+		err = keys.Finish(ctx, model.IdempotencyKey{
+			Key:             key,
+			Status:          status,
+			ContentType:     header.Get("Content-Type"),
+			Location:        header.Get("Location"),
+			ContentLocation: header.Get("Content-Location"),
+			Body:            recorder.body.Bytes(),
+		})
+		if err != nil {
+			_ = c.Error(err)
+		}
+	}
+}
+
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// fingerprint identifies a request by its method, route under basePath,
+// path parameters, query and body, so a key sent again with anything else is
+// caught.
+func fingerprint(c *gin.Context, basePath string, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, c.Request.Method+" "+strings.TrimPrefix(c.FullPath(), basePath)+"\n")
+	for _, param := range c.Params {
+		io.WriteString(h, param.Key+"="+param.Value+"\n")
+	}
+	io.WriteString(h, c.Request.URL.Query().Encode()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(c *gin.Context, stored *model.IdempotencyKey) {
+	for name, value := range map[string]string{
+		"Content-Type":     stored.ContentType,
+		"Location":         stored.Location,
+		"Content-Location": stored.ContentLocation,
+	} {
+		if value != "" {
+			c.Header(name, value)
+		}
+	}
+	c.Header(idempotentReplayedHeader, "true")
+	c.Status(stored.Status)
+	_, _ = c.Writer.Write(stored.Body)
+	c.Abort()
+}
+
+// responseRecorder keeps a copy of the response body as it is written.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,158 @@
+package gin_handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LoanHandler struct {
+	loans *service.LoanService
+}
+
+func NewLoanHandler(loans *service.LoanService) *LoanHandler {
+	return &LoanHandler{loans: loans}
+}
+
+// LendBook checks a copy of the book out as a new loan, which the Location
+// header points to.
+func (h *LoanHandler) LendBook(c *gin.Context) {
+	loan, err := h.loans.Lend(c.Request.Context(), c.Param("id"))
+	switch {
+	case errors.Is(err, repository.ErrBookNotFound):
+		writeProblem(c, problemBookNotFound, "")
+		return
+	case errors.Is(err, service.ErrBookNotAvailable):
+		writeProblem(c, problemBookNotAvailable, "No copies are on the shelf")
+		return
+	case err != nil:
+		writeServerError(c, err, "Could not lend book")
+		return
+	}
+	c.Header("Location", loanLocation(c, "/books/:id/loans", loan))
+	c.IndentedJSON(http.StatusCreated, loan)
+}
+
+// ListBookLoans answers every loan of the book, oldest first.
+func (h *LoanHandler) ListBookLoans(c *gin.Context) {
+	loans, err := h.loans.BookLoans(c.Request.Context(), c.Param("id"))
+	switch {
+	case errors.Is(err, repository.ErrBookNotFound):
+		writeProblem(c, problemBookNotFound, "")
+		return
+	case err != nil:
+		writeServerError(c, err, "")
+		return
+	}
+	c.IndentedJSON(http.StatusOK, loans)
+}
+
+// CheckoutBook is PATCH /checkout?id=, the book-oriented form of LendBook.
+// It answers with the book rather than the loan.
+func (h *LoanHandler) CheckoutBook(c *gin.Context) {
+	id, ok := c.GetQuery("id")
+
+	if !ok {
+		writeValidationError(c, FieldError{Field: "id", Message: "is required"})
+		return
+	}
+
+	book, err := h.loans.Checkout(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, repository.ErrBookNotFound):
+		writeProblem(c, problemBookNotFound, "")
+		return
+	case errors.Is(err, service.ErrBookNotAvailable):
+		writeProblem(c, problemBookNotAvailable, "No copies are on the shelf")
+		return
+	case err != nil:
+		writeServerError(c, err, "Could not update book")
+		return
+	}
+	c.IndentedJSON(http.StatusOK, book)
+}
+
+// ReturnBook is PATCH /return?id=. It returns the book's oldest open loan
+// and answers with the book.
+func (h *LoanHandler) ReturnBook(c *gin.Context) {
+	id, ok := c.GetQuery("id")
+
+	if !ok {
+		writeValidationError(c, FieldError{Field: "id", Message: "is required"})
+		return
+	}
+
+	book, err := h.loans.ReturnBook(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, repository.ErrBookNotFound):
+		writeProblem(c, problemBookNotFound, "")
+		return
+	case err != nil:
+		writeServerError(c, err, "Could not update book")
+		return
+	}
+	c.IndentedJSON(http.StatusOK, book)
+}
+
+func (h *LoanHandler) GetLoan(c *gin.Context) {
+	id, ok := loanID(c)
+	if !ok {
+		return
+	}
+	loan, err := h.loans.Loan(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, repository.ErrLoanNotFound):
+		writeProblem(c, problemLoanNotFound, "")
+		return
+	case err != nil:
+		writeServerError(c, err, "")
+		return
+	}
+	c.IndentedJSON(http.StatusOK, loan)
+}
+
+// ReturnLoan brings the loan's copy back. A loan already returned is
+// answered as it is, so the request can safely be retried.
+func (h *LoanHandler) ReturnLoan(c *gin.Context) {
+	id, ok := loanID(c)
+	if !ok {
+		return
+	}
+	loan, err := h.loans.Return(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, repository.ErrLoanNotFound):
+		writeProblem(c, problemLoanNotFound, "")
+		return
+	case errors.Is(err, repository.ErrBookNotFound):
+		writeProblem(c, problemBookNotFound, "The loaned book is no longer in the catalogue")
+		return
+	case err != nil:
+		writeServerError(c, err, "Could not return loan")
+		return
+	}
+	c.Header("Content-Location", loanLocation(c, "/loans/:id/return", loan))
+	c.IndentedJSON(http.StatusOK, loan)
+}
+
+func loanID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		writeProblem(c, problemLoanNotFound, "")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// loanLocation is the URL of loan under the API prefix the request came in
+// on, found by taking route off the request's route.
+func loanLocation(c *gin.Context, route string, loan *model.Loan) string {
+	return fmt.Sprintf("%s/loans/%d", strings.TrimSuffix(c.FullPath(), route), loan.ID)
+}
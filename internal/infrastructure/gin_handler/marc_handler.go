@@ -0,0 +1,99 @@
+package gin_handler
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/infrastructure/marc"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	marcContentType    = "application/marc"
+	marcXMLContentType = "application/marcxml+xml"
+)
+
+// ImportResult lists the books an import created or updated.
+type ImportResult struct {
+	Imported int          `json:"imported"`
+	Books    []model.Book `json:"books"`
+}
+
+// ImportMARC accepts MARC21 binary or MARCXML. Records whose 001 matches an
+// existing book update it (keeping its quantity), the rest are created.
+func (h *BookHandler) ImportMARC(c *gin.Context) {
+	body, ok := readBody(c)
+	if !ok {
+		return
+	}
+
+	var records []marc.Record
+	var err error
+	if isMARCXML(c.ContentType(), body) {
+		records, err = marc.ReadXML(bytes.NewReader(body))
+	} else {
+		records, err = marc.ReadBinary(bytes.NewReader(body))
+	}
+	if err != nil {
+		writeProblem(c, problemInvalidMARC, err.Error())
+		return
+	}
+
+	imported := make([]model.Book, 0, len(records))
+	for _, record := range records {
+		book, err := marc.ToBook(record)
+		if err != nil {
+			writeProblem(c, problemInvalidMARC, err.Error())
+			return
+		}
+
+		if book.ID == "" {
+			book.ID = generateID()
+		}
+		saved, err := h.books.Import(c.Request.Context(), book)
+		if err != nil {
+			writeServerError(c, err, "Could not import book")
+			return
+		}
+		imported = append(imported, *saved)
+	}
+	c.IndentedJSON(http.StatusOK, ImportResult{Imported: len(imported), Books: imported})
+}
+
+func (h *BookHandler) ExportMARCXML(c *gin.Context) {
+	books, err := h.repo.GetAll(c.Request.Context())
+	if err != nil {
+		writeServerError(c, err, "")
+		return
+	}
+
+	records := make([]marc.Record, 0, len(books))
+	for _, book := range books {
+		record, err := marc.FromBook(book)
+		if err != nil {
+			writeServerError(c, err, "Could not export book "+book.ID)
+			return
+		}
+		records = append(records, record)
+	}
+
+	var out bytes.Buffer
+	if err := marc.WriteXML(&out, records); err != nil {
+		writeServerError(c, err, "Could not write MARCXML")
+		return
+	}
+	c.Data(http.StatusOK, marcXMLContentType+"; charset=utf-8", out.Bytes())
+}
+
+func isMARCXML(contentType string, body []byte) bool {
+	switch {
+	case contentType == marcContentType:
+		return false
+	case strings.HasSuffix(contentType, "xml"):
+		return true
+	}
+	return bytes.HasPrefix(bytes.TrimSpace(body), []byte("<"))
+}
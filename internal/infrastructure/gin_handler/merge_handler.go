@@ -0,0 +1,109 @@
+package gin_handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MergeHandler struct {
+	merges *service.MergeService
+}
+
+func NewMergeHandler(merges *service.MergeService) *MergeHandler {
+	return &MergeHandler{merges: merges}
+}
+
+// Duplicates reports pairs of books that look like the same title, scoring
+// at least ?min_score= (0.85 by default).
+func (h *MergeHandler) Duplicates(c *gin.Context) {
+	minScore := service.DefaultMinScore
+	if value, ok := c.GetQuery("min_score"); ok {
+		var err error
+		if minScore, err = strconv.ParseFloat(value, 64); err != nil || minScore < 0 || minScore > 1 {
+			writeValidationError(c, FieldError{Field: "min_score", Message: "must be between 0 and 1"})
+			return
+		}
+	}
+
+	candidates, err := h.merges.Duplicates(c.Request.Context(), minScore)
+	if err != nil {
+		writeServerError(c, err, "")
+		return
+	}
+	c.IndentedJSON(http.StatusOK, candidates)
+}
+
+func (h *MergeHandler) ListMerges(c *gin.Context) {
+	merges, err := h.merges.Merges(c.Request.Context())
+	if err != nil {
+		writeServerError(c, err, "")
+		return
+	}
+	c.IndentedJSON(http.StatusOK, merges)
+}
+
+// MergeRequest is the body of POST /merges.
+type MergeRequest struct {
+	SurvivorID  string `json:"survivor_id" binding:"required"`
+	DuplicateID string `json:"duplicate_id" binding:"required"`
+}
+
+// MergeBooks folds duplicate_id into survivor_id.
+func (h *MergeHandler) MergeBooks(c *gin.Context) {
+	var body MergeRequest
+	if !bindJSON(c, &body) {
+		return
+	}
+
+	merge, err := h.merges.Merge(c.Request.Context(), body.SurvivorID, body.DuplicateID)
+	switch {
+	case errors.Is(err, service.ErrSameBook):
+		writeProblem(c, problemSameBook, "")
+		return
+	case errors.Is(err, repository.ErrBookNotFound):
+		writeProblem(c, problemBookNotFound, "")
+		return
+	case err != nil:
+		writeServerError(c, err, "Could not merge books")
+		return
+	}
+	c.IndentedJSON(http.StatusCreated, merge)
+}
+
+// UndoMerge splits a merge again.
+func (h *MergeHandler) UndoMerge(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		writeProblem(c, problemMergeNotFound, "")
+		return
+	}
+
+	merge, err := h.merges.UndoMerge(c.Request.Context(), uint(id))
+	switch {
+	case errors.Is(err, repository.ErrMergeNotFound):
+		writeProblem(c, problemMergeNotFound, "")
+		return
+	case errors.Is(err, repository.ErrBookNotFound):
+		writeProblem(c, problemBookNotFound, "")
+		return
+	case errors.Is(err, repository.ErrBookExists):
+		writeProblem(c, problemBookExists, "A book with the duplicate's ID exists again")
+		return
+	case errors.Is(err, service.ErrMergeUndone):
+		writeProblem(c, problemMergeUndone, "")
+		return
+	case errors.Is(err, service.ErrCopiesOnLoan):
+		writeProblem(c, problemCopiesOnLoan, "The merged copies are no longer all on the shelf")
+		return
+	case err != nil:
+		writeServerError(c, err, "Could not undo merge")
+		return
+	}
+	c.IndentedJSON(http.StatusOK, merge)
+}
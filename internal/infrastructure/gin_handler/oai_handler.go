@@ -0,0 +1,47 @@
+package gin_handler
+
+import (
+	"net/http"
+
+	"github.com/brianantony456/go-doc/internal/infrastructure/oaipmh"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OAIHandler struct {
+	provider *oaipmh.Provider
+}
+
+func NewOAIHandler(provider *oaipmh.Provider) *OAIHandler {
+	return &OAIHandler{provider: provider}
+}
+
+// Handle serves every OAI-PMH verb, from GET query strings or POSTed forms.
+func (h *OAIHandler) Handle(c *gin.Context) {
+	args := c.Request.URL.Query()
+	if c.Request.Method == http.MethodPost {
+		if err := c.Request.ParseForm(); err != nil {
+			writeProblem(c, problemInvalidRequest, "Could not read form")
+			return
+		}
+		args = c.Request.PostForm
+	}
+
+	resp, err := h.provider.Handle(c.Request.Context(), args, requestBaseURL(c))
+	if err != nil {
+		writeServerError(c, err, "")
+		return
+	}
+	writeXML(c, http.StatusOK, "text/xml", resp)
+}
+
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := c.GetHeader("X-Forwarded-Proto"); forwarded != "" {
+		scheme = forwarded
+	}
+	return scheme + "://" + c.Request.Host + c.Request.URL.Path
+}
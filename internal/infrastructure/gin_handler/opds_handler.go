@@ -0,0 +1,191 @@
+package gin_handler
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/infrastructure/opds"
+
+	"github.com/gin-gonic/gin"
+)
+
+const opdsPageSize = 20
+
+type OPDSHandler struct {
+	repo     repository.BookRepository
+	basePath string
+	apiPath  string
+}
+
+// NewOPDSHandler serves the catalogue under basePath, pointing borrow links
+// at the book's loans under apiPath.
+func NewOPDSHandler(repo repository.BookRepository, basePath, apiPath string) *OPDSHandler {
+	return &OPDSHandler{repo: repo, basePath: basePath, apiPath: apiPath}
+}
+
+// Root is the navigation feed. It is as recent as the latest change to the
+// catalogue.
+func (h *OPDSHandler) Root(c *gin.Context) {
+	books, err := h.repo.GetAll(c.Request.Context())
+	if err != nil {
+		writeServerError(c, err, "")
+		return
+	}
+	updated := lastUpdated(books)
+	feed := opds.NewFeed("urn:go-doc:opds:root", "Library catalogue", updated)
+	feed.Links = []opds.Link{
+		{Rel: "self", Href: h.basePath, Type: opds.NavigationType},
+		{Rel: "start", Href: h.basePath, Type: opds.NavigationType},
+		{Rel: opds.RelSearch, Href: h.basePath + "/opensearch.xml", Type: opds.OpenSearchType},
+	}
+	feed.Entries = []opds.Entry{
+		h.navigationEntry("all", "All books", "Every book in the catalogue", h.basePath+"/books", updated),
+		h.navigationEntry("available", "Available now", "Books with copies on the shelf", h.basePath+"/books?available=true", updated),
+	}
+	writeXML(c, http.StatusOK, opds.NavigationType, feed)
+}
+
+func (h *OPDSHandler) navigationEntry(id, title, text, href string, updated time.Time) opds.Entry {
+	return opds.Entry{
+		ID:      "urn:go-doc:opds:" + id,
+		Title:   title,
+		Updated: opds.FormatTime(updated),
+		Content: &opds.Content{Type: "text", Text: text},
+		Links:   []opds.Link{{Rel: opds.RelSubsection, Href: href, Type: opds.AcquisitionType}},
+	}
+}
+
+// Books is the paginated acquisition feed, ?available=true keeps only books
+// with copies left.
+func (h *OPDSHandler) Books(c *gin.Context) {
+	books, err := h.repo.GetAll(c.Request.Context())
+	if err != nil {
+		writeServerError(c, err, "")
+		return
+	}
+	if c.Query("available") == "true" {
+		books = filterBooks(books, func(b model.Book) bool { return b.Quantity > 0 })
+	}
+	h.writeAcquisitionFeed(c, "urn:go-doc:opds:books", "All books", h.basePath+"/books", books)
+}
+
+func (h *OPDSHandler) Search(c *gin.Context) {
+	query := strings.ToLower(strings.TrimSpace(c.Query("q")))
+	books, err := h.repo.GetAll(c.Request.Context())
+	if err != nil {
+		writeServerError(c, err, "")
+		return
+	}
+	books = filterBooks(books, func(b model.Book) bool {
+		return strings.Contains(strings.ToLower(b.Title), query) ||
+			strings.Contains(strings.ToLower(b.Author), query) ||
+			(b.ISBN != "" && strings.Contains(b.ISBN, query))
+	})
+	title := fmt.Sprintf("Search results for %q", c.Query("q"))
+	h.writeAcquisitionFeed(c, "urn:go-doc:opds:search", title, h.basePath+"/search", books)
+}
+
+func (h *OPDSHandler) Book(c *gin.Context) {
+	book, err := h.repo.GetByID(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, repository.ErrBookNotFound) {
+		writeProblem(c, problemBookNotFound, "")
+		return
+	}
+	if err != nil {
+		writeServerError(c, err, "")
+		return
+	}
+	writeXML(c, http.StatusOK, opds.EntryType, opds.NewEntryDocument(h.bookEntry(*book)))
+}
+
+func (h *OPDSHandler) OpenSearch(c *gin.Context) {
+	description := opds.NewOpenSearchDescription("Catalogue", "Search the library catalogue by title, author or ISBN", h.basePath+"/search?q={searchTerms}")
+	writeXML(c, http.StatusOK, opds.OpenSearchType, description)
+}
+
+func (h *OPDSHandler) writeAcquisitionFeed(c *gin.Context, id, title, path string, books []model.Book) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	lastPage := (len(books) + opdsPageSize - 1) / opdsPageSize
+	if lastPage == 0 {
+		lastPage = 1
+	}
+	if page < 1 || page > lastPage {
+		writeProblem(c, problemPageNotFound, fmt.Sprintf("The feed has %d pages", lastPage))
+		return
+	}
+
+	pageURL := func(n int) string {
+		query := c.Request.URL.Query()
+		query.Set("page", strconv.Itoa(n))
+		return path + "?" + query.Encode()
+	}
+
+	feed := opds.NewFeed(id, title, lastUpdated(books))
+	feed.Links = []opds.Link{
+		{Rel: "self", Href: pageURL(page), Type: opds.AcquisitionType},
+		{Rel: "start", Href: h.basePath, Type: opds.NavigationType},
+		{Rel: "up", Href: h.basePath, Type: opds.NavigationType},
+		{Rel: opds.RelSearch, Href: h.basePath + "/opensearch.xml", Type: opds.OpenSearchType},
+		{Rel: "first", Href: pageURL(1), Type: opds.AcquisitionType},
+		{Rel: "last", Href: pageURL(lastPage), Type: opds.AcquisitionType},
+	}
+	if page > 1 {
+		feed.Links = append(feed.Links, opds.Link{Rel: "previous", Href: pageURL(page - 1), Type: opds.AcquisitionType})
+	}
+	if page < lastPage {
+		feed.Links = append(feed.Links, opds.Link{Rel: "next", Href: pageURL(page + 1), Type: opds.AcquisitionType})
+	}
+
+	start := (page - 1) * opdsPageSize
+	end := min(start+opdsPageSize, len(books))
+	feed.SetPage(len(books), opdsPageSize, start+1)
+	for _, book := range books[start:end] {
+		feed.Entries = append(feed.Entries, h.bookEntry(book))
+	}
+	writeXML(c, http.StatusOK, opds.AcquisitionType, feed)
+}
+
+func (h *OPDSHandler) bookEntry(book model.Book) opds.Entry {
+	return opds.BookEntry(book, book.UpdatedAt,
+		h.basePath+"/books/"+url.PathEscape(book.ID),
+		h.apiPath+"/books/"+url.PathEscape(book.ID)+"/loans")
+}
+
+// lastUpdated is when the most recently changed of books was updated, the
+// zero time when there are none.
+func lastUpdated(books []model.Book) time.Time {
+	var last time.Time
+	for _, book := range books {
+		if book.UpdatedAt.After(last) {
+			last = book.UpdatedAt
+		}
+	}
+	return last
+}
+
+func filterBooks(books []model.Book, keep func(model.Book) bool) []model.Book {
+	filtered := books[:0]
+	for _, book := range books {
+		if keep(book) {
+			filtered = append(filtered, book)
+		}
+	}
+	return filtered
+}
+
+func writeXML(c *gin.Context, status int, contentType string, v interface{}) {
+	out, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		writeServerError(c, err, "")
+		return
+	}
+	c.Data(status, contentType+";charset=utf-8", append([]byte(xml.Header), out...))
+}
@@ -0,0 +1,27 @@
+package gin_handler
+
+import (
+	"net/http"
+
+	"github.com/brianantony456/go-doc/internal/infrastructure/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPIHandler serves the API description and a page to try it out.
+type OpenAPIHandler struct {
+	doc      *openapi.Document
+	explorer []byte
+}
+
+func NewOpenAPIHandler(doc *openapi.Document, explorer []byte) *OpenAPIHandler {
+	return &OpenAPIHandler{doc: doc, explorer: explorer}
+}
+
+func (h *OpenAPIHandler) Spec(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, h.doc)
+}
+
+func (h *OpenAPIHandler) Explorer(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", h.explorer)
+}
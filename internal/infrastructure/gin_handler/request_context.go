@@ -0,0 +1,40 @@
+package gin_handler
+
+import (
+	"github.com/brianantony456/go-doc/internal/domain/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	actorHeader     = "X-Actor"
+	defaultActor    = "api"
+)
+
+// RequestContext tags the request context with a request ID, the client's
+// X-Request-ID or a new one, the actor and the client's IP, so ledger and
+// audit entries can be traced back to the request that made them. The actor
+// is the credential of the API key the request authenticated with, and only
+// requests without one are taken at their X-Actor word.
+func RequestContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if id == "" {
+			id = generateID()
+		}
+		actor := service.Credential(c.Request.Context())
+		if actor == "" {
+			actor = c.GetHeader(actorHeader)
+		}
+		if actor == "" {
+			actor = defaultActor
+		}
+		c.Header(requestIDHeader, id)
+
+		ctx := service.WithRequestID(c.Request.Context(), id)
+		ctx = service.WithActor(ctx, actor)
+		c.Request = c.Request.WithContext(service.WithClientIP(ctx, c.ClientIP()))
+		c.Next()
+	}
+}
@@ -0,0 +1,71 @@
+package gin_handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BookRevisions lists the changes made to a book, oldest first.
+func (h *BookHandler) BookRevisions(c *gin.Context) {
+	revisions, err := h.books.Revisions(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, repository.ErrBookNotFound) {
+		writeProblem(c, problemBookNotFound, "")
+		return
+	}
+	if err != nil {
+		writeServerError(c, err, "")
+		return
+	}
+	c.IndentedJSON(http.StatusOK, revisions)
+}
+
+// RevertRequest is the body of POST /books/:id/revert.
+type RevertRequest struct {
+	Revision         uint `json:"revision" binding:"required"`
+	ExpectedRevision uint `json:"expected_revision"`
+}
+
+// RevertBook restores a book to the revision in the body. If the body also
+// has expected_revision, the revert is refused when the book's catalogue
+// data has changed since that revision.
+func (h *BookHandler) RevertBook(c *gin.Context) {
+	var body RevertRequest
+	if !bindJSON(c, &body) {
+		return
+	}
+
+	book, err := h.books.Revert(c.Request.Context(), c.Param("id"), body.Revision, body.ExpectedRevision)
+	writeRevisionResult(c, book, err)
+}
+
+// UndoLastChange reverts the latest catalogue change made with the request's
+// API key within the undo window.
+func (h *BookHandler) UndoLastChange(c *gin.Context) {
+	book, err := h.books.Undo(c.Request.Context())
+	writeRevisionResult(c, book, err)
+}
+
+func writeRevisionResult(c *gin.Context, book *model.Book, err error) {
+	switch {
+	case errors.Is(err, repository.ErrBookNotFound):
+		writeProblem(c, problemBookNotFound, "")
+	case errors.Is(err, service.ErrRevisionNotFound):
+		writeProblem(c, problemRevisionNotFound, "")
+	case errors.Is(err, service.ErrNothingToUndo):
+		writeProblem(c, problemNothingToUndo, "")
+	case errors.Is(err, service.ErrUndoNeedsAPIKey):
+		writeProblem(c, problemUndoNeedsAPIKey, "")
+	case errors.Is(err, service.ErrEditConflict):
+		writeProblem(c, problemEditConflict, "Reload the book and try again")
+	case err != nil:
+		writeServerError(c, err, "Could not update book")
+	default:
+		c.IndentedJSON(http.StatusOK, book)
+	}
+}
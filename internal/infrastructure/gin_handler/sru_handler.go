@@ -0,0 +1,26 @@
+package gin_handler
+
+import (
+	"net/http"
+
+	"github.com/brianantony456/go-doc/internal/infrastructure/sru"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SRUHandler struct {
+	server *sru.Server
+}
+
+func NewSRUHandler(server *sru.Server) *SRUHandler {
+	return &SRUHandler{server: server}
+}
+
+func (h *SRUHandler) SearchRetrieve(c *gin.Context) {
+	resp, err := h.server.SearchRetrieve(c.Request.Context(), c.Request.URL.Query())
+	if err != nil {
+		writeServerError(c, err, "")
+		return
+	}
+	writeXML(c, http.StatusOK, "application/sru+xml", resp)
+}
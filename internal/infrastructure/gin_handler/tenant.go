@@ -0,0 +1,132 @@
+package gin_handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	tenantHeader = "X-Tenant"
+	apiKeyHeader = "X-API-Key"
+	tenantKey    = "tenant"
+)
+
+// Tenant is one library served by the deployment and how its requests are
+// recognised.
+type Tenant struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Hosts   []string `json:"hosts,omitempty"`
+	APIKeys []string `json:"api_keys,omitempty"`
+}
+
+// TenantScope resolves the tenant of each request from its API key, its
+// X-Tenant header and its host name, and scopes the request context to it.
+// Every way that resolves must name the same tenant, so the header can only
+// pick a tenant on hosts that don't belong to one. With no tenants
+// configured every request belongs to the default tenant.
+//
+// A request that authenticated with an API key carries a fingerprint of the
+// key as its credential, which the audit log records next to the actor.
+func TenantScope(tenants []Tenant) gin.HandlerFunc {
+	byID := map[string]*Tenant{}
+	byHost := map[string]*Tenant{}
+	byKey := map[string]*Tenant{}
+	for i := range tenants {
+		t := &tenants[i]
+		byID[t.ID] = t
+		for _, host := range t.Hosts {
+			byHost[strings.ToLower(host)] = t
+		}
+		for _, key := range t.APIKeys {
+			byKey[key] = t
+		}
+	}
+
+	return func(c *gin.Context) {
+		if len(tenants) == 0 {
+			c.Next()
+			return
+		}
+
+		var resolved *Tenant
+		ctx := c.Request.Context()
+		agree := func(t *Tenant) bool {
+			if resolved != nil && resolved != t {
+				writeProblem(c, problemTenantConflict, "")
+				return false
+			}
+			resolved = t
+			return true
+		}
+		if key := c.GetHeader(apiKeyHeader); key != "" {
+			t, ok := byKey[key]
+			if !ok {
+				writeProblem(c, problemInvalidAPIKey, "")
+				return
+			}
+			agree(t)
+			ctx = service.WithCredential(ctx, keyCredential(key))
+		}
+		if id := c.GetHeader(tenantHeader); id != "" {
+			t, ok := byID[id]
+			if !ok {
+				writeProblem(c, problemUnknownTenant, "")
+				return
+			}
+			if !agree(t) {
+				return
+			}
+		}
+		if t, ok := byHost[requestHost(c.Request)]; ok && !agree(t) {
+			return
+		}
+		if resolved == nil {
+			writeProblem(c, problemUnknownTenant, "")
+			return
+		}
+
+		c.Set(tenantKey, resolved)
+		c.Request = c.Request.WithContext(repository.WithTenant(ctx, resolved.ID))
+		c.Next()
+	}
+}
+
+// keyCredential identifies an API key in the audit log without storing the
+// key itself.
+func keyCredential(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "api-key:" + hex.EncodeToString(sum[:8])
+}
+
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// TenantInfo is the public part of a Tenant.
+type TenantInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// CurrentTenant describes the library the request was made for.
+func CurrentTenant(c *gin.Context) {
+	if t, ok := c.Get(tenantKey); ok {
+		tenant := t.(*Tenant)
+		c.IndentedJSON(http.StatusOK, TenantInfo{ID: tenant.ID, Name: tenant.Name})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, TenantInfo{ID: repository.DefaultTenant})
+}
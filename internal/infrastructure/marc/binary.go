@@ -0,0 +1,166 @@
+package marc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	subfieldDelimiter = 0x1f
+	fieldTerminator   = 0x1e
+	recordTerminator  = 0x1d
+
+	leaderLength         = 24
+	directoryEntryLength = 12
+)
+
+var ErrInvalidRecord = errors.New("invalid MARC record")
+
+// ReadBinary decodes every MARC21 (ISO 2709) record in r.
+func ReadBinary(r io.Reader) ([]Record, error) {
+	br := bufio.NewReader(r)
+	var records []Record
+	for {
+		// Skip whitespace some exporters put between records
+		for {
+			b, err := br.Peek(1)
+			if err == io.EOF {
+				return records, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if b[0] != '\n' && b[0] != '\r' && b[0] != ' ' {
+				break
+			}
+			br.ReadByte()
+		}
+
+		head := make([]byte, 5)
+		if _, err := io.ReadFull(br, head); err != nil {
+			return nil, fmt.Errorf("%w: truncated record length", ErrInvalidRecord)
+		}
+		length, err := strconv.Atoi(string(head))
+		if err != nil || length < leaderLength+1 {
+			return nil, fmt.Errorf("%w: bad record length %q", ErrInvalidRecord, head)
+		}
+		raw := make([]byte, length)
+		copy(raw, head)
+		if _, err := io.ReadFull(br, raw[5:]); err != nil {
+			return nil, fmt.Errorf("%w: record shorter than its length %d", ErrInvalidRecord, length)
+		}
+
+		record, err := decodeBinaryRecord(raw)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(records)+1, err)
+		}
+		records = append(records, *record)
+	}
+}
+
+func decodeBinaryRecord(raw []byte) (*Record, error) {
+	if raw[len(raw)-1] != recordTerminator {
+		return nil, fmt.Errorf("%w: missing record terminator", ErrInvalidRecord)
+	}
+	leader := raw[:leaderLength]
+	base, err := strconv.Atoi(string(leader[12:17]))
+	if err != nil || base <= leaderLength || base > len(raw) {
+		return nil, fmt.Errorf("%w: bad base address %q", ErrInvalidRecord, leader[12:17])
+	}
+
+	record := &Record{Leader: string(leader)}
+	directory := raw[leaderLength : base-1]
+	if len(directory)%directoryEntryLength != 0 {
+		return nil, fmt.Errorf("%w: malformed directory", ErrInvalidRecord)
+	}
+	for i := 0; i < len(directory); i += directoryEntryLength {
+		entry := directory[i : i+directoryEntryLength]
+		tag := string(entry[0:3])
+		length, err1 := strconv.Atoi(string(entry[3:7]))
+		start, err2 := strconv.Atoi(string(entry[7:12]))
+		if err1 != nil || err2 != nil || base+start+length > len(raw) {
+			return nil, fmt.Errorf("%w: bad directory entry for tag %s", ErrInvalidRecord, tag)
+		}
+		data := bytes.TrimSuffix(raw[base+start:base+start+length], []byte{fieldTerminator})
+
+		if isControlTag(tag) {
+			record.ControlFields = append(record.ControlFields, ControlField{Tag: tag, Value: string(data)})
+			continue
+		}
+		record.DataFields = append(record.DataFields, decodeBinaryDataField(tag, data))
+	}
+	return record, nil
+}
+
+func decodeBinaryDataField(tag string, data []byte) DataField {
+	field := DataField{Tag: tag, Ind1: " ", Ind2: " "}
+	if len(data) >= 2 {
+		field.Ind1, field.Ind2 = string(data[0]), string(data[1])
+		data = data[2:]
+	}
+	for _, chunk := range bytes.Split(data, []byte{subfieldDelimiter}) {
+		if len(chunk) == 0 {
+			continue
+		}
+		field.Subfields = append(field.Subfields, Subfield{Code: string(chunk[0]), Value: string(chunk[1:])})
+	}
+	return field
+}
+
+// WriteBinary encodes records as MARC21 (ISO 2709).
+func WriteBinary(w io.Writer, records []Record) error {
+	for _, record := range records {
+		if _, err := w.Write(encodeBinaryRecord(record)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeBinaryRecord(record Record) []byte {
+	var directory, data bytes.Buffer
+	addField := func(tag string, body []byte) {
+		body = append(body, fieldTerminator)
+		fmt.Fprintf(&directory, "%3s%04d%05d", tag, len(body), data.Len())
+		data.Write(body)
+	}
+	for _, f := range record.ControlFields {
+		addField(f.Tag, []byte(f.Value))
+	}
+	for _, f := range record.DataFields {
+		body := []byte(indicator(f.Ind1) + indicator(f.Ind2))
+		for _, s := range f.Subfields {
+			body = append(body, subfieldDelimiter)
+			body = append(body, s.Code...)
+			body = append(body, s.Value...)
+		}
+		addField(f.Tag, body)
+	}
+	directory.WriteByte(fieldTerminator)
+
+	leader := []byte(record.Leader)
+	if len(leader) != leaderLength {
+		leader = []byte(defaultLeader)
+	}
+	base := leaderLength + directory.Len()
+	total := base + data.Len() + 1
+	copy(leader[0:5], fmt.Sprintf("%05d", total))
+	copy(leader[12:17], fmt.Sprintf("%05d", base))
+
+	out := make([]byte, 0, total)
+	out = append(out, leader...)
+	out = append(out, directory.Bytes()...)
+	out = append(out, data.Bytes()...)
+	return append(out, recordTerminator)
+}
+
+func indicator(ind string) string {
+	if len(ind) != 1 {
+		return " "
+	}
+	return ind
+}
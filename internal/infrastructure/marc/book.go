@@ -0,0 +1,153 @@
+package marc
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+)
+
+var yearPattern = regexp.MustCompile(`\d{4}`)
+
+// ToBook maps the catalogued fields of a record onto a book and keeps the
+// whole record in MarcRecord:
+//
+//	001      -> ID
+//	020 $a   -> ISBN
+//	100 $a   -> Author (falling back to 110 $a, then 700 $a)
+//	245 $a$b -> Title
+//	264/260  -> Publisher ($b) and Year ($c)
+func ToBook(record Record) (model.Book, error) {
+	raw, err := MarshalRecord(record)
+	if err != nil {
+		return model.Book{}, err
+	}
+	book := mappedFields(&record)
+	book.MarcRecord = raw
+	return book, nil
+}
+
+// FromBook builds the record for a book. When the book was imported, its
+// stored record is the starting point so unmapped fields survive, and a
+// mapped field is only rewritten if the book no longer matches it.
+func FromBook(book model.Book) (Record, error) {
+	record := NewRecord()
+	if book.MarcRecord != "" {
+		stored, err := UnmarshalRecord(book.MarcRecord)
+		if err != nil {
+			return Record{}, err
+		}
+		record = stored
+	}
+	current := mappedFields(record)
+
+	if book.ID != "" && current.ID != book.ID {
+		record.SetControlField("001", book.ID)
+	}
+	if current.ISBN != book.ISBN {
+		setSubfield(record, "020", "a", book.ISBN)
+	}
+	if current.Author != book.Author {
+		setSubfield(record, "100", "a", book.Author)
+	}
+	if current.Title != book.Title {
+		if f := record.Field("245"); f != nil {
+			removeSubfield(f, "b")
+		}
+		setSubfield(record, "245", "a", book.Title)
+	}
+	publication := "264"
+	if record.Field("264") == nil && record.Field("260") != nil {
+		publication = "260"
+	}
+	if current.Publisher != book.Publisher {
+		setSubfield(record, publication, "b", book.Publisher)
+	}
+	if current.Year != book.Year {
+		setSubfield(record, publication, "c", book.Year)
+	}
+	return *record, nil
+}
+
+func mappedFields(record *Record) model.Book {
+	book := model.Book{
+		ID:   strings.TrimSpace(record.ControlField("001")),
+		ISBN: normalizeISBN(record.SubfieldValue("020", "a")),
+	}
+
+	for _, tag := range []string{"100", "110", "700"} {
+		if author := trimPunctuation(record.SubfieldValue(tag, "a")); author != "" {
+			book.Author = author
+			break
+		}
+	}
+
+	if f := record.Field("245"); f != nil {
+		title := trimPunctuation(f.Subfield("a"))
+		if subtitle := trimPunctuation(f.Subfield("b")); subtitle != "" {
+			title += ": " + subtitle
+		}
+		book.Title = title
+	}
+
+	for _, tag := range []string{"264", "260"} {
+		if f := record.Field(tag); f != nil {
+			book.Publisher = trimPunctuation(f.Subfield("b"))
+			book.Year = yearPattern.FindString(f.Subfield("c"))
+			break
+		}
+	}
+	return book
+}
+
+func setSubfield(record *Record, tag, code, value string) {
+	field := record.Field(tag)
+	if field == nil {
+		if value != "" {
+			record.SetField(DataField{Tag: tag, Ind1: " ", Ind2: " ", Subfields: []Subfield{{Code: code, Value: value}}})
+		}
+		return
+	}
+	if value == "" {
+		removeSubfield(field, code)
+		return
+	}
+	for i := range field.Subfields {
+		if field.Subfields[i].Code == code {
+			field.Subfields[i].Value = value
+			return
+		}
+	}
+	i := 0
+	for i < len(field.Subfields) && field.Subfields[i].Code < code {
+		i++
+	}
+	field.Subfields = append(field.Subfields, Subfield{})
+	copy(field.Subfields[i+1:], field.Subfields[i:])
+	field.Subfields[i] = Subfield{Code: code, Value: value}
+}
+
+func removeSubfield(field *DataField, code string) {
+	subfields := field.Subfields[:0]
+	for _, s := range field.Subfields {
+		if s.Code != code {
+			subfields = append(subfields, s)
+		}
+	}
+	field.Subfields = subfields
+}
+
+// normalizeISBN drops qualifiers such as "(pbk.)" and hyphens.
+func normalizeISBN(value string) string {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ReplaceAll(fields[0], "-", "")
+}
+
+// trimPunctuation removes the ISBD punctuation cataloguers leave at the end
+// of subfields, e.g. "Austen, Jane," or "Pride and prejudice /".
+func trimPunctuation(value string) string {
+	return strings.TrimSpace(strings.TrimRight(strings.TrimSpace(value), " /:;,."))
+}
@@ -0,0 +1,117 @@
+package marc
+
+import (
+	"sort"
+	"strings"
+)
+
+const defaultLeader = "00000nam a2200000 a 4500"
+
+type Record struct {
+	Leader        string
+	ControlFields []ControlField
+	DataFields    []DataField
+}
+
+type ControlField struct {
+	Tag   string
+	Value string
+}
+
+type DataField struct {
+	Tag       string
+	Ind1      string
+	Ind2      string
+	Subfields []Subfield
+}
+
+type Subfield struct {
+	Code  string
+	Value string
+}
+
+func NewRecord() *Record {
+	return &Record{Leader: defaultLeader}
+}
+
+// ControlField returns the value of the first control field with the tag.
+func (r *Record) ControlField(tag string) string {
+	for _, f := range r.ControlFields {
+		if f.Tag == tag {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+func (r *Record) SetControlField(tag, value string) {
+	for i := range r.ControlFields {
+		if r.ControlFields[i].Tag == tag {
+			r.ControlFields[i].Value = value
+			return
+		}
+	}
+	r.ControlFields = append(r.ControlFields, ControlField{Tag: tag, Value: value})
+	sort.SliceStable(r.ControlFields, func(i, j int) bool {
+		return r.ControlFields[i].Tag < r.ControlFields[j].Tag
+	})
+}
+
+// Field returns the first data field with the tag, or nil.
+func (r *Record) Field(tag string) *DataField {
+	for i := range r.DataFields {
+		if r.DataFields[i].Tag == tag {
+			return &r.DataFields[i]
+		}
+	}
+	return nil
+}
+
+// SubfieldValue returns the first $code of the first field with the tag.
+func (r *Record) SubfieldValue(tag, code string) string {
+	if f := r.Field(tag); f != nil {
+		return f.Subfield(code)
+	}
+	return ""
+}
+
+// SetField replaces the first data field with the same tag, or inserts the
+// field keeping data fields in tag order.
+func (r *Record) SetField(field DataField) {
+	for i := range r.DataFields {
+		if r.DataFields[i].Tag == field.Tag {
+			r.DataFields[i] = field
+			return
+		}
+	}
+	i := 0
+	for i < len(r.DataFields) && r.DataFields[i].Tag <= field.Tag {
+		i++
+	}
+	r.DataFields = append(r.DataFields, DataField{})
+	copy(r.DataFields[i+1:], r.DataFields[i:])
+	r.DataFields[i] = field
+}
+
+func (r *Record) RemoveField(tag string) {
+	fields := r.DataFields[:0]
+	for _, f := range r.DataFields {
+		if f.Tag != tag {
+			fields = append(fields, f)
+		}
+	}
+	r.DataFields = fields
+}
+
+func (f *DataField) Subfield(code string) string {
+	for _, s := range f.Subfields {
+		if s.Code == code {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+func isControlTag(tag string) bool {
+	return strings.HasPrefix(tag, "00")
+}
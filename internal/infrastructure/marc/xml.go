@@ -0,0 +1,130 @@
+package marc
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+const Namespace = "http://www.loc.gov/MARC21/slim"
+
+type xmlCollection struct {
+	XMLName xml.Name    `xml:"collection"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	Records []xmlRecord `xml:"record"`
+}
+
+type xmlRecord struct {
+	XMLName       xml.Name          `xml:"record"`
+	Xmlns         string            `xml:"xmlns,attr,omitempty"`
+	Leader        string            `xml:"leader"`
+	ControlFields []xmlControlField `xml:"controlfield"`
+	DataFields    []xmlDataField    `xml:"datafield"`
+}
+
+type xmlControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlDataField struct {
+	Tag       string        `xml:"tag,attr"`
+	Ind1      string        `xml:"ind1,attr"`
+	Ind2      string        `xml:"ind2,attr"`
+	Subfields []xmlSubfield `xml:"subfield"`
+}
+
+type xmlSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// ReadXML decodes MARCXML records, either a <collection> or a bare <record>.
+func ReadXML(r io.Reader) ([]Record, error) {
+	decoder := xml.NewDecoder(r)
+	var records []Record
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRecord, err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "record" {
+			continue
+		}
+		var x xmlRecord
+		if err := decoder.DecodeElement(&x, &start); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRecord, err)
+		}
+		records = append(records, fromXMLRecord(x))
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: no record elements found", ErrInvalidRecord)
+	}
+	return records, nil
+}
+
+// WriteXML encodes records as a MARCXML <collection>.
+func WriteXML(w io.Writer, records []Record) error {
+	collection := xmlCollection{Xmlns: Namespace}
+	for _, record := range records {
+		collection.Records = append(collection.Records, toXMLRecord(record))
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(collection)
+}
+
+// MarshalRecord encodes a single record as a standalone MARCXML <record>.
+func MarshalRecord(record Record) (string, error) {
+	x := toXMLRecord(record)
+	x.Xmlns = Namespace
+	out, err := xml.Marshal(x)
+	return string(out), err
+}
+
+// UnmarshalRecord decodes a single record stored by MarshalRecord.
+func UnmarshalRecord(data string) (*Record, error) {
+	var x xmlRecord
+	if err := xml.Unmarshal([]byte(data), &x); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRecord, err)
+	}
+	record := fromXMLRecord(x)
+	return &record, nil
+}
+
+func fromXMLRecord(x xmlRecord) Record {
+	record := Record{Leader: x.Leader}
+	for _, f := range x.ControlFields {
+		record.ControlFields = append(record.ControlFields, ControlField{Tag: f.Tag, Value: f.Value})
+	}
+	for _, f := range x.DataFields {
+		field := DataField{Tag: f.Tag, Ind1: indicator(f.Ind1), Ind2: indicator(f.Ind2)}
+		for _, s := range f.Subfields {
+			field.Subfields = append(field.Subfields, Subfield{Code: s.Code, Value: s.Value})
+		}
+		record.DataFields = append(record.DataFields, field)
+	}
+	return record
+}
+
+func toXMLRecord(record Record) xmlRecord {
+	x := xmlRecord{Leader: record.Leader}
+	for _, f := range record.ControlFields {
+		x.ControlFields = append(x.ControlFields, xmlControlField{Tag: f.Tag, Value: f.Value})
+	}
+	for _, f := range record.DataFields {
+		field := xmlDataField{Tag: f.Tag, Ind1: indicator(f.Ind1), Ind2: indicator(f.Ind2)}
+		for _, s := range f.Subfields {
+			field.Subfields = append(field.Subfields, xmlSubfield{Code: s.Code, Value: s.Value})
+		}
+		x.DataFields = append(x.DataFields, field)
+	}
+	return x
+}
@@ -0,0 +1,411 @@
+package oaipmh
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/infrastructure/dublincore"
+)
+
+const (
+	dayGranularity    = "2006-01-02"
+	secondGranularity = "2006-01-02T15:04:05Z"
+	defaultPageSize   = 100
+)
+
+type Config struct {
+	RepositoryName string
+	AdminEmail     string
+	// RepositoryIdentifier is the namespace part of oai:<id>:<book id>
+	// identifiers, usually the repository's domain name.
+	RepositoryIdentifier string
+	PageSize             int
+}
+
+// Provider answers OAI-PMH requests from the book repository. Datestamps
+// come from the books' UpdatedAt. Deleted books are found in the history
+// and kept for good as deleted records.
+type Provider struct {
+	repo    repository.BookRepository
+	history repository.BookHistoryRepository
+	config  Config
+}
+
+func NewProvider(repo repository.BookRepository, history repository.BookHistoryRepository, config Config) *Provider {
+	if config.PageSize <= 0 {
+		config.PageSize = defaultPageSize
+	}
+	return &Provider{repo: repo, history: history, config: config}
+}
+
+var verbArguments = map[string]struct{ required, optional []string }{
+	"Identify":            {},
+	"ListMetadataFormats": {optional: []string{"identifier"}},
+	"ListSets":            {optional: []string{"resumptionToken"}},
+	"ListIdentifiers":     {required: []string{"metadataPrefix"}, optional: []string{"from", "until", "set"}},
+	"ListRecords":         {required: []string{"metadataPrefix"}, optional: []string{"from", "until", "set"}},
+	"GetRecord":           {required: []string{"identifier", "metadataPrefix"}},
+}
+
+// Handle answers one request, given the arguments from the query string or
+// the form body, and the URL the request was made to. Protocol errors are part
+// of the response; the error is only set when the repository fails.
+func (p *Provider) Handle(ctx context.Context, args url.Values, baseURL string) (*Response, error) {
+	resp := &Response{
+		Xmlns:          Namespace,
+		XmlnsXSI:       dublincore.XSINamespace,
+		SchemaLocation: schemaLocation,
+		ResponseDate:   time.Now().UTC().Format(secondGranularity),
+		Request:        Request{BaseURL: baseURL},
+	}
+
+	verb := args.Get("verb")
+	rules, ok := verbArguments[verb]
+	if !ok || len(args["verb"]) > 1 {
+		return resp.fail(Error{BadVerb, "Illegal OAI verb"}), nil
+	}
+	if err := checkArguments(verb, args, rules.required, rules.optional); err != nil {
+		return resp.fail(*err), nil
+	}
+	for key := range args {
+		resp.Request.Attrs = append(resp.Request.Attrs, xml.Attr{Name: xml.Name{Local: key}, Value: args.Get(key)})
+	}
+	sort.Slice(resp.Request.Attrs, func(i, j int) bool {
+		return resp.Request.Attrs[i].Name.Local < resp.Request.Attrs[j].Name.Local
+	})
+
+	var err error
+	switch verb {
+	case "Identify":
+		err = p.identify(ctx, resp, baseURL)
+	case "ListMetadataFormats":
+		err = p.listMetadataFormats(ctx, resp, args.Get("identifier"))
+	case "ListSets":
+		err = Error{NoSetHierarchy, "This repository does not support sets"}
+		if args.Has("resumptionToken") {
+			err = Error{BadResumptionToken, "Invalid resumption token"}
+		}
+	case "ListIdentifiers", "ListRecords":
+		err = p.list(ctx, resp, verb, args)
+	case "GetRecord":
+		err = p.getRecord(ctx, resp, args.Get("identifier"), args.Get("metadataPrefix"))
+	}
+	var oaiErr Error
+	if errors.As(err, &oaiErr) {
+		return resp.fail(oaiErr), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (r *Response) fail(err Error) *Response {
+	r.Errors = append(r.Errors, err)
+	if err.Code == BadVerb || err.Code == BadArgument {
+		// The spec forbids echoing arguments of a request we couldn't parse
+		r.Request.Attrs = nil
+	}
+	return r
+}
+
+func checkArguments(verb string, args url.Values, required, optional []string) *Error {
+	allowed := map[string]bool{"verb": true}
+	for _, name := range append(required, optional...) {
+		allowed[name] = true
+	}
+	if verb == "ListIdentifiers" || verb == "ListRecords" {
+		allowed["resumptionToken"] = true
+	}
+	for name, values := range args {
+		if !allowed[name] {
+			return &Error{BadArgument, "Illegal argument " + name}
+		}
+		if len(values) > 1 {
+			return &Error{BadArgument, "Repeated argument " + name}
+		}
+	}
+	if args.Has("resumptionToken") {
+		if len(args) > 2 {
+			return &Error{BadArgument, "resumptionToken is an exclusive argument"}
+		}
+		return nil
+	}
+	for _, name := range required {
+		if args.Get(name) == "" {
+			return &Error{BadArgument, "Missing required argument " + name}
+		}
+	}
+	return nil
+}
+
+func (p *Provider) identify(ctx context.Context, resp *Response, baseURL string) error {
+	books, _, err := p.records(ctx)
+	if err != nil {
+		return err
+	}
+	earliest := time.Unix(0, 0)
+	for i, book := range books {
+		if i == 0 || book.UpdatedAt.Before(earliest) {
+			earliest = book.UpdatedAt
+		}
+	}
+	resp.Identify = &Identify{
+		RepositoryName:    p.config.RepositoryName,
+		BaseURL:           baseURL,
+		ProtocolVersion:   "2.0",
+		AdminEmail:        p.config.AdminEmail,
+		EarliestDatestamp: datestamp(earliest),
+		DeletedRecord:     "persistent",
+		Granularity:       "YYYY-MM-DDThh:mm:ssZ",
+	}
+	return nil
+}
+
+var oaiDC = MetadataFormat{
+	Prefix:    "oai_dc",
+	Schema:    dublincore.OAIDCSchema,
+	Namespace: dublincore.OAIDCNamespace,
+}
+
+func (p *Provider) listMetadataFormats(ctx context.Context, resp *Response, identifier string) error {
+	if identifier != "" {
+		if _, _, err := p.book(ctx, identifier); err != nil {
+			return err
+		}
+	}
+	resp.ListMetadataFormats = &ListMetadataFormats{Formats: []MetadataFormat{oaiDC}}
+	return nil
+}
+
+func (p *Provider) getRecord(ctx context.Context, resp *Response, identifier, prefix string) error {
+	if prefix != oaiDC.Prefix {
+		return Error{CannotDisseminateFormat, "Unsupported metadata format " + prefix}
+	}
+	book, deleted, err := p.book(ctx, identifier)
+	if err != nil {
+		return err
+	}
+	resp.GetRecord = &GetRecord{Record: p.record(*book, deleted)}
+	return nil
+}
+
+func (p *Provider) list(ctx context.Context, resp *Response, verb string, args url.Values) error {
+	state := resumptionState{Prefix: args.Get("metadataPrefix"), From: args.Get("from"), Until: args.Get("until")}
+	if token := args.Get("resumptionToken"); token != "" {
+		var ok bool
+		if state, ok = decodeToken(token); !ok {
+			return Error{BadResumptionToken, "Invalid resumption token"}
+		}
+	}
+	if args.Has("set") {
+		return Error{NoSetHierarchy, "This repository does not support sets"}
+	}
+	if state.Prefix != oaiDC.Prefix {
+		return Error{CannotDisseminateFormat, "Unsupported metadata format " + state.Prefix}
+	}
+	from, until, rangeErr := parseRange(state.From, state.Until)
+	if rangeErr != nil {
+		return *rangeErr
+	}
+
+	if state.Frozen != 0 {
+		until = time.Unix(state.Frozen, 0).UTC()
+	} else if until.IsZero() {
+		until = time.Now().UTC().Truncate(time.Second)
+	}
+
+	books, deleted, err := p.records(ctx)
+	if err != nil {
+		return err
+	}
+	books = selectBooks(books, from, until)
+	if len(books) == 0 {
+		return Error{NoRecordsMatch, "No records match the request"}
+	}
+	start := 0
+	if state.ID != "" {
+		last := time.Unix(state.Stamp, 0).UTC()
+		start = sort.Search(len(books), func(i int) bool {
+			stamp := modified(books[i])
+			return stamp.After(last) || (stamp.Equal(last) && books[i].ID > state.ID)
+		})
+		if start == len(books) {
+			return Error{BadResumptionToken, "Resumption token is past the end of the list"}
+		}
+	}
+
+	end := min(start+p.config.PageSize, len(books))
+	page := books[start:end]
+	var token *ResumptionToken
+	if state.ID != "" || end < len(books) {
+		token = &ResumptionToken{CompleteListSize: len(books), Cursor: state.Sent}
+		if end < len(books) {
+			next := state
+			next.Frozen = until.Unix()
+			next.Stamp = modified(page[len(page)-1]).Unix()
+			next.ID = page[len(page)-1].ID
+			next.Sent += len(page)
+			token.Token = encodeToken(next)
+		}
+	}
+
+	if verb == "ListIdentifiers" {
+		list := &ListIdentifiers{ResumptionToken: token}
+		for _, book := range page {
+			list.Headers = append(list.Headers, p.header(book, deleted[book.ID]))
+		}
+		resp.ListIdentifiers = list
+		return nil
+	}
+	list := &ListRecords{ResumptionToken: token}
+	for _, book := range page {
+		list.Records = append(list.Records, p.record(book, deleted[book.ID]))
+	}
+	resp.ListRecords = list
+	return nil
+}
+
+// selectBooks keeps the books modified within [from, until] ordered by
+// datestamp and then ID, the order resumption tokens continue from.
+func selectBooks(books []model.Book, from, until time.Time) []model.Book {
+	selected := books[:0]
+	for _, book := range books {
+		stamp := modified(book)
+		if (!from.IsZero() && stamp.Before(from)) || (!until.IsZero() && stamp.After(until)) {
+			continue
+		}
+		selected = append(selected, book)
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		a, b := modified(selected[i]), modified(selected[j])
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return selected[i].ID < selected[j].ID
+	})
+	return selected
+}
+
+func parseRange(fromArg, untilArg string) (from, until time.Time, err *Error) {
+	from, fromDay, err := parseDate(fromArg)
+	if err != nil {
+		return
+	}
+	until, untilDay, err := parseDate(untilArg)
+	if err != nil {
+		return
+	}
+	if fromArg != "" && untilArg != "" && fromDay != untilDay {
+		err = &Error{BadArgument, "from and until must have the same granularity"}
+		return
+	}
+	if untilDay {
+		until = until.Add(24*time.Hour - time.Second)
+	}
+	if !from.IsZero() && !until.IsZero() && from.After(until) {
+		err = &Error{BadArgument, "from must not be later than until"}
+	}
+	return
+}
+
+func parseDate(value string) (t time.Time, isDay bool, err *Error) {
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+	if t, parseErr := time.Parse(secondGranularity, value); parseErr == nil {
+		return t, false, nil
+	}
+	if t, parseErr := time.Parse(dayGranularity, value); parseErr == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, &Error{BadArgument, "Invalid date " + value}
+}
+
+func (p *Provider) identifierPrefix() string {
+	return "oai:" + p.config.RepositoryIdentifier + ":"
+}
+
+// records returns the books together with tombstones for the deleted ones,
+// and the IDs of those deleted. A tombstone is a book with just its ID and
+// its deletion time as UpdatedAt.
+func (p *Provider) records(ctx context.Context) ([]model.Book, map[string]bool, error) {
+	books, err := p.repo.GetAll(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	deletions, err := p.history.Deletions(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	deleted := map[string]bool{}
+	for _, d := range deletions {
+		deleted[d.BookID] = true
+		books = append(books, tombstone(d))
+	}
+	return books, deleted, nil
+}
+
+// book finds the book an identifier names, or its tombstone, reporting
+// whether it was deleted.
+func (p *Provider) book(ctx context.Context, identifier string) (*model.Book, bool, error) {
+	id, ok := strings.CutPrefix(identifier, p.identifierPrefix())
+	if !ok {
+		return nil, false, Error{IDDoesNotExist, "Unknown identifier " + identifier}
+	}
+	book, err := p.repo.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrBookNotFound) {
+		deletions, err := p.history.Deletions(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, d := range deletions {
+			if d.BookID == id {
+				book := tombstone(d)
+				return &book, true, nil
+			}
+		}
+		return nil, false, Error{IDDoesNotExist, "Unknown identifier " + identifier}
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return book, false, nil
+}
+
+func tombstone(d repository.Deletion) model.Book {
+	return model.Book{ID: d.BookID, UpdatedAt: d.DeletedAt}
+}
+
+func (p *Provider) header(book model.Book, deleted bool) Header {
+	header := Header{Identifier: p.identifierPrefix() + book.ID, Datestamp: datestamp(book.UpdatedAt)}
+	if deleted {
+		header.Status = "deleted"
+	}
+	return header
+}
+
+// record is the book's record. Deleted records have a header only.
+func (p *Provider) record(book model.Book, deleted bool) Record {
+	if deleted {
+		return Record{Header: p.header(book, true)}
+	}
+	return Record{Header: p.header(book, false), Metadata: &Metadata{DC: dublincore.FromBook(book)}}
+}
+
+func datestamp(t time.Time) string {
+	return t.UTC().Format(secondGranularity)
+}
+
+// modified is the book's datestamp as a time, at the second granularity the
+// protocol exposes.
+func modified(book model.Book) time.Time {
+	return book.UpdatedAt.UTC().Truncate(time.Second)
+}
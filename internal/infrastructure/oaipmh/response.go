@@ -0,0 +1,123 @@
+package oaipmh
+
+import (
+	"encoding/xml"
+
+	"github.com/brianantony456/go-doc/internal/infrastructure/dublincore"
+)
+
+const (
+	Namespace      = "http://www.openarchives.org/OAI/2.0/"
+	schemaLocation = Namespace + " http://www.openarchives.org/OAI/2.0/OAI-PMH.xsd"
+)
+
+// Error codes defined by the OAI-PMH specification.
+const (
+	BadArgument             = "badArgument"
+	BadResumptionToken      = "badResumptionToken"
+	BadVerb                 = "badVerb"
+	CannotDisseminateFormat = "cannotDisseminateFormat"
+	IDDoesNotExist          = "idDoesNotExist"
+	NoRecordsMatch          = "noRecordsMatch"
+	NoMetadataFormats       = "noMetadataFormats"
+	NoSetHierarchy          = "noSetHierarchy"
+)
+
+type Response struct {
+	XMLName        xml.Name `xml:"OAI-PMH"`
+	Xmlns          string   `xml:"xmlns,attr"`
+	XmlnsXSI       string   `xml:"xmlns:xsi,attr"`
+	SchemaLocation string   `xml:"xsi:schemaLocation,attr"`
+
+	ResponseDate string  `xml:"responseDate"`
+	Request      Request `xml:"request"`
+	Errors       []Error `xml:"error"`
+
+	Identify            *Identify            `xml:"Identify,omitempty"`
+	ListMetadataFormats *ListMetadataFormats `xml:"ListMetadataFormats,omitempty"`
+	ListSets            *ListSets            `xml:"ListSets,omitempty"`
+	ListIdentifiers     *ListIdentifiers     `xml:"ListIdentifiers,omitempty"`
+	ListRecords         *ListRecords         `xml:"ListRecords,omitempty"`
+	GetRecord           *GetRecord           `xml:"GetRecord,omitempty"`
+}
+
+type Request struct {
+	Attrs   []xml.Attr `xml:",any,attr"`
+	BaseURL string     `xml:",chardata"`
+}
+
+type Error struct {
+	Code    string `xml:"code,attr"`
+	Message string `xml:",chardata"`
+}
+
+func (e Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+type Identify struct {
+	RepositoryName    string `xml:"repositoryName"`
+	BaseURL           string `xml:"baseURL"`
+	ProtocolVersion   string `xml:"protocolVersion"`
+	AdminEmail        string `xml:"adminEmail"`
+	EarliestDatestamp string `xml:"earliestDatestamp"`
+	DeletedRecord     string `xml:"deletedRecord"`
+	Granularity       string `xml:"granularity"`
+}
+
+type ListMetadataFormats struct {
+	Formats []MetadataFormat `xml:"metadataFormat"`
+}
+
+type MetadataFormat struct {
+	Prefix    string `xml:"metadataPrefix"`
+	Schema    string `xml:"schema"`
+	Namespace string `xml:"metadataNamespace"`
+}
+
+type ListSets struct {
+	Sets []Set `xml:"set"`
+}
+
+type Set struct {
+	Spec string `xml:"setSpec"`
+	Name string `xml:"setName"`
+}
+
+type ListIdentifiers struct {
+	Headers         []Header         `xml:"header"`
+	ResumptionToken *ResumptionToken `xml:"resumptionToken,omitempty"`
+}
+
+type ListRecords struct {
+	Records         []Record         `xml:"record"`
+	ResumptionToken *ResumptionToken `xml:"resumptionToken,omitempty"`
+}
+
+type GetRecord struct {
+	Record Record `xml:"record"`
+}
+
+type Record struct {
+	Header   Header    `xml:"header"`
+	Metadata *Metadata `xml:"metadata,omitempty"`
+}
+
+type Header struct {
+	// Status is "deleted" for the tombstones of deleted books
+	Status     string `xml:"status,attr,omitempty"`
+	Identifier string `xml:"identifier"`
+	Datestamp  string `xml:"datestamp"`
+}
+
+type Metadata struct {
+	DC *dublincore.Record
+}
+
+// ResumptionToken is always present on a page of an incomplete list; the
+// last page carries an empty token to mark the end.
+type ResumptionToken struct {
+	CompleteListSize int    `xml:"completeListSize,attr"`
+	Cursor           int    `xml:"cursor,attr"`
+	Token            string `xml:",chardata"`
+}
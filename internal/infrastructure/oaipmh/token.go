@@ -0,0 +1,42 @@
+package oaipmh
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// resumptionState is everything needed to continue a list request. It is
+// handed to the harvester as an opaque token so the provider stays
+// stateless.
+//
+// The position is the datestamp and ID of the last record sent rather than
+// an offset, so a book that changes mid-harvest cannot shift the records
+// behind it onto a page that was already served. Until is frozen at the
+// first request for the same reason: a book updated during the harvest
+// leaves the list instead of reappearing at its end.
+type resumptionState struct {
+	Prefix string `json:"p"`
+	From   string `json:"f,omitempty"`
+	Until  string `json:"u,omitempty"`
+	// Frozen is the effective upper bound of the list in Unix seconds.
+	Frozen int64 `json:"t"`
+	// Stamp and ID identify the last record of the previous page; Sent
+	// counts the records sent so far and is only reported back as cursor.
+	Stamp int64  `json:"s"`
+	ID    string `json:"i"`
+	Sent  int    `json:"n"`
+}
+
+func encodeToken(state resumptionState) string {
+	data, _ := json.Marshal(state)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeToken(token string) (resumptionState, bool) {
+	var state resumptionState
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || json.Unmarshal(data, &state) != nil || state.Prefix == "" || state.ID == "" || state.Frozen == 0 {
+		return resumptionState{}, false
+	}
+	return state, true
+}
@@ -0,0 +1,54 @@
+package opds
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+)
+
+// BookEntry builds the acquisition entry for a book. Borrowing goes through
+// the loans API, which answers in JSON, so the borrow link is typed LoanType.
+// The availability indicator follows Quantity.
+func BookEntry(book model.Book, updated time.Time, entryHref, borrowHref string) Entry {
+	entry := Entry{
+		ID:        "urn:go-doc:book:" + book.ID,
+		Title:     book.Title,
+		Updated:   FormatTime(updated),
+		Publisher: book.Publisher,
+		Issued:    book.Year,
+		Content:   &Content{Type: "text", Text: summary(book)},
+		Links: []Link{
+			{Rel: "alternate", Href: entryHref, Type: EntryType},
+		},
+	}
+	if book.Author != "" {
+		entry.Authors = []Person{{Name: book.Author}}
+	}
+	if book.ISBN != "" {
+		entry.Identifier = "urn:isbn:" + book.ISBN
+	}
+
+	status := "available"
+	if book.Quantity <= 0 {
+		status = "unavailable"
+	}
+	entry.Links = append(entry.Links, Link{
+		Rel:          RelBorrow,
+		Href:         borrowHref,
+		Type:         LoanType,
+		Availability: &Availability{Status: status},
+		Copies:       &Copies{Available: max(book.Quantity, 0)},
+	})
+	return entry
+}
+
+func summary(book model.Book) string {
+	if book.Quantity <= 0 {
+		return "No copies available"
+	}
+	if book.Quantity == 1 {
+		return "1 copy available"
+	}
+	return fmt.Sprintf("%d copies available", book.Quantity)
+}
@@ -0,0 +1,147 @@
+package opds
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+const (
+	AtomNamespace       = "http://www.w3.org/2005/Atom"
+	OPDSNamespace       = "http://opds-spec.org/2010/catalog"
+	OpenSearchNamespace = "http://a9.com/-/spec/opensearch/1.1/"
+	DCTermsNamespace    = "http://purl.org/dc/terms/"
+
+	NavigationType  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	AcquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	EntryType       = "application/atom+xml;type=entry;profile=opds-catalog"
+	OpenSearchType  = "application/opensearchdescription+xml"
+	// LoanType is what the loans API a borrow link points at answers with
+	LoanType = "application/json"
+
+	RelSubsection = "subsection"
+	RelBorrow     = "http://opds-spec.org/acquisition/borrow"
+	RelSearch     = "search"
+)
+
+type Feed struct {
+	XMLName      xml.Name `xml:"feed"`
+	Xmlns        string   `xml:"xmlns,attr"`
+	XmlnsOPDS    string   `xml:"xmlns:opds,attr"`
+	XmlnsSearch  string   `xml:"xmlns:opensearch,attr"`
+	XmlnsDCTerms string   `xml:"xmlns:dcterms,attr"`
+
+	ID           string  `xml:"id"`
+	Title        string  `xml:"title"`
+	Updated      string  `xml:"updated"`
+	Author       *Person `xml:"author,omitempty"`
+	Links        []Link  `xml:"link"`
+	TotalResults *int    `xml:"opensearch:totalResults,omitempty"`
+	ItemsPerPage *int    `xml:"opensearch:itemsPerPage,omitempty"`
+	StartIndex   *int    `xml:"opensearch:startIndex,omitempty"`
+	Entries      []Entry `xml:"entry"`
+}
+
+type Entry struct {
+	ID         string   `xml:"id"`
+	Title      string   `xml:"title"`
+	Updated    string   `xml:"updated"`
+	Authors    []Person `xml:"author,omitempty"`
+	Identifier string   `xml:"dcterms:identifier,omitempty"`
+	Publisher  string   `xml:"dcterms:publisher,omitempty"`
+	Issued     string   `xml:"dcterms:issued,omitempty"`
+	Content    *Content `xml:"content,omitempty"`
+	Links      []Link   `xml:"link"`
+}
+
+type Person struct {
+	Name string `xml:"name"`
+}
+
+type Content struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+type Link struct {
+	Rel          string        `xml:"rel,attr,omitempty"`
+	Href         string        `xml:"href,attr"`
+	Type         string        `xml:"type,attr,omitempty"`
+	Title        string        `xml:"title,attr,omitempty"`
+	Availability *Availability `xml:"opds:availability,omitempty"`
+	Copies       *Copies       `xml:"opds:copies,omitempty"`
+}
+
+// Availability is the OPDS 1.2 indicator on borrow links.
+type Availability struct {
+	Status string `xml:"status,attr"`
+}
+
+type Copies struct {
+	Available int `xml:"available,attr"`
+}
+
+func NewFeed(id, title string, updated time.Time) *Feed {
+	return &Feed{
+		Xmlns:        AtomNamespace,
+		XmlnsOPDS:    OPDSNamespace,
+		XmlnsSearch:  OpenSearchNamespace,
+		XmlnsDCTerms: DCTermsNamespace,
+		ID:           id,
+		Title:        title,
+		Updated:      FormatTime(updated),
+	}
+}
+
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// SetPage records the OpenSearch paging counts for an acquisition feed.
+func (f *Feed) SetPage(total, perPage, startIndex int) {
+	f.TotalResults = &total
+	f.ItemsPerPage = &perPage
+	f.StartIndex = &startIndex
+}
+
+// OpenSearchDescription describes the search endpoint to OPDS clients.
+type OpenSearchDescription struct {
+	XMLName       xml.Name      `xml:"OpenSearchDescription"`
+	Xmlns         string        `xml:"xmlns,attr"`
+	ShortName     string        `xml:"ShortName"`
+	Description   string        `xml:"Description"`
+	InputEncoding string        `xml:"InputEncoding"`
+	URL           OpenSearchURL `xml:"Url"`
+}
+
+type OpenSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
+
+func NewOpenSearchDescription(shortName, description, template string) *OpenSearchDescription {
+	return &OpenSearchDescription{
+		Xmlns:         OpenSearchNamespace,
+		ShortName:     shortName,
+		Description:   description,
+		InputEncoding: "UTF-8",
+		URL:           OpenSearchURL{Type: AcquisitionType, Template: template},
+	}
+}
+
+// EntryDocument is a complete entry served on its own rather than in a feed.
+type EntryDocument struct {
+	XMLName      xml.Name `xml:"entry"`
+	Xmlns        string   `xml:"xmlns,attr"`
+	XmlnsOPDS    string   `xml:"xmlns:opds,attr"`
+	XmlnsDCTerms string   `xml:"xmlns:dcterms,attr"`
+	Entry
+}
+
+func NewEntryDocument(entry Entry) *EntryDocument {
+	return &EntryDocument{
+		Xmlns:        AtomNamespace,
+		XmlnsOPDS:    OPDSNamespace,
+		XmlnsDCTerms: DCTermsNamespace,
+		Entry:        entry,
+	}
+}
@@ -0,0 +1,291 @@
+// Package openapi builds OpenAPI 3.1 documents. Schemas are derived from Go
+// types: properties from their json tags and constraints from the binding
+// tags gin validates requests with.
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const Version = "3.1.0"
+
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Security   []map[string][]string `json:"security,omitempty"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps lower case HTTP methods to their operations.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Tags        []string            `json:"tags,omitempty"`
+	Summary     string              `json:"summary"`
+	Description string              `json:"description,omitempty"`
+	OperationID string              `json:"operationId,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+	Deprecated  bool                `json:"deprecated,omitempty"`
+	// Security overrides the document's requirements when set
+	Security []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	Name        string `json:"name,omitempty"`
+	In          string `json:"in,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Schema is the subset of JSON Schema the derived schemas use. An empty
+// Schema accepts any value.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	ReadOnly             bool               `json:"readOnly,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Not                  *Schema            `json:"not,omitempty"`
+	Const                interface{}        `json:"const,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+}
+
+func New(info Info) *Document {
+	return &Document{
+		OpenAPI:    Version,
+		Info:       info,
+		Paths:      map[string]PathItem{},
+		Components: Components{Schemas: map[string]*Schema{}},
+	}
+}
+
+// Add describes the route method path, written the way gin writes it. Path
+// parameters the operation doesn't describe are added as plain strings.
+func (d *Document) Add(method, path string, op *Operation) {
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			name := segment[1:]
+			if !hasParameter(op, name) {
+				op.Parameters = append([]Parameter{{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}}}, op.Parameters...)
+			}
+			segment = "{" + name + "}"
+		}
+		segments = append(segments, segment)
+	}
+	openAPIPath := strings.Join(segments, "/")
+	if d.Paths[openAPIPath] == nil {
+		d.Paths[openAPIPath] = PathItem{}
+	}
+	d.Paths[openAPIPath][strings.ToLower(method)] = op
+}
+
+func hasParameter(op *Operation, name string) bool {
+	for _, p := range op.Parameters {
+		if p.In == "path" && p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Has reports whether the route method path, written the way gin writes
+// it, is described.
+func (d *Document) Has(method, path string) bool {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	_, ok := d.Paths[strings.Join(segments, "/")][strings.ToLower(method)]
+	return ok
+}
+
+// Schema returns the schema of v's type. Named structs are added to the
+// components once and referred to from then on.
+func (d *Document) Schema(v interface{}) *Schema {
+	return d.schemaFor(reflect.TypeOf(v))
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+func (d *Document) schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Implements(marshalerType):
+		// Types that write their own JSON can be anything
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: float(0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: d.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		if _, ok := d.Components.Schemas[t.Name()]; !ok {
+			// Claim the name first so a type that refers to itself terminates
+			d.Components.Schemas[t.Name()] = &Schema{}
+			d.Components.Schemas[t.Name()] = d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	}
+	return &Schema{}
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: false}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			continue
+		}
+		// Embedded structs without a name are flattened, as encoding/json
+		// does
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			embedded := d.structSchema(field.Type)
+			for property, schema := range embedded.Properties {
+				s.Properties[property] = schema
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		property := d.schemaFor(field.Type)
+		property.ReadOnly = field.Tag.Get("readonly") == "true"
+		if rules := field.Tag.Get("binding"); rules != "" {
+			if applyRules(property, rules) {
+				s.Required = append(s.Required, name)
+			}
+		}
+		s.Properties[name] = property
+	}
+	return s
+}
+
+// applyRules turns validator rules into schema constraints and reports
+// whether the field is required.
+func applyRules(s *Schema, rules string) (required bool) {
+	for _, rule := range strings.Split(rules, ",") {
+		tag, param, _ := strings.Cut(rule, "=")
+		n, err := strconv.ParseFloat(param, 64)
+		hasNumber := err == nil
+		switch {
+		case tag == "required":
+			required = true
+		case tag == "numeric":
+			s.Pattern = "^[0-9]+$"
+		case tag == "isbn":
+			s.Format = "isbn"
+			s.Description = "ISBN-10 or ISBN-13, hyphens and spaces allowed"
+		case tag == "oneof":
+			s.Enum = strings.Fields(param)
+		case tag == "ne" && hasNumber:
+			s.Not = &Schema{Const: n}
+		case !hasNumber:
+		case s.Type == "string" && (tag == "min" || tag == "gte"):
+			s.MinLength = length(n)
+		case s.Type == "string" && (tag == "max" || tag == "lte"):
+			s.MaxLength = length(n)
+		case s.Type == "string" && tag == "len":
+			s.MinLength, s.MaxLength = length(n), length(n)
+		case tag == "min" || tag == "gte":
+			s.Minimum = float(n)
+		case tag == "max" || tag == "lte":
+			s.Maximum = float(n)
+		}
+	}
+	return required
+}
+
+func length(n float64) *int {
+	i := int(n)
+	return &i
+}
+
+func float(n float64) *float64 {
+	return &n
+}
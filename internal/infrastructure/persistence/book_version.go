@@ -0,0 +1,63 @@
+package persistence
+
+import (
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+)
+
+// bookVersion is a book as it was from ValidFrom until its next version.
+type bookVersion struct {
+	ID            uint `gorm:"primaryKey"`
+	TenantID      string
+	BookID        string
+	Title         string
+	Author        string
+	Quantity      int
+	ISBN          string
+	Publisher     string
+	Year          string
+	BookCreatedAt time.Time
+	BookUpdatedAt time.Time
+	ValidFrom     time.Time
+	// Deleted marks the version that ends a book's history.
+	Deleted bool
+}
+
+func (bookVersion) TableName() string { return "book_versions" }
+
+func newBookVersion(book model.Book, validFrom time.Time) bookVersion {
+	return bookVersion{
+		TenantID:      book.TenantID,
+		BookID:        book.ID,
+		Title:         book.Title,
+		Author:        book.Author,
+		Quantity:      book.Quantity,
+		ISBN:          book.ISBN,
+		Publisher:     book.Publisher,
+		Year:          book.Year,
+		BookCreatedAt: book.CreatedAt,
+		BookUpdatedAt: book.UpdatedAt,
+		// Stored in UTC so versions compare correctly as text in SQLite
+		ValidFrom: validFrom.UTC(),
+	}
+}
+
+func deletedBookVersion(tenant, id string, validFrom time.Time) bookVersion {
+	return bookVersion{TenantID: tenant, BookID: id, ValidFrom: validFrom.UTC(), Deleted: true}
+}
+
+func (v bookVersion) book() model.Book {
+	return model.Book{
+		TenantID:  v.TenantID,
+		ID:        v.BookID,
+		Title:     v.Title,
+		Author:    v.Author,
+		Quantity:  v.Quantity,
+		ISBN:      v.ISBN,
+		Publisher: v.Publisher,
+		Year:      v.Year,
+		CreatedAt: v.BookCreatedAt,
+		UpdatedAt: v.BookUpdatedAt,
+	}
+}
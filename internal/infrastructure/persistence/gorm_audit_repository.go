@@ -0,0 +1,75 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type GormAuditRepository struct {
+	db *gorm.DB
+}
+
+func NewGormAuditRepository(db *gorm.DB) *GormAuditRepository {
+	return &GormAuditRepository{db: db}
+}
+
+// Append inserts the entry before reading the previous hash. SQLite lets
+// one transaction write at a time, so once the insert is done no other
+// entry can slip in before this one and fork the chain. Every tenant has a
+// chain of its own.
+func (r *GormAuditRepository) Append(ctx context.Context, entry model.AuditEntry) error {
+	return dbFromContext(ctx, r.db).WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		entry.ID = 0
+		entry.TenantID = repository.Tenant(ctx)
+		entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+
+		var prev []model.AuditEntry
+		if err := tx.Where("tenant_id = ? AND id < ?", entry.TenantID, entry.ID).Order("id DESC").Limit(1).Find(&prev).Error; err != nil {
+			return err
+		}
+		entry.PrevHash = ""
+		if len(prev) > 0 {
+			entry.PrevHash = prev[0].Hash
+		}
+		entry.Hash = entry.ComputeHash()
+		return tx.Model(&entry).Updates(map[string]interface{}{"prev_hash": entry.PrevHash, "hash": entry.Hash}).Error
+	})
+}
+
+func (r *GormAuditRepository) List(ctx context.Context, filter repository.AuditFilter) ([]model.AuditEntry, error) {
+	query := dbFromContext(ctx, r.db).WithContext(ctx).Where("tenant_id = ?", repository.Tenant(ctx)).Order("id")
+	for column, value := range map[string]string{
+		"actor":       filter.Actor,
+		"action":      filter.Action,
+		"entity_type": filter.EntityType,
+		"entity_id":   filter.EntityID,
+		"request_id":  filter.RequestID,
+	} {
+		if value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at <= ?", filter.To.UTC())
+	}
+	if filter.AfterID > 0 {
+		query = query.Where("id > ?", filter.AfterID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	entries := []model.AuditEntry{}
+	err := query.Find(&entries).Error
+	return entries, err
+}
@@ -1,40 +1,175 @@
 package persistence
 
 import (
+	"context"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
 	"gorm.io/gorm"
 )
 
+// GormBookRepository only sees the books of the tenant in the caller's
+// context.
 type GormBookRepository struct {
-	db *gorm.DB
+	db           *gorm.DB
+	queryTimeout time.Duration
 }
 
+// NewGormBookRepository expects a database already brought up to date with
+// MigrateUp.
 func NewGormBookRepository(db *gorm.DB) *GormBookRepository {
-	db.AutoMigrate(&model.Book{})
 	return &GormBookRepository{db: db}
 }
 
-func (r *GormBookRepository) GetAll() ([]model.Book, error) {
+// WithQueryTimeout returns a copy of the repository that gives every query
+// at most d on top of the caller's deadline. Zero means no limit.
+func (r *GormBookRepository) WithQueryTimeout(d time.Duration) *GormBookRepository {
+	return &GormBookRepository{db: r.db, queryTimeout: d}
+}
+
+func (r *GormBookRepository) GetAll(ctx context.Context) ([]model.Book, error) {
+	db, cancel := r.conn(ctx)
+	defer cancel()
+
 	var books []model.Book
-	result := r.db.Find(&books)
+	result := db.Where("tenant_id = ?", repository.Tenant(ctx)).Order("rowid").Find(&books)
 	return books, result.Error
 }
 
-func (r *GormBookRepository) GetByID(id string) (*model.Book, error) {
+func (r *GormBookRepository) GetByID(ctx context.Context, id string) (*model.Book, error) {
+	db, cancel := r.conn(ctx)
+	defer cancel()
+
 	var book model.Book
-	result := r.db.First(&book, "id = ?", id)
+	result := db.First(&book, "tenant_id = ? AND id = ?", repository.Tenant(ctx), id)
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		return nil, errors.New("book not found")
+		return nil, repository.ErrBookNotFound
 	}
-	return &book, result.Error
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &book, nil
 }
 
-func (r *GormBookRepository) Create(book model.Book) error {
-	return r.db.Create(&book).Error
+// Create and Update save a version of the book with every write, for
+// GetAllAsOf and GetByIDAsOf.
+func (r *GormBookRepository) Create(ctx context.Context, book model.Book) error {
+	db, cancel := r.conn(ctx)
+	defer cancel()
+
+	now := time.Now()
+	book.TenantID = repository.Tenant(ctx)
+	book.CreatedAt, book.UpdatedAt = now, now
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&book).Error; err != nil {
+			return err
+		}
+		version := newBookVersion(book, now)
+		return tx.Create(&version).Error
+	})
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return repository.ErrBookExists
+	}
+	return err
 }
 
-func (r *GormBookRepository) Update(updatedBook model.Book) error {
-	return r.db.Save(&updatedBook).Error
+func (r *GormBookRepository) Update(ctx context.Context, updatedBook model.Book) error {
+	db, cancel := r.conn(ctx)
+	defer cancel()
+
+	updatedBook.TenantID = repository.Tenant(ctx)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&updatedBook).Error; err != nil {
+			return err
+		}
+		version := newBookVersion(updatedBook, time.Now())
+		return tx.Create(&version).Error
+	})
+}
+
+func (r *GormBookRepository) Delete(ctx context.Context, id string) error {
+	db, cancel := r.conn(ctx)
+	defer cancel()
+
+	tenant := repository.Tenant(ctx)
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&model.Book{}, "tenant_id = ? AND id = ?", tenant, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return repository.ErrBookNotFound
+		}
+		version := deletedBookVersion(tenant, id, time.Now())
+		return tx.Create(&version).Error
+	})
+}
+
+func (r *GormBookRepository) GetAllAsOf(ctx context.Context, t time.Time) ([]model.Book, error) {
+	db, cancel := r.conn(ctx)
+	defer cancel()
+
+	var versions []bookVersion
+	latest := db.Model(&bookVersion{}).Select("MAX(id)").
+		Where("tenant_id = ? AND valid_from <= ?", repository.Tenant(ctx), t.UTC()).Group("book_id")
+	err := db.Where("id IN (?)", latest).
+		Order("(SELECT MIN(first.id) FROM book_versions first WHERE first.tenant_id = book_versions.tenant_id AND first.book_id = book_versions.book_id)").
+		Find(&versions).Error
+	if err != nil {
+		return nil, err
+	}
+	books := make([]model.Book, 0, len(versions))
+	for _, v := range versions {
+		if !v.Deleted {
+			books = append(books, v.book())
+		}
+	}
+	return books, nil
+}
+
+func (r *GormBookRepository) Deletions(ctx context.Context) ([]repository.Deletion, error) {
+	db, cancel := r.conn(ctx)
+	defer cancel()
+
+	var versions []bookVersion
+	latest := db.Model(&bookVersion{}).Select("MAX(id)").
+		Where("tenant_id = ?", repository.Tenant(ctx)).Group("book_id")
+	err := db.Where("id IN (?) AND deleted", latest).Order("valid_from, book_id").Find(&versions).Error
+	if err != nil {
+		return nil, err
+	}
+	deletions := make([]repository.Deletion, 0, len(versions))
+	for _, v := range versions {
+		deletions = append(deletions, repository.Deletion{BookID: v.BookID, DeletedAt: v.ValidFrom})
+	}
+	return deletions, nil
+}
+
+func (r *GormBookRepository) GetByIDAsOf(ctx context.Context, id string, t time.Time) (*model.Book, error) {
+	db, cancel := r.conn(ctx)
+	defer cancel()
+
+	var version bookVersion
+	err := db.Where("tenant_id = ? AND book_id = ? AND valid_from <= ?", repository.Tenant(ctx), id, t.UTC()).Order("id DESC").Take(&version).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || version.Deleted {
+		return nil, repository.ErrBookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	book := version.book()
+	return &book, nil
+}
+
+// conn returns the database to query, joining the caller's unit of work.
+func (r *GormBookRepository) conn(ctx context.Context) (*gorm.DB, context.CancelFunc) {
+	db := dbFromContext(ctx, r.db)
+	if r.queryTimeout <= 0 {
+		return db.WithContext(ctx), func() {}
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	return db.WithContext(ctx), cancel
 }
@@ -0,0 +1,61 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type GormIdempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewGormIdempotencyRepository(db *gorm.DB) *GormIdempotencyRepository {
+	return &GormIdempotencyRepository{db: db}
+}
+
+func (r *GormIdempotencyRepository) Create(ctx context.Context, key model.IdempotencyKey) error {
+	key.TenantID = repository.Tenant(ctx)
+	err := dbFromContext(ctx, r.db).WithContext(ctx).Create(&key).Error
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return repository.ErrIdempotencyKeyExists
+	}
+	return err
+}
+
+func (r *GormIdempotencyRepository) Get(ctx context.Context, key string) (*model.IdempotencyKey, error) {
+	var stored model.IdempotencyKey
+	err := dbFromContext(ctx, r.db).WithContext(ctx).First(&stored, "tenant_id = ? AND key = ?", repository.Tenant(ctx), key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, repository.ErrIdempotencyKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+func (r *GormIdempotencyRepository) Update(ctx context.Context, key model.IdempotencyKey) error {
+	result := dbFromContext(ctx, r.db).WithContext(ctx).Model(&model.IdempotencyKey{}).
+		Where("tenant_id = ? AND key = ?", repository.Tenant(ctx), key.Key).
+		Select("*").Omit("tenant_id", "key").Updates(&key)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return repository.ErrIdempotencyKeyNotFound
+	}
+	return result.Error
+}
+
+func (r *GormIdempotencyRepository) Delete(ctx context.Context, key string) error {
+	return dbFromContext(ctx, r.db).WithContext(ctx).
+		Delete(&model.IdempotencyKey{}, "tenant_id = ? AND key = ?", repository.Tenant(ctx), key).Error
+}
+
+func (r *GormIdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	return dbFromContext(ctx, r.db).WithContext(ctx).
+		Delete(&model.IdempotencyKey{}, "expires_at <= ?", now).Error
+}
@@ -0,0 +1,58 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type GormLedgerRepository struct {
+	db *gorm.DB
+}
+
+func NewGormLedgerRepository(db *gorm.DB) *GormLedgerRepository {
+	return &GormLedgerRepository{db: db}
+}
+
+func (r *GormLedgerRepository) Append(ctx context.Context, entry model.LedgerEntry) error {
+	entry.ID = 0
+	entry.TenantID = repository.Tenant(ctx)
+	return dbFromContext(ctx, r.db).WithContext(ctx).Create(&entry).Error
+}
+
+func (r *GormLedgerRepository) MoveBook(ctx context.Context, fromID, toID string, mergeID uint) error {
+	return dbFromContext(ctx, r.db).WithContext(ctx).Model(&model.LedgerEntry{}).
+		Where("tenant_id = ? AND book_id = ?", repository.Tenant(ctx), fromID).
+		Updates(map[string]interface{}{"book_id": toID, "merge_id": mergeID}).Error
+}
+
+func (r *GormLedgerRepository) MoveBack(ctx context.Context, mergeID uint, bookID string) error {
+	return dbFromContext(ctx, r.db).WithContext(ctx).Model(&model.LedgerEntry{}).
+		Where("tenant_id = ? AND merge_id = ?", repository.Tenant(ctx), mergeID).
+		Updates(map[string]interface{}{"book_id": bookID, "merge_id": nil}).Error
+}
+
+func (r *GormLedgerRepository) ListByBook(ctx context.Context, bookID string) ([]model.LedgerEntry, error) {
+	var entries []model.LedgerEntry
+	err := dbFromContext(ctx, r.db).WithContext(ctx).Where("tenant_id = ? AND book_id = ?", repository.Tenant(ctx), bookID).Order("id").Find(&entries).Error
+	return entries, err
+}
+
+func (r *GormLedgerRepository) Balances(ctx context.Context) (map[string]int, error) {
+	var rows []struct {
+		BookID  string
+		Balance int
+	}
+	err := dbFromContext(ctx, r.db).WithContext(ctx).Model(&model.LedgerEntry{}).
+		Select("book_id, SUM(delta) AS balance").Where("tenant_id = ?", repository.Tenant(ctx)).Group("book_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	balances := make(map[string]int, len(rows))
+	for _, row := range rows {
+		balances[row.BookID] = row.Balance
+	}
+	return balances, nil
+}
@@ -0,0 +1,57 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type GormLoanRepository struct {
+	db *gorm.DB
+}
+
+func NewGormLoanRepository(db *gorm.DB) *GormLoanRepository {
+	return &GormLoanRepository{db: db}
+}
+
+func (r *GormLoanRepository) Create(ctx context.Context, loan *model.Loan) error {
+	loan.ID = 0
+	loan.TenantID = repository.Tenant(ctx)
+	return dbFromContext(ctx, r.db).WithContext(ctx).Create(loan).Error
+}
+
+func (r *GormLoanRepository) GetByID(ctx context.Context, id uint) (*model.Loan, error) {
+	var loan model.Loan
+	err := dbFromContext(ctx, r.db).WithContext(ctx).First(&loan, "tenant_id = ? AND id = ?", repository.Tenant(ctx), id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, repository.ErrLoanNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &loan, nil
+}
+
+func (r *GormLoanRepository) ListByBook(ctx context.Context, bookID string) ([]model.Loan, error) {
+	loans := []model.Loan{}
+	err := dbFromContext(ctx, r.db).WithContext(ctx).Where("tenant_id = ? AND book_id = ?", repository.Tenant(ctx), bookID).Order("id").Find(&loans).Error
+	return loans, err
+}
+
+func (r *GormLoanRepository) ListByMerge(ctx context.Context, mergeID uint) ([]model.Loan, error) {
+	loans := []model.Loan{}
+	err := dbFromContext(ctx, r.db).WithContext(ctx).Where("tenant_id = ? AND merge_id = ?", repository.Tenant(ctx), mergeID).Order("id").Find(&loans).Error
+	return loans, err
+}
+
+func (r *GormLoanRepository) Update(ctx context.Context, loan model.Loan) error {
+	result := dbFromContext(ctx, r.db).WithContext(ctx).Model(&loan).
+		Where("tenant_id = ?", repository.Tenant(ctx)).Select("*").Omit("tenant_id", "id").Updates(&loan)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return repository.ErrLoanNotFound
+	}
+	return result.Error
+}
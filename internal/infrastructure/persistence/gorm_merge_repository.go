@@ -0,0 +1,51 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type GormMergeRepository struct {
+	db *gorm.DB
+}
+
+func NewGormMergeRepository(db *gorm.DB) *GormMergeRepository {
+	return &GormMergeRepository{db: db}
+}
+
+func (r *GormMergeRepository) Create(ctx context.Context, merge *model.BookMerge) error {
+	merge.ID = 0
+	merge.TenantID = repository.Tenant(ctx)
+	return dbFromContext(ctx, r.db).WithContext(ctx).Create(merge).Error
+}
+
+func (r *GormMergeRepository) GetByID(ctx context.Context, id uint) (*model.BookMerge, error) {
+	var merge model.BookMerge
+	err := dbFromContext(ctx, r.db).WithContext(ctx).First(&merge, "tenant_id = ? AND id = ?", repository.Tenant(ctx), id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, repository.ErrMergeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &merge, nil
+}
+
+func (r *GormMergeRepository) List(ctx context.Context) ([]model.BookMerge, error) {
+	merges := []model.BookMerge{}
+	err := dbFromContext(ctx, r.db).WithContext(ctx).Where("tenant_id = ?", repository.Tenant(ctx)).Order("id").Find(&merges).Error
+	return merges, err
+}
+
+func (r *GormMergeRepository) Update(ctx context.Context, merge model.BookMerge) error {
+	result := dbFromContext(ctx, r.db).WithContext(ctx).Model(&merge).
+		Where("tenant_id = ?", repository.Tenant(ctx)).Select("*").Omit("tenant_id", "id").Updates(&merge)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return repository.ErrMergeNotFound
+	}
+	return result.Error
+}
@@ -0,0 +1,41 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// GormUnitOfWork runs units in database transactions, nested units in
+// savepoints.
+type GormUnitOfWork struct {
+	db *gorm.DB
+}
+
+func NewGormUnitOfWork(db *gorm.DB) *GormUnitOfWork {
+	return &GormUnitOfWork{db: db}
+}
+
+func (u *GormUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	outermost := !repository.InUnit(ctx)
+	ctx = repository.WithinUnit(ctx)
+	err := dbFromContext(ctx, u.db).WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+	if err == nil && outermost {
+		repository.Committed(ctx)
+	}
+	return err
+}
+
+// dbFromContext returns the transaction of the unit of work ctx belongs to,
+// or db outside a unit.
+func dbFromContext(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return db
+}
@@ -0,0 +1,83 @@
+package persistence
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+)
+
+type MemoryAuditRepository struct {
+	mu      sync.RWMutex
+	entries []model.AuditEntry
+}
+
+func NewMemoryAuditRepository() *MemoryAuditRepository {
+	return &MemoryAuditRepository{}
+}
+
+func (r *MemoryAuditRepository) Append(ctx context.Context, entry model.AuditEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.ID = uint(len(r.entries) + 1)
+	entry.TenantID = repository.Tenant(ctx)
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	entry.PrevHash = ""
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		if r.entries[i].TenantID == entry.TenantID {
+			entry.PrevHash = r.entries[i].Hash
+			break
+		}
+	}
+	entry.Hash = entry.ComputeHash()
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *MemoryAuditRepository) List(ctx context.Context, filter repository.AuditFilter) ([]model.AuditEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenant := repository.Tenant(ctx)
+	entries := []model.AuditEntry{}
+	for _, e := range r.entries {
+		switch {
+		case e.TenantID != tenant,
+			filter.Actor != "" && e.Actor != filter.Actor,
+			filter.Action != "" && e.Action != filter.Action,
+			filter.EntityType != "" && e.EntityType != filter.EntityType,
+			filter.EntityID != "" && e.EntityID != filter.EntityID,
+			filter.RequestID != "" && e.RequestID != filter.RequestID,
+			!filter.From.IsZero() && e.CreatedAt.Before(filter.From),
+			!filter.To.IsZero() && e.CreatedAt.After(filter.To),
+			e.ID <= filter.AfterID:
+			continue
+		}
+		entries = append(entries, e)
+		if filter.Limit > 0 && len(entries) == filter.Limit {
+			break
+		}
+	}
+	return entries, nil
+}
+
+func (r *MemoryAuditRepository) snapshot() func() {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := r.entries[:len(r.entries):len(r.entries)]
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.entries = entries
+	}
+}
@@ -0,0 +1,205 @@
+package persistence
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+)
+
+// MemoryBookRepository keeps books in memory with the same behaviour as
+// GormBookRepository: books come back in insertion order, GetByID reports
+// repository.ErrBookNotFound, Update inserts missing books and timestamps are
+// set the way GORM sets them. Callers always get copies, so changing a
+// returned book never changes the stored one, and only see the books of the
+// tenant in their context.
+type MemoryBookRepository struct {
+	mu       sync.RWMutex
+	books    map[bookKey]model.Book
+	order    []bookKey
+	versions []bookVersion
+}
+
+type bookKey struct {
+	tenant, id string
+}
+
+func NewMemoryBookRepository() *MemoryBookRepository {
+	return &MemoryBookRepository{books: map[bookKey]model.Book{}}
+}
+
+func (r *MemoryBookRepository) GetAll(ctx context.Context) ([]model.Book, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenant := repository.Tenant(ctx)
+	books := []model.Book{}
+	for _, key := range r.order {
+		if key.tenant == tenant {
+			books = append(books, r.books[key])
+		}
+	}
+	return books, nil
+}
+
+func (r *MemoryBookRepository) GetByID(ctx context.Context, id string) (*model.Book, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	book, ok := r.books[bookKey{repository.Tenant(ctx), id}]
+	if !ok {
+		return nil, repository.ErrBookNotFound
+	}
+	return &book, nil
+}
+
+func (r *MemoryBookRepository) Create(ctx context.Context, book model.Book) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	book.TenantID = repository.Tenant(ctx)
+	if _, ok := r.books[bookKey{book.TenantID, book.ID}]; ok {
+		return repository.ErrBookExists
+	}
+	r.insert(book, time.Now())
+	return nil
+}
+
+func (r *MemoryBookRepository) Update(ctx context.Context, book model.Book) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	book.TenantID = repository.Tenant(ctx)
+	key := bookKey{book.TenantID, book.ID}
+	if _, ok := r.books[key]; !ok {
+		r.insert(book, now)
+		return nil
+	}
+	book.UpdatedAt = now
+	r.books[key] = book
+	r.versions = append(r.versions, newBookVersion(book, now))
+	return nil
+}
+
+func (r *MemoryBookRepository) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := bookKey{repository.Tenant(ctx), id}
+	if _, ok := r.books[key]; !ok {
+		return repository.ErrBookNotFound
+	}
+	delete(r.books, key)
+	order := make([]bookKey, 0, len(r.order)-1)
+	for _, other := range r.order {
+		if other != key {
+			order = append(order, other)
+		}
+	}
+	r.order = order
+	r.versions = append(r.versions, deletedBookVersion(key.tenant, id, time.Now()))
+	return nil
+}
+
+func (r *MemoryBookRepository) insert(book model.Book, now time.Time) {
+	book.CreatedAt, book.UpdatedAt = now, now
+	key := bookKey{book.TenantID, book.ID}
+	r.books[key] = book
+	r.order = append(r.order, key)
+	r.versions = append(r.versions, newBookVersion(book, now))
+}
+
+func (r *MemoryBookRepository) GetAllAsOf(ctx context.Context, t time.Time) ([]model.Book, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Deleted books are gone from r.order, so go by when each book was
+	// first seen
+	tenant := repository.Tenant(ctx)
+	books := []model.Book{}
+	seen := map[string]bool{}
+	for _, v := range r.versions {
+		if v.TenantID != tenant || seen[v.BookID] {
+			continue
+		}
+		seen[v.BookID] = true
+		if book, ok := r.bookAsOf(tenant, v.BookID, t); ok {
+			books = append(books, book)
+		}
+	}
+	return books, nil
+}
+
+func (r *MemoryBookRepository) Deletions(ctx context.Context) ([]repository.Deletion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenant := repository.Tenant(ctx)
+	latest := map[string]bookVersion{}
+	for _, v := range r.versions {
+		if v.TenantID == tenant {
+			latest[v.BookID] = v
+		}
+	}
+	deletions := []repository.Deletion{}
+	for _, v := range latest {
+		if v.Deleted {
+			deletions = append(deletions, repository.Deletion{BookID: v.BookID, DeletedAt: v.ValidFrom})
+		}
+	}
+	sort.Slice(deletions, func(i, j int) bool {
+		if !deletions[i].DeletedAt.Equal(deletions[j].DeletedAt) {
+			return deletions[i].DeletedAt.Before(deletions[j].DeletedAt)
+		}
+		return deletions[i].BookID < deletions[j].BookID
+	})
+	return deletions, nil
+}
+
+func (r *MemoryBookRepository) GetByIDAsOf(ctx context.Context, id string, t time.Time) (*model.Book, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	book, ok := r.bookAsOf(repository.Tenant(ctx), id, t)
+	if !ok {
+		return nil, repository.ErrBookNotFound
+	}
+	return &book, nil
+}
+
+func (r *MemoryBookRepository) bookAsOf(tenant, id string, t time.Time) (model.Book, bool) {
+	for i := len(r.versions) - 1; i >= 0; i-- {
+		if v := r.versions[i]; v.TenantID == tenant && v.BookID == id && !v.ValidFrom.After(t) {
+			return v.book(), !v.Deleted
+		}
+	}
+	return model.Book{}, false
+}
@@ -0,0 +1,96 @@
+package persistence
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+)
+
+type MemoryIdempotencyRepository struct {
+	mu   sync.RWMutex
+	keys map[tenantKey]model.IdempotencyKey
+}
+
+type tenantKey struct {
+	tenant, key string
+}
+
+func NewMemoryIdempotencyRepository() *MemoryIdempotencyRepository {
+	return &MemoryIdempotencyRepository{keys: map[tenantKey]model.IdempotencyKey{}}
+}
+
+func (r *MemoryIdempotencyRepository) Create(ctx context.Context, key model.IdempotencyKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key.TenantID = repository.Tenant(ctx)
+	if _, ok := r.keys[tenantKey{key.TenantID, key.Key}]; ok {
+		return repository.ErrIdempotencyKeyExists
+	}
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+	r.keys[tenantKey{key.TenantID, key.Key}] = key
+	return nil
+}
+
+func (r *MemoryIdempotencyRepository) Get(ctx context.Context, key string) (*model.IdempotencyKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, ok := r.keys[tenantKey{repository.Tenant(ctx), key}]
+	if !ok {
+		return nil, repository.ErrIdempotencyKeyNotFound
+	}
+	return &stored, nil
+}
+
+func (r *MemoryIdempotencyRepository) Update(ctx context.Context, key model.IdempotencyKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key.TenantID = repository.Tenant(ctx)
+	if _, ok := r.keys[tenantKey{key.TenantID, key.Key}]; !ok {
+		return repository.ErrIdempotencyKeyNotFound
+	}
+	r.keys[tenantKey{key.TenantID, key.Key}] = key
+	return nil
+}
+
+func (r *MemoryIdempotencyRepository) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.keys, tenantKey{repository.Tenant(ctx), key})
+	return nil
+}
+
+func (r *MemoryIdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for k, key := range r.keys {
+		if !key.ExpiresAt.After(now) {
+			delete(r.keys, k)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package persistence
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+)
+
+type MemoryLedgerRepository struct {
+	mu      sync.RWMutex
+	entries []model.LedgerEntry
+}
+
+func NewMemoryLedgerRepository() *MemoryLedgerRepository {
+	return &MemoryLedgerRepository{}
+}
+
+func (r *MemoryLedgerRepository) Append(ctx context.Context, entry model.LedgerEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.ID = uint(len(r.entries) + 1)
+	entry.TenantID = repository.Tenant(ctx)
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *MemoryLedgerRepository) MoveBook(ctx context.Context, fromID, toID string, mergeID uint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tenant := repository.Tenant(ctx)
+	for i := range r.entries {
+		if r.entries[i].TenantID == tenant && r.entries[i].BookID == fromID {
+			r.entries[i].BookID, r.entries[i].MergeID = toID, &mergeID
+		}
+	}
+	return nil
+}
+
+func (r *MemoryLedgerRepository) MoveBack(ctx context.Context, mergeID uint, bookID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tenant := repository.Tenant(ctx)
+	for i := range r.entries {
+		entry := &r.entries[i]
+		if entry.TenantID == tenant && entry.MergeID != nil && *entry.MergeID == mergeID {
+			entry.BookID, entry.MergeID = bookID, nil
+		}
+	}
+	return nil
+}
+
+func (r *MemoryLedgerRepository) ListByBook(ctx context.Context, bookID string) ([]model.LedgerEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenant := repository.Tenant(ctx)
+	entries := []model.LedgerEntry{}
+	for _, entry := range r.entries {
+		if entry.TenantID == tenant && entry.BookID == bookID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (r *MemoryLedgerRepository) Balances(ctx context.Context) (map[string]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenant := repository.Tenant(ctx)
+	balances := map[string]int{}
+	for _, entry := range r.entries {
+		if entry.TenantID == tenant {
+			balances[entry.BookID] += entry.Delta
+		}
+	}
+	return balances, nil
+}
+
+func (r *MemoryLedgerRepository) snapshot() func() {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Merges move entries in place, so copy them all
+	entries := append([]model.LedgerEntry{}, r.entries...)
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.entries = entries
+	}
+}
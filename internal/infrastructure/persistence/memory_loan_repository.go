@@ -0,0 +1,102 @@
+package persistence
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+)
+
+type MemoryLoanRepository struct {
+	mu    sync.RWMutex
+	loans []model.Loan
+}
+
+func NewMemoryLoanRepository() *MemoryLoanRepository {
+	return &MemoryLoanRepository{}
+}
+
+func (r *MemoryLoanRepository) Create(ctx context.Context, loan *model.Loan) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	loan.ID = uint(len(r.loans) + 1)
+	loan.TenantID = repository.Tenant(ctx)
+	if loan.CreatedAt.IsZero() {
+		loan.CreatedAt = time.Now()
+	}
+	r.loans = append(r.loans, *loan)
+	return nil
+}
+
+func (r *MemoryLoanRepository) GetByID(ctx context.Context, id uint) (*model.Loan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if id == 0 || int(id) > len(r.loans) || r.loans[id-1].TenantID != repository.Tenant(ctx) {
+		return nil, repository.ErrLoanNotFound
+	}
+	loan := r.loans[id-1]
+	return &loan, nil
+}
+
+func (r *MemoryLoanRepository) ListByBook(ctx context.Context, bookID string) ([]model.Loan, error) {
+	return r.list(ctx, func(loan model.Loan) bool { return loan.BookID == bookID })
+}
+
+func (r *MemoryLoanRepository) ListByMerge(ctx context.Context, mergeID uint) ([]model.Loan, error) {
+	return r.list(ctx, func(loan model.Loan) bool { return loan.MergeID != nil && *loan.MergeID == mergeID })
+}
+
+func (r *MemoryLoanRepository) list(ctx context.Context, match func(model.Loan) bool) ([]model.Loan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenant := repository.Tenant(ctx)
+	loans := []model.Loan{}
+	for _, loan := range r.loans {
+		if loan.TenantID == tenant && match(loan) {
+			loans = append(loans, loan)
+		}
+	}
+	return loans, nil
+}
+
+func (r *MemoryLoanRepository) Update(ctx context.Context, loan model.Loan) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	loan.TenantID = repository.Tenant(ctx)
+	if loan.ID == 0 || int(loan.ID) > len(r.loans) || r.loans[loan.ID-1].TenantID != loan.TenantID {
+		return repository.ErrLoanNotFound
+	}
+	r.loans[loan.ID-1] = loan
+	return nil
+}
+
+func (r *MemoryLoanRepository) snapshot() func() {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Update changes loans in place, so copy them all
+	loans := append([]model.Loan{}, r.loans...)
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.loans = loans
+	}
+}
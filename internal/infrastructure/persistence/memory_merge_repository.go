@@ -0,0 +1,94 @@
+package persistence
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+)
+
+type MemoryMergeRepository struct {
+	mu     sync.RWMutex
+	merges []model.BookMerge
+}
+
+func NewMemoryMergeRepository() *MemoryMergeRepository {
+	return &MemoryMergeRepository{}
+}
+
+func (r *MemoryMergeRepository) Create(ctx context.Context, merge *model.BookMerge) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	merge.ID = uint(len(r.merges) + 1)
+	merge.TenantID = repository.Tenant(ctx)
+	if merge.CreatedAt.IsZero() {
+		merge.CreatedAt = time.Now()
+	}
+	r.merges = append(r.merges, *merge)
+	return nil
+}
+
+func (r *MemoryMergeRepository) GetByID(ctx context.Context, id uint) (*model.BookMerge, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if id == 0 || int(id) > len(r.merges) || r.merges[id-1].TenantID != repository.Tenant(ctx) {
+		return nil, repository.ErrMergeNotFound
+	}
+	merge := r.merges[id-1]
+	return &merge, nil
+}
+
+func (r *MemoryMergeRepository) List(ctx context.Context) ([]model.BookMerge, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenant := repository.Tenant(ctx)
+	merges := []model.BookMerge{}
+	for _, merge := range r.merges {
+		if merge.TenantID == tenant {
+			merges = append(merges, merge)
+		}
+	}
+	return merges, nil
+}
+
+func (r *MemoryMergeRepository) Update(ctx context.Context, merge model.BookMerge) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	merge.TenantID = repository.Tenant(ctx)
+	if merge.ID == 0 || int(merge.ID) > len(r.merges) || r.merges[merge.ID-1].TenantID != merge.TenantID {
+		return repository.ErrMergeNotFound
+	}
+	r.merges[merge.ID-1] = merge
+	return nil
+}
+
+func (r *MemoryMergeRepository) snapshot() func() {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Update changes merges in place, so copy them all
+	merges := append([]model.BookMerge{}, r.merges...)
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.merges = merges
+	}
+}
@@ -0,0 +1,79 @@
+package persistence
+
+import (
+	"context"
+	"sync"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+)
+
+type memoryUnitKey struct{}
+
+// memoryStore is an in-memory repository a MemoryUnitOfWork can roll back:
+// snapshot returns a function that restores the current state.
+type memoryStore interface {
+	snapshot() (restore func())
+}
+
+// MemoryUnitOfWork gives the in-memory repositories units of work by
+// snapshotting them and restoring them on failure. Units run one at a time,
+// but writes made outside any unit are not held back while one runs.
+type MemoryUnitOfWork struct {
+	mu     sync.Mutex
+	stores []memoryStore
+}
+
+func NewMemoryUnitOfWork(books *MemoryBookRepository, others ...memoryStore) *MemoryUnitOfWork {
+	return &MemoryUnitOfWork{stores: append([]memoryStore{books}, others...)}
+}
+
+func (u *MemoryUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	outermost := ctx.Value(memoryUnitKey{}) != u
+	if outermost {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		ctx = repository.WithinUnit(context.WithValue(ctx, memoryUnitKey{}, u))
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	restores := make([]func(), 0, len(u.stores))
+	for _, store := range u.stores {
+		restores = append(restores, store.snapshot())
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			for _, restore := range restores {
+				restore()
+			}
+		}
+	}()
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	committed = true
+	if outermost {
+		repository.Committed(ctx)
+	}
+	return nil
+}
+
+func (r *MemoryBookRepository) snapshot() func() {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	books := make(map[bookKey]model.Book, len(r.books))
+	for key, book := range r.books {
+		books[key] = book
+	}
+	order := append([]bookKey(nil), r.order...)
+	versions := r.versions[:len(r.versions):len(r.versions)]
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.books, r.order, r.versions = books, order, versions
+	}
+}
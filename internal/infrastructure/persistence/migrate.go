@@ -0,0 +1,146 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is one numbered schema change. Up and Down run inside a
+// transaction together with the bookkeeping row in schema_migrations.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// MigrationStatus reports whether a migration has been applied.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// ErrSchemaTooNew means the database was migrated by a newer build, which
+// this binary doesn't know how to read.
+var ErrSchemaTooNew = errors.New("database schema is newer than this build")
+
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// LatestSchemaVersion is the version MigrateUp brings a database to.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// SchemaVersion returns the highest applied migration, or 0 for a database
+// that has never been migrated.
+func SchemaVersion(db *gorm.DB) (int, error) {
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		return 0, nil
+	}
+	var version int
+	err := db.Model(&schemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// MigrateUp applies every pending migration in order. It refuses to touch a
+// database whose schema is newer than LatestSchemaVersion.
+func MigrateUp(db *gorm.DB) error {
+	current, err := checkSchema(db)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d %s up: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// MigrateDown reverts the last steps applied migrations.
+func MigrateDown(db *gorm.DB, steps int) error {
+	current, err := checkSchema(db)
+	if err != nil {
+		return err
+	}
+	for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+		m := migrations[i]
+		if m.Version > current {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d %s down: %w", m.Version, m.Name, err)
+		}
+		steps--
+	}
+	return nil
+}
+
+// MigrationStatuses lists every known migration with the time it was
+// applied, if it was.
+func MigrationStatuses(db *gorm.DB) ([]MigrationStatus, error) {
+	applied := map[int]time.Time{}
+	if db.Migrator().HasTable(&schemaMigration{}) {
+		var rows []schemaMigration
+		if err := db.Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			applied[row.Version] = row.AppliedAt
+		}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if at, ok := applied[m.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// checkSchema creates the schema_migrations table when needed and returns
+// the current schema version.
+func checkSchema(db *gorm.DB) (int, error) {
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		if err := db.Migrator().CreateTable(&schemaMigration{}); err != nil {
+			return 0, fmt.Errorf("create schema_migrations: %w", err)
+		}
+	}
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return 0, err
+	}
+	if current > LatestSchemaVersion() {
+		return current, fmt.Errorf("%w: database is at version %d, this build knows up to %d",
+			ErrSchemaTooNew, current, LatestSchemaVersion())
+	}
+	return current, nil
+}
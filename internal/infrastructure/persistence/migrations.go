@@ -0,0 +1,350 @@
+package persistence
+
+import (
+	"strings"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"gorm.io/gorm"
+)
+
+// migrations is the schema history, oldest first. Never edit a migration
+// that has shipped; add a new one instead.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_books",
+		Up: func(tx *gorm.DB) error {
+			// Databases created by the old AutoMigrate already have the table
+			if tx.Migrator().HasTable("books") {
+				return nil
+			}
+			return tx.Exec(`CREATE TABLE books (
+				id text PRIMARY KEY,
+				title text,
+				author text,
+				quantity integer
+			)`).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP TABLE books").Error
+		},
+	},
+	{
+		Version: 2,
+		Name:    "add_book_metadata",
+		Up: func(tx *gorm.DB) error {
+			return addColumns(tx, "books", "isbn text", "publisher text", "year text", "marc_record text")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, "books", "isbn", "publisher", "year", "marc_record")
+		},
+	},
+	{
+		Version: 3,
+		Name:    "add_book_timestamps",
+		Up: func(tx *gorm.DB) error {
+			return addColumns(tx, "books", "created_at datetime", "updated_at datetime")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, "books", "created_at", "updated_at")
+		},
+	},
+	{
+		Version: 4,
+		Name:    "create_ledger_entries",
+		Up: func(tx *gorm.DB) error {
+			err := tx.Exec(`CREATE TABLE ledger_entries (
+				id integer PRIMARY KEY AUTOINCREMENT,
+				book_id text NOT NULL,
+				delta integer NOT NULL,
+				reason text NOT NULL,
+				actor text,
+				request_id text,
+				created_at datetime
+			)`).Error
+			if err != nil {
+				return err
+			}
+			if err := tx.Exec("CREATE INDEX idx_ledger_entries_book_id ON ledger_entries (book_id)").Error; err != nil {
+				return err
+			}
+			// Books that predate the ledger start from their current quantity
+			return tx.Exec(`INSERT INTO ledger_entries (book_id, delta, reason, actor, created_at)
+				SELECT id, quantity, ?, 'migration', ? FROM books WHERE quantity <> 0`,
+				model.ReasonOpeningBalance, time.Now()).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP TABLE ledger_entries").Error
+		},
+	},
+	{
+		Version: 5,
+		Name:    "create_book_versions",
+		Up: func(tx *gorm.DB) error {
+			err := tx.Exec(`CREATE TABLE book_versions (
+				id integer PRIMARY KEY AUTOINCREMENT,
+				book_id text NOT NULL,
+				title text,
+				author text,
+				quantity integer,
+				isbn text,
+				publisher text,
+				year text,
+				book_created_at datetime,
+				book_updated_at datetime,
+				valid_from datetime NOT NULL
+			)`).Error
+			if err != nil {
+				return err
+			}
+			if err := tx.Exec("CREATE INDEX idx_book_versions_book_id ON book_versions (book_id, valid_from)").Error; err != nil {
+				return err
+			}
+			// History starts now for books that predate it
+			return tx.Exec(`INSERT INTO book_versions
+				(book_id, title, author, quantity, isbn, publisher, year, book_created_at, book_updated_at, valid_from)
+				SELECT id, title, author, quantity, isbn, publisher, year, created_at, updated_at, ? FROM books ORDER BY rowid`,
+				time.Now().UTC()).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP TABLE book_versions").Error
+		},
+	},
+	{
+		Version: 6,
+		Name:    "create_audit_entries",
+		Up: func(tx *gorm.DB) error {
+			err := tx.Exec(`CREATE TABLE audit_entries (
+				id integer PRIMARY KEY AUTOINCREMENT,
+				actor text,
+				action text NOT NULL,
+				entity_type text NOT NULL,
+				entity_id text,
+				before_json text,
+				after_json text,
+				diff text,
+				client_ip text,
+				request_id text,
+				created_at datetime NOT NULL,
+				prev_hash text,
+				hash text
+			)`).Error
+			if err != nil {
+				return err
+			}
+			return tx.Exec("CREATE INDEX idx_audit_entries_entity ON audit_entries (entity_type, entity_id)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP TABLE audit_entries").Error
+		},
+	},
+	{
+		Version: 7,
+		Name:    "add_book_version_deleted",
+		Up: func(tx *gorm.DB) error {
+			return addColumns(tx, "book_versions", "deleted boolean NOT NULL DEFAULT false")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, "book_versions", "deleted")
+		},
+	},
+	{
+		Version: 8,
+		Name:    "create_book_merges",
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec(`CREATE TABLE book_merges (
+				id integer PRIMARY KEY AUTOINCREMENT,
+				survivor_id text NOT NULL,
+				duplicate_id text NOT NULL,
+				duplicate text,
+				duplicate_marc_record text,
+				copies integer NOT NULL,
+				actor text,
+				created_at datetime NOT NULL,
+				undone_at datetime
+			)`).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP TABLE book_merges").Error
+		},
+	},
+	{
+		Version: 9,
+		Name:    "add_tenants",
+		Up: func(tx *gorm.DB) error {
+			// SQLite can't change a primary key, so books is rebuilt keyed
+			// by tenant and ID
+			if err := rebuildBooks(tx, "PRIMARY KEY (tenant_id, id)", "tenant_id text NOT NULL DEFAULT 'default',"); err != nil {
+				return err
+			}
+			for _, table := range tenantTables {
+				if err := addColumns(tx, table, "tenant_id text NOT NULL DEFAULT 'default'"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Exec("DELETE FROM books WHERE tenant_id <> 'default'").Error; err != nil {
+				return err
+			}
+			if err := rebuildBooks(tx, "PRIMARY KEY (id)", ""); err != nil {
+				return err
+			}
+			for _, table := range tenantTables {
+				if err := tx.Exec("DELETE FROM " + table + " WHERE tenant_id <> 'default'").Error; err != nil {
+					return err
+				}
+				if err := dropColumns(tx, table, "tenant_id"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 10,
+		Name:    "create_loans",
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec(`CREATE TABLE loans (
+				id integer PRIMARY KEY AUTOINCREMENT,
+				tenant_id text NOT NULL DEFAULT 'default',
+				book_id text NOT NULL,
+				actor text,
+				created_at datetime NOT NULL,
+				returned_at datetime
+			)`).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP TABLE loans").Error
+		},
+	},
+	{
+		Version: 11,
+		Name:    "create_idempotency_keys",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec(`CREATE TABLE idempotency_keys (
+				tenant_id text NOT NULL,
+				key text NOT NULL,
+				fingerprint text NOT NULL,
+				status integer NOT NULL,
+				content_type text,
+				location text,
+				content_location text,
+				body blob,
+				created_at datetime NOT NULL,
+				expires_at datetime NOT NULL,
+				PRIMARY KEY (tenant_id, key)
+			)`).Error; err != nil {
+				return err
+			}
+			return tx.Exec("CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys (expires_at)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP TABLE idempotency_keys").Error
+		},
+	},
+	{
+		Version: 12,
+		Name:    "add_loan_merge_id",
+		Up: func(tx *gorm.DB) error {
+			if err := addColumns(tx, "loans", "merge_id integer"); err != nil {
+				return err
+			}
+			return tx.Exec("CREATE INDEX idx_loans_book_id ON loans (book_id)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Exec("DROP INDEX idx_loans_book_id").Error; err != nil {
+				return err
+			}
+			return dropColumns(tx, "loans", "merge_id")
+		},
+	},
+	{
+		Version: 13,
+		Name:    "add_audit_credential",
+		Up: func(tx *gorm.DB) error {
+			return addColumns(tx, "audit_entries", "credential text")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, "audit_entries", "credential")
+		},
+	},
+	{
+		Version: 14,
+		Name:    "add_loan_patron",
+		Up: func(tx *gorm.DB) error {
+			return addColumns(tx, "loans", "patron text", "renewed_at datetime")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, "loans", "patron", "renewed_at")
+		},
+	},
+	{
+		Version: 15,
+		Name:    "add_ledger_merge_id",
+		Up: func(tx *gorm.DB) error {
+			return addColumns(tx, "ledger_entries", "merge_id integer")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, "ledger_entries", "merge_id")
+		},
+	},
+}
+
+// tenantTables are the tables other than books that hold tenant data.
+var tenantTables = []string{"book_versions", "ledger_entries", "audit_entries", "book_merges"}
+
+// rebuildBooks recreates the books table with the given key, keeping its
+// rows in order. tenantColumn is the tenant_id definition, if any.
+func rebuildBooks(tx *gorm.DB, key, tenantColumn string) error {
+	err := tx.Exec(`CREATE TABLE books_rebuilt (
+		` + tenantColumn + `
+		id text NOT NULL,
+		title text,
+		author text,
+		quantity integer,
+		isbn text,
+		publisher text,
+		year text,
+		marc_record text,
+		created_at datetime,
+		updated_at datetime,
+		` + key + `
+	)`).Error
+	if err != nil {
+		return err
+	}
+	columns := "id, title, author, quantity, isbn, publisher, year, marc_record, created_at, updated_at"
+	if err := tx.Exec("INSERT INTO books_rebuilt (" + columns + ") SELECT " + columns + " FROM books ORDER BY rowid").Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("DROP TABLE books").Error; err != nil {
+		return err
+	}
+	return tx.Exec("ALTER TABLE books_rebuilt RENAME TO books").Error
+}
+
+// addColumns adds each "name type" column the table doesn't have yet.
+func addColumns(tx *gorm.DB, table string, columns ...string) error {
+	for _, column := range columns {
+		name := strings.Fields(column)[0]
+		if tx.Migrator().HasColumn(table, name) {
+			continue
+		}
+		if err := tx.Exec("ALTER TABLE " + table + " ADD COLUMN " + column).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dropColumns(tx *gorm.DB, table string, columns ...string) error {
+	for _, column := range columns {
+		if err := tx.Exec("ALTER TABLE " + table + " DROP COLUMN " + column).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
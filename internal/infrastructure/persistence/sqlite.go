@@ -0,0 +1,22 @@
+package persistence
+
+import (
+	"strings"
+	"time"
+)
+
+// sqliteBusyTimeout is how long a connection waits for another one's write
+// lock before failing with "database is locked".
+const sqliteBusyTimeout = 5 * time.Second
+
+// withParams appends query parameters to a SQLite DSN. Units of work write
+// after they read, so their transactions are begun IMMEDIATE: taking the
+// write lock up front lets concurrent units queue on the busy timeout
+// instead of failing when they try to upgrade a read lock.
+func withParams(dsn string, params ...string) string {
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + strings.Join(params, "&")
+}
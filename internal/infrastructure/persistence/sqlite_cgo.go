@@ -0,0 +1,20 @@
+//go:build cgo && !purego
+
+package persistence
+
+import (
+	"strconv"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// SQLiteDriver names the SQLite driver compiled into the binary.
+const SQLiteDriver = "mattn/go-sqlite3"
+
+// OpenSQLite returns the dialector for the SQLite database at dsn. Builds
+// with cgo use mattn/go-sqlite3; build with CGO_ENABLED=0 or -tags purego
+// for the pure-Go driver.
+func OpenSQLite(dsn string) gorm.Dialector {
+	return sqlite.Open(withParams(dsn, "_txlock=immediate", "_busy_timeout="+strconv.FormatInt(sqliteBusyTimeout.Milliseconds(), 10)))
+}
@@ -0,0 +1,19 @@
+//go:build !cgo || purego
+
+package persistence
+
+import (
+	"strconv"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// SQLiteDriver names the SQLite driver compiled into the binary.
+const SQLiteDriver = "glebarez/sqlite"
+
+// OpenSQLite returns the dialector for the SQLite database at dsn, using the
+// cgo-free glebarez/sqlite driver.
+func OpenSQLite(dsn string) gorm.Dialector {
+	return sqlite.Open(withParams(dsn, "_txlock=immediate", "_pragma=busy_timeout("+strconv.FormatInt(sqliteBusyTimeout.Milliseconds(), 10)+")"))
+}
@@ -0,0 +1,288 @@
+package sip2
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+)
+
+type Config struct {
+	// Username and Password are the credentials kiosks log in with. When
+	// Username is empty no login is required.
+	Username      string
+	Password      string
+	InstitutionID string
+	LibraryName   string
+	LoanPeriod    time.Duration
+	// TenantID is the library whose books the kiosks circulate; empty
+	// means the default tenant.
+	TenantID string
+}
+
+// Server is a SIP2 ACS (automated circulation system) for self-check
+// kiosks. Item identifiers are book IDs; there is no patron database, so
+// any patron identifier is accepted.
+type Server struct {
+	repo   repository.BookRepository
+	loans  *service.LoanService
+	config Config
+	now    func() time.Time
+}
+
+func NewServer(repo repository.BookRepository, loans *service.LoanService, config Config) *Server {
+	if config.LoanPeriod <= 0 {
+		config.LoanPeriod = 14 * 24 * time.Hour
+	}
+	return &Server{repo: repo, loans: loans, config: config, now: time.Now}
+}
+
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+func (s *Server) Serve(l net.Listener) error {
+	defer l.Close()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	// Queries still running when the kiosk hangs up are abandoned
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sess := s.NewSession()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\r')
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Printf("sip2: read from %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		line = strings.TrimLeft(line, "\n")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		resp, ok := sess.Handle(ctx, line)
+		if !ok {
+			log.Printf("sip2: closing %s: %s before login", conn.RemoteAddr(), line[:min(2, len(line))])
+			return
+		}
+		if resp == "" {
+			log.Printf("sip2: ignoring unsupported message %s from %s", line[:min(2, len(line))], conn.RemoteAddr())
+			continue
+		}
+		if _, err := io.WriteString(conn, resp+"\r"); err != nil {
+			return
+		}
+	}
+}
+
+// Session is the state of one kiosk connection.
+type Session struct {
+	server       *Server
+	loggedIn     bool
+	user         string
+	lastResponse string
+}
+
+// NewSession starts a connection-less session, mainly for tests.
+func (s *Server) NewSession() *Session {
+	return &Session{server: s, loggedIn: s.config.Username == ""}
+}
+
+// Handle answers one raw message. ok is false when the connection should be
+// dropped because the kiosk has not logged in. resp is empty for messages the
+// server doesn't support, which get no answer: SIP2 has no negative response
+// for them, and a resend request would only make the kiosk send them again.
+func (sess *Session) Handle(ctx context.Context, raw string) (resp string, ok bool) {
+	msg, err := Parse(raw)
+	if err != nil {
+		// Bad checksum or garbled message: ask the kiosk to send it again
+		return SCResendRequest, true
+	}
+
+	if msg.Code == ACSResendRequest {
+		return sess.lastResponse, true
+	}
+	if !sess.loggedIn && msg.Code != LoginRequest {
+		return "", false
+	}
+	ctx = repository.WithTenant(service.WithActor(ctx, sess.actor()), sess.server.config.TenantID)
+
+	var out *Message
+	switch msg.Code {
+	case LoginRequest:
+		out = sess.login(msg)
+	case SCStatusRequest:
+		out = sess.server.acsStatus()
+	case PatronStatusRequest:
+		out = sess.server.patronStatus(msg)
+	case ItemInfoRequest:
+		out = sess.server.itemInformation(ctx, msg)
+	case CheckoutRequest:
+		out = sess.server.checkout(ctx, msg)
+	case CheckinRequest:
+		out = sess.server.checkin(ctx, msg)
+	case RenewRequest:
+		out = sess.server.renew(ctx, msg)
+	default:
+		return "", true
+	}
+	out.Sequence = msg.Sequence
+	sess.lastResponse = out.Encode()
+	return sess.lastResponse, true
+}
+
+func (sess *Session) login(msg *Message) *Message {
+	cfg := sess.server.config
+	ok := cfg.Username == "" || (msg.Get("CN") == cfg.Username && msg.Get("CO") == cfg.Password)
+	if ok {
+		sess.loggedIn = true
+		sess.user = msg.Get("CN")
+	}
+	return &Message{Code: LoginResponse, Fixed: bit(ok)}
+}
+
+// actor names the kiosk in the inventory ledger.
+func (sess *Session) actor() string {
+	if sess.user == "" {
+		return "sip2"
+	}
+	return "sip2:" + sess.user
+}
+
+func (s *Server) acsStatus() *Message {
+	// Supported messages, in the order of the BX field: patron status,
+	// checkout, checkin, block patron, SC/ACS status, request resend, login,
+	// patron info, end session, fee paid, item info, item status update,
+	// patron enable, hold, renew, renew all.
+	supported := "YYYNYYYNNNYNNNYN"
+	fixed := "Y" + "Y" + "Y" + "Y" + "N" + "N" + "030" + "003" + formatDate(s.now()) + "2.00"
+	out := &Message{Code: ACSStatusResponse, Fixed: fixed}
+	out.Add("AO", s.config.InstitutionID).Add("AM", s.config.LibraryName).Add("BX", supported)
+	return out
+}
+
+func (s *Server) patronStatus(msg *Message) *Message {
+	patron := msg.Get("AA")
+	valid := patron != ""
+	// 14 patron status flags, all clear: nothing blocks this patron
+	fixed := strings.Repeat(" ", 14) + "000" + formatDate(s.now())
+	out := &Message{Code: PatronStatusResp, Fixed: fixed}
+	out.Add("AO", s.config.InstitutionID).Add("AA", patron).Add("AE", patron).
+		Add("BL", flag(valid)).Add("CQ", flag(valid))
+	if !valid {
+		out.Add("AF", "Patron not found")
+	}
+	return out
+}
+
+func (s *Server) itemInformation(ctx context.Context, msg *Message) *Message {
+	item := msg.Get("AB")
+	// Circulation status 01 (other) when the item is unknown, 03 when a copy
+	// is on the shelf and 04 when every copy is charged.
+	status := "01"
+	book, err := s.repo.GetByID(ctx, item)
+	if err == nil {
+		status = "04"
+		if book.Quantity > 0 {
+			status = "03"
+		}
+	}
+	out := &Message{Code: ItemInfoResponse, Fixed: status + "00" + "01" + formatDate(s.now())}
+	out.Add("AB", item).Add("AJ", title(book))
+	if err != nil {
+		out.Add("AF", "Item not found")
+	}
+	return out
+}
+
+func (s *Server) checkout(ctx context.Context, msg *Message) *Message {
+	now := s.now()
+	book, err := s.loans.CheckoutTo(ctx, msg.Get("AB"), msg.Get("AA"))
+	ok := err == nil
+	fixed := bit(ok) + flag(ok) + "N" + flag(ok) + formatDate(now)
+	out := &Message{Code: CheckoutResponse, Fixed: fixed}
+	out.Add("AO", s.config.InstitutionID).Add("AA", msg.Get("AA")).Add("AB", msg.Get("AB")).
+		Add("AJ", title(book)).Add("AH", dueDate(ok, now.Add(s.config.LoanPeriod)))
+	if err != nil {
+		out.Add("AF", screenMessage(err))
+	}
+	return out
+}
+
+func (s *Server) checkin(ctx context.Context, msg *Message) *Message {
+	book, err := s.loans.ReturnBook(ctx, msg.Get("AB"))
+	ok := err == nil
+	fixed := bit(ok) + flag(ok) + "N" + "N" + formatDate(s.now())
+	out := &Message{Code: CheckinResponse, Fixed: fixed}
+	out.Add("AO", s.config.InstitutionID).Add("AB", msg.Get("AB")).
+		Add("AQ", s.config.InstitutionID).Add("AJ", title(book))
+	if err != nil {
+		out.Add("AF", screenMessage(err))
+	}
+	return out
+}
+
+// renew extends the due date of the patron's loan of the item by a loan
+// period from now.
+func (s *Server) renew(ctx context.Context, msg *Message) *Message {
+	now := s.now()
+	book, _, err := s.loans.Renew(ctx, msg.Get("AB"), msg.Get("AA"))
+	ok := err == nil
+	fixed := bit(ok) + flag(ok) + "N" + "N" + formatDate(now)
+	out := &Message{Code: RenewResponse, Fixed: fixed}
+	out.Add("AO", s.config.InstitutionID).Add("AA", msg.Get("AA")).Add("AB", msg.Get("AB")).
+		Add("AJ", title(book)).Add("AH", dueDate(ok, now.Add(s.config.LoanPeriod)))
+	if err != nil {
+		out.Add("AF", screenMessage(err))
+	}
+	return out
+}
+
+func title(book *model.Book) string {
+	if book == nil {
+		return ""
+	}
+	return book.Title
+}
+
+func dueDate(ok bool, due time.Time) string {
+	if !ok {
+		return ""
+	}
+	return formatDate(due)
+}
+
+func screenMessage(err error) string {
+	switch {
+	case errors.Is(err, repository.ErrBookNotFound):
+		return "Item not found"
+	case errors.Is(err, service.ErrBookNotAvailable):
+		return "No copies available"
+	case errors.Is(err, service.ErrNoOpenLoan):
+		return "Item not checked out to this patron"
+	}
+	return "Transaction failed, please see a librarian"
+}
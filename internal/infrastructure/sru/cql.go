@@ -0,0 +1,204 @@
+package sru
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Node is a parsed CQL query: either a *Boolean or a *Clause.
+type Node interface{}
+
+type Boolean struct {
+	Op          string
+	Left, Right Node
+}
+
+type Clause struct {
+	Index    string
+	Relation string
+	Term     string
+}
+
+const serverChoice = "cql.serverchoice"
+
+var relationWords = map[string]bool{"any": true, "all": true, "adj": true, "exact": true, "within": true, "encloses": true}
+
+var booleans = map[string]bool{"and": true, "or": true, "not": true, "prox": true}
+
+// ParseCQL parses the subset of CQL we can run: search clauses joined by
+// boolean operators, with optional parentheses. Anything else comes back as
+// a diagnostic.
+func ParseCQL(query string) (Node, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, &Diagnostic{Code: DiagMandatoryParameter, Details: "query", Message: "Empty query"}
+	}
+	p := &parser{tokens: tokens}
+	node, err := p.scopedClause()
+	if err != nil {
+		return nil, err
+	}
+	if p.more() {
+		if strings.EqualFold(p.peek().text, "sortby") && !p.peek().quoted {
+			return nil, &Diagnostic{Code: DiagSortNotSupported, Message: "Sort not supported"}
+		}
+		return nil, syntaxError("unexpected " + p.peek().text)
+	}
+	return node, nil
+}
+
+type token struct {
+	text   string
+	quoted bool
+}
+
+func (t token) is(text string) bool {
+	return !t.quoted && t.text == text
+}
+
+func lex(query string) ([]token, error) {
+	var tokens []token
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == '/':
+			tokens = append(tokens, token{text: string(r)})
+			i++
+		case r == '=' || r == '<' || r == '>':
+			op := string(r)
+			if i+1 < len(runes) {
+				if pair := op + string(runes[i+1]); pair == "==" || pair == "<>" || pair == "<=" || pair == ">=" {
+					op = pair
+				}
+			}
+			tokens = append(tokens, token{text: op})
+			i += len(op)
+		case r == '"':
+			var b strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					if runes[i] != '"' && runes[i] != '\\' {
+						// Keep escaped wildcards escaped for the matcher
+						b.WriteRune('\\')
+					}
+				}
+				b.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, syntaxError("unterminated quoted string")
+			}
+			tokens = append(tokens, token{text: b.String(), quoted: true})
+			i++
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`()/=<>"`, runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{text: string(runes[start:i])})
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) more() bool {
+	return p.pos < len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	p.pos++
+	return t
+}
+
+func (p *parser) scopedClause() (Node, error) {
+	left, err := p.searchClause()
+	if err != nil {
+		return nil, err
+	}
+	for p.more() && !p.peek().quoted && booleans[strings.ToLower(p.peek().text)] {
+		op := strings.ToLower(p.next().text)
+		if op == "prox" {
+			return nil, &Diagnostic{Code: DiagUnsupportedBoolean, Details: op, Message: "Unsupported boolean operator"}
+		}
+		if p.more() && p.peek().is("/") {
+			return nil, &Diagnostic{Code: DiagUnsupportedBooleanModifier, Details: op, Message: "Unsupported boolean modifier"}
+		}
+		right, err := p.searchClause()
+		if err != nil {
+			return nil, err
+		}
+		left = &Boolean{Op: op, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) searchClause() (Node, error) {
+	if !p.more() {
+		return nil, syntaxError("missing search term")
+	}
+	if p.peek().is("(") {
+		p.next()
+		node, err := p.scopedClause()
+		if err != nil {
+			return nil, err
+		}
+		if !p.more() || !p.next().is(")") {
+			return nil, syntaxError("missing )")
+		}
+		return node, nil
+	}
+	if p.peek().is(")") || p.peek().is("/") || isRelationSymbol(p.peek()) {
+		return nil, syntaxError("unexpected " + p.peek().text)
+	}
+
+	first := p.next()
+	if !p.more() || !p.isRelation(p.peek()) {
+		return &Clause{Index: serverChoice, Relation: "=", Term: first.text}, nil
+	}
+	if first.quoted {
+		return nil, syntaxError("index name must not be quoted")
+	}
+
+	relation := strings.ToLower(p.next().text)
+	if p.more() && p.peek().is("/") {
+		return nil, &Diagnostic{Code: DiagUnsupportedRelationModifier, Details: relation, Message: "Unsupported relation modifier"}
+	}
+	if !p.more() || p.peek().is(")") || p.peek().is("(") || isRelationSymbol(p.peek()) {
+		return nil, syntaxError("missing search term after " + relation)
+	}
+	term := p.next()
+	return &Clause{Index: strings.ToLower(first.text), Relation: relation, Term: term.text}, nil
+}
+
+func (p *parser) isRelation(t token) bool {
+	return isRelationSymbol(t) || (!t.quoted && relationWords[strings.ToLower(t.text)])
+}
+
+func isRelationSymbol(t token) bool {
+	switch t.text {
+	case "=", "==", "<>", "<", ">", "<=", ">=":
+		return !t.quoted
+	}
+	return false
+}
+
+func syntaxError(details string) *Diagnostic {
+	return &Diagnostic{Code: DiagQuerySyntax, Details: details, Message: "Query syntax error"}
+}
@@ -0,0 +1,203 @@
+package sru
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+)
+
+var indexFields = map[string][]string{
+	serverChoice:   {"title", "author", "isbn"},
+	"cql.anywhere": {"title", "author", "isbn"},
+	"title":        {"title"},
+	"dc.title":     {"title"},
+	"author":       {"author"},
+	"creator":      {"author"},
+	"dc.creator":   {"author"},
+	"isbn":         {"isbn"},
+	"bath.isbn":    {"isbn"},
+}
+
+// matcher is a compiled CQL query.
+type matcher func(model.Book) bool
+
+func compile(node Node) (matcher, error) {
+	switch n := node.(type) {
+	case *Boolean:
+		left, err := compile(n.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := compile(n.Right)
+		if err != nil {
+			return nil, err
+		}
+		switch n.Op {
+		case "and":
+			return func(b model.Book) bool { return left(b) && right(b) }, nil
+		case "or":
+			return func(b model.Book) bool { return left(b) || right(b) }, nil
+		case "not":
+			return func(b model.Book) bool { return left(b) && !right(b) }, nil
+		}
+		return nil, &Diagnostic{Code: DiagUnsupportedBoolean, Details: n.Op, Message: "Unsupported boolean operator"}
+	case *Clause:
+		return compileClause(n)
+	}
+	return nil, syntaxError("unknown query node")
+}
+
+func compileClause(c *Clause) (matcher, error) {
+	if c.Index == "cql.allrecords" {
+		return func(model.Book) bool { return true }, nil
+	}
+	fields, ok := indexFields[c.Index]
+	if !ok {
+		return nil, &Diagnostic{Code: DiagUnsupportedIndex, Details: c.Index, Message: "Unsupported index"}
+	}
+
+	var match func(value string) bool
+	switch c.Relation {
+	case "=", "adj":
+		match = phraseMatcher(words(c.Term))
+	case "all":
+		match = allMatcher(words(c.Term))
+	case "any":
+		match = anyMatcher(words(c.Term))
+	case "==", "exact":
+		match = exactMatcher(c.Term)
+	case "<>":
+		exact := exactMatcher(c.Term)
+		match = func(value string) bool { return !exact(value) }
+	default:
+		return nil, &Diagnostic{Code: DiagUnsupportedRelation, Details: c.Relation, Message: "Unsupported relation"}
+	}
+
+	return func(b model.Book) bool {
+		for _, field := range fields {
+			if match(fieldValue(b, field)) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func fieldValue(b model.Book, field string) string {
+	switch field {
+	case "title":
+		return b.Title
+	case "author":
+		return b.Author
+	case "isbn":
+		return b.ISBN
+	}
+	return ""
+}
+
+// words splits a value into lowercase words. Hyphens are dropped so ISBNs
+// and hyphenated words compare as one word, while wildcards and escapes are
+// kept so a term word can be compiled into a pattern.
+func words(value string) []string {
+	value = strings.ToLower(strings.ReplaceAll(value, "-", ""))
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '*' && r != '?' && r != '\\'
+	})
+}
+
+// wordPattern turns a term word into a regexp, honouring the CQL wildcards
+// * and ? unless they are escaped with a backslash.
+func wordPattern(word string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	escaped := false
+	for _, r := range word {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '*':
+			b.WriteString(".*")
+		case r == '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+func patterns(termWords []string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, len(termWords))
+	for i, w := range termWords {
+		compiled[i] = wordPattern(w)
+	}
+	return compiled
+}
+
+func containsWord(value []string, pattern *regexp.Regexp) bool {
+	for _, w := range value {
+		if pattern.MatchString(w) {
+			return true
+		}
+	}
+	return false
+}
+
+func phraseMatcher(termWords []string) func(string) bool {
+	phrase := patterns(termWords)
+	return func(value string) bool {
+		valueWords := words(value)
+		if len(phrase) == 0 {
+			return false
+		}
+	outer:
+		for start := 0; start+len(phrase) <= len(valueWords); start++ {
+			for i, pattern := range phrase {
+				if !pattern.MatchString(valueWords[start+i]) {
+					continue outer
+				}
+			}
+			return true
+		}
+		return false
+	}
+}
+
+func allMatcher(termWords []string) func(string) bool {
+	all := patterns(termWords)
+	return func(value string) bool {
+		valueWords := words(value)
+		for _, pattern := range all {
+			if !containsWord(valueWords, pattern) {
+				return false
+			}
+		}
+		return len(all) > 0
+	}
+}
+
+func anyMatcher(termWords []string) func(string) bool {
+	anyOf := patterns(termWords)
+	return func(value string) bool {
+		valueWords := words(value)
+		for _, pattern := range anyOf {
+			if containsWord(valueWords, pattern) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func exactMatcher(term string) func(string) bool {
+	term = strings.ToLower(strings.TrimSpace(term))
+	return func(value string) bool {
+		return strings.ToLower(strings.TrimSpace(value)) == term
+	}
+}
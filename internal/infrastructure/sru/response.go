@@ -0,0 +1,100 @@
+package sru
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+const (
+	Namespace12     = "http://www.loc.gov/zing/srw/"
+	DiagNamespace12 = "http://www.loc.gov/zing/srw/diagnostic/"
+	Namespace20     = "http://docs.oasis-open.org/ns/search-ws/sruResponse"
+	DiagNamespace20 = "http://docs.oasis-open.org/ns/search-ws/diagnostic"
+)
+
+// Diagnostic codes from the SRU diagnostics list
+// (info:srw/diagnostic/1/<code>).
+const (
+	DiagGeneralSystemError          = 1
+	DiagUnsupportedOperation        = 4
+	DiagUnsupportedVersion          = 5
+	DiagUnsupportedParameterValue   = 6
+	DiagMandatoryParameter          = 7
+	DiagQuerySyntax                 = 10
+	DiagUnsupportedIndex            = 16
+	DiagUnsupportedRelation         = 19
+	DiagUnsupportedRelationModifier = 20
+	DiagUnsupportedBoolean          = 37
+	DiagUnsupportedBooleanModifier  = 46
+	DiagFirstRecordOutOfRange       = 61
+	DiagUnknownSchema               = 66
+	DiagUnsupportedRecordPacking    = 71
+	DiagSortNotSupported            = 80
+)
+
+type Diagnostic struct {
+	XMLName xml.Name
+	Code    int    `xml:"-"`
+	URI     string `xml:"uri"`
+	Details string `xml:"details,omitempty"`
+	Message string `xml:"message"`
+}
+
+func (d *Diagnostic) Error() string {
+	return fmt.Sprintf("SRU diagnostic %d: %s %s", d.Code, d.Message, d.Details)
+}
+
+type Response struct {
+	XMLName            xml.Name
+	Version            string       `xml:"version"`
+	NumberOfRecords    int          `xml:"numberOfRecords"`
+	Records            *Records     `xml:"records,omitempty"`
+	NextRecordPosition int          `xml:"nextRecordPosition,omitempty"`
+	Diagnostics        *Diagnostics `xml:"diagnostics,omitempty"`
+}
+
+type Records struct {
+	Records []Record `xml:"record"`
+}
+
+type Record struct {
+	RecordSchema string `xml:"recordSchema"`
+	// RecordPacking is SRU 1.2, RecordXMLEscaping its SRU 2.0 name
+	RecordPacking     string     `xml:"recordPacking,omitempty"`
+	RecordXMLEscaping string     `xml:"recordXMLEscaping,omitempty"`
+	RecordData        RecordData `xml:"recordData"`
+	RecordPosition    int        `xml:"recordPosition"`
+}
+
+type RecordData struct {
+	XML string `xml:",innerxml"`
+}
+
+type Diagnostics struct {
+	Diagnostics []Diagnostic `xml:"diagnostic"`
+}
+
+func newResponse(version string) *Response {
+	namespace := Namespace20
+	if version == "1.2" {
+		namespace = Namespace12
+	}
+	return &Response{
+		XMLName: xml.Name{Space: namespace, Local: "searchRetrieveResponse"},
+		Version: version,
+	}
+}
+
+func (r *Response) addDiagnostic(d *Diagnostic) *Response {
+	namespace := DiagNamespace20
+	if r.Version == "1.2" {
+		namespace = DiagNamespace12
+	}
+	d.XMLName = xml.Name{Space: namespace, Local: "diagnostic"}
+	d.URI = fmt.Sprintf("info:srw/diagnostic/1/%d", d.Code)
+	if r.Diagnostics == nil {
+		r.Diagnostics = &Diagnostics{}
+	}
+	r.Diagnostics.Diagnostics = append(r.Diagnostics.Diagnostics, *d)
+	return r
+}
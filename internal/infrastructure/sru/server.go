@@ -0,0 +1,158 @@
+package sru
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"strconv"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/infrastructure/dublincore"
+	"github.com/brianantony456/go-doc/internal/infrastructure/marc"
+)
+
+const (
+	SchemaDC      = "info:srw/schema/1/dc-v1.1"
+	SchemaMARCXML = "info:srw/schema/1/marcxml-v1.1"
+
+	defaultMaximumRecords = 10
+	maxMaximumRecords     = 100
+)
+
+var schemaAliases = map[string]string{
+	"":            SchemaDC,
+	"dc":          SchemaDC,
+	"oai_dc":      SchemaDC,
+	SchemaDC:      SchemaDC,
+	"marcxml":     SchemaMARCXML,
+	SchemaMARCXML: SchemaMARCXML,
+}
+
+type Server struct {
+	repo repository.BookRepository
+}
+
+func NewServer(repo repository.BookRepository) *Server {
+	return &Server{repo: repo}
+}
+
+// SearchRetrieve runs an SRU 1.2 or 2.0 searchRetrieve request. Anything
+// the client got wrong is reported as diagnostics in the response; the error
+// is only set when the repository fails.
+func (s *Server) SearchRetrieve(ctx context.Context, params url.Values) (*Response, error) {
+	version := params.Get("version")
+	switch version {
+	case "":
+		version = "2.0"
+	case "1.2", "2.0":
+	default:
+		return newResponse("2.0").addDiagnostic(&Diagnostic{Code: DiagUnsupportedVersion, Details: "2.0", Message: "Unsupported version"}), nil
+	}
+	resp := newResponse(version)
+
+	if op := params.Get("operation"); op != "" && op != "searchRetrieve" {
+		return resp.addDiagnostic(&Diagnostic{Code: DiagUnsupportedOperation, Details: op, Message: "Unsupported operation"}), nil
+	}
+	query := params.Get("query")
+	if query == "" {
+		return resp.addDiagnostic(&Diagnostic{Code: DiagMandatoryParameter, Details: "query", Message: "Mandatory parameter not supplied"}), nil
+	}
+	startRecord, diag := intParam(params, "startRecord", 1, 1)
+	if diag != nil {
+		return resp.addDiagnostic(diag), nil
+	}
+	maximumRecords, diag := intParam(params, "maximumRecords", defaultMaximumRecords, 0)
+	if diag != nil {
+		return resp.addDiagnostic(diag), nil
+	}
+	maximumRecords = min(maximumRecords, maxMaximumRecords)
+
+	schema, ok := schemaAliases[params.Get("recordSchema")]
+	if !ok {
+		return resp.addDiagnostic(&Diagnostic{Code: DiagUnknownSchema, Details: params.Get("recordSchema"), Message: "Unknown schema for retrieval"}), nil
+	}
+	for _, name := range []string{"recordPacking", "recordXMLEscaping"} {
+		if packing := params.Get(name); packing != "" && packing != "xml" {
+			return resp.addDiagnostic(&Diagnostic{Code: DiagUnsupportedRecordPacking, Details: packing, Message: "Unsupported record packing"}), nil
+		}
+	}
+
+	node, err := ParseCQL(query)
+	if err == nil {
+		var match matcher
+		if match, err = compile(node); err == nil {
+			return s.search(ctx, resp, match, schema, startRecord, maximumRecords)
+		}
+	}
+	var d *Diagnostic
+	if errors.As(err, &d) {
+		return resp.addDiagnostic(d), nil
+	}
+	return nil, err
+}
+
+func (s *Server) search(ctx context.Context, resp *Response, match matcher, schema string, startRecord, maximumRecords int) (*Response, error) {
+	books, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var hits []model.Book
+	for _, book := range books {
+		if match(book) {
+			hits = append(hits, book)
+		}
+	}
+	resp.NumberOfRecords = len(hits)
+	if maximumRecords == 0 || len(hits) == 0 {
+		return resp, nil
+	}
+	if startRecord > len(hits) {
+		return resp.addDiagnostic(&Diagnostic{Code: DiagFirstRecordOutOfRange, Details: strconv.Itoa(startRecord), Message: "First record position out of range"}), nil
+	}
+
+	end := min(startRecord-1+maximumRecords, len(hits))
+	resp.Records = &Records{}
+	for i, book := range hits[startRecord-1 : end] {
+		data, err := recordData(book, schema)
+		if err != nil {
+			return nil, err
+		}
+		record := Record{RecordSchema: schema, RecordData: RecordData{XML: data}, RecordPosition: startRecord + i}
+		if resp.Version == "1.2" {
+			record.RecordPacking = "xml"
+		} else {
+			record.RecordXMLEscaping = "xml"
+		}
+		resp.Records.Records = append(resp.Records.Records, record)
+	}
+	if end < len(hits) {
+		resp.NextRecordPosition = end + 1
+	}
+	return resp, nil
+}
+
+func recordData(book model.Book, schema string) (string, error) {
+	if schema == SchemaMARCXML {
+		record, err := marc.FromBook(book)
+		if err != nil {
+			return "", err
+		}
+		return marc.MarshalRecord(record)
+	}
+	out, err := xml.Marshal(dublincore.FromBook(book))
+	return string(out), err
+}
+
+func intParam(params url.Values, name string, def, minimum int) (int, *Diagnostic) {
+	value := params.Get(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < minimum {
+		return 0, &Diagnostic{Code: DiagUnsupportedParameterValue, Details: name, Message: "Unsupported parameter value"}
+	}
+	return n, nil
+}
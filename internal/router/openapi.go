@@ -0,0 +1,419 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+	"github.com/brianantony456/go-doc/internal/infrastructure/citation"
+	"github.com/brianantony456/go-doc/internal/infrastructure/gin_handler"
+	"github.com/brianantony456/go-doc/internal/infrastructure/opds"
+	"github.com/brianantony456/go-doc/internal/infrastructure/openapi"
+)
+
+// apiSpec collects the operations of the OpenAPI document.
+type apiSpec struct {
+	*openapi.Document
+	problem openapi.Response
+}
+
+// OpenAPI describes every route SetupRouter registers. Keep it next to the
+// Register functions: the route test fails when a route is missing here.
+func OpenAPI() *openapi.Document {
+	s := &apiSpec{Document: openapi.New(openapi.Info{
+		Title:   "go-doc library catalogue",
+		Version: "1.0.0",
+		Description: "Books, circulation, history and catalogue maintenance for one or several libraries. " +
+			"Name the library with X-API-Key, X-Tenant or the host name, and the person making changes with X-Actor. " +
+			"Errors are RFC 7807 problem details.",
+	})}
+	s.problem = openapi.Response{
+		Description: "Problem details",
+		Content:     map[string]openapi.MediaType{"application/problem+json": {Schema: s.Schema(gin_handler.Problem{})}},
+	}
+	s.Components.SecuritySchemes = map[string]openapi.SecurityScheme{
+		"apiKey":   {Type: "apiKey", In: "header", Name: "X-API-Key", Description: "Picks the library when several are served, and is recorded in the audit log as the actor"},
+		"adminKey": {Type: "http", Scheme: "bearer", Description: "One of the -admin-keys, required by the /admin routes"},
+	}
+	s.Security = []map[string][]string{{}, {"apiKey": {}}}
+
+	book := s.Schema(model.Book{})
+	books := s.Schema([]model.Book{})
+	asOf := query("as_of", "Answer as the catalogue was at this RFC 3339 timestamp or date", str())
+	id := query("id", "Book ID", str())
+	id.Required = true
+	actor := openapi.Parameter{Name: "X-Actor", In: "header", Description: "Who is making the change, recorded in the audit log", Schema: str()}
+
+	s.Add(http.MethodGet, "/api/openapi.json", &openapi.Operation{
+		Tags: []string{"docs"}, Summary: "This document",
+		Responses: s.responses(http.StatusOK, "OpenAPI document", jsonContent(&openapi.Schema{Type: "object"})),
+	})
+	s.Add(http.MethodGet, "/api/docs", &openapi.Operation{
+		Tags: []string{"docs"}, Summary: "Interactive explorer for this document",
+		Responses: s.responses(http.StatusOK, "HTML page", html()),
+	})
+
+	s.api(http.MethodGet, "/tenant", &openapi.Operation{
+		Tags: []string{"tenancy"}, Summary: "The library the request was made for",
+		Responses: s.responses(http.StatusOK, "Library", jsonContent(s.Schema(gin_handler.TenantInfo{})), http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound),
+	})
+
+	s.api(http.MethodGet, "/books", &openapi.Operation{
+		Tags: []string{"books"}, Summary: "List books",
+		Parameters: []openapi.Parameter{asOf},
+		Responses:  s.responses(http.StatusOK, "Books", jsonContent(books)),
+	})
+	s.api(http.MethodPost, "/books", &openapi.Operation{
+		Tags: []string{"books"}, Summary: "Create a book",
+		Description: "The ID is generated when left out.",
+		Parameters:  []openapi.Parameter{actor},
+		RequestBody: jsonBody(book),
+		Responses:   s.responses(http.StatusCreated, "Created book", jsonContent(book), http.StatusBadRequest, http.StatusRequestEntityTooLarge),
+	})
+	s.api(http.MethodGet, "/books/:id", &openapi.Operation{
+		Tags: []string{"books"}, Summary: "Get a book",
+		Parameters: []openapi.Parameter{asOf},
+		Responses:  s.responses(http.StatusOK, "Book", jsonContent(book), http.StatusNotFound),
+	})
+	s.api(http.MethodGet, "/books/diff", &openapi.Operation{
+		Tags: []string{"history"}, Summary: "Books added, changed or deleted between two instants",
+		Parameters: []openapi.Parameter{
+			required(query("from", "RFC 3339 timestamp or date", str())),
+			query("to", "RFC 3339 timestamp or date, now when left out", str()),
+		},
+		Responses: s.responses(http.StatusOK, "Changes", jsonContent(s.Schema(gin_handler.CatalogueDiff{})), http.StatusBadRequest),
+	})
+
+	s.api(http.MethodPatch, "/checkout", &openapi.Operation{
+		Tags: []string{"circulation"}, Summary: "Check out a copy",
+		Description: "Opens a loan like POST /books/{id}/loans, but answers with the book.",
+		Parameters:  []openapi.Parameter{id, actor},
+		Responses:   s.responses(http.StatusOK, "Book with one copy fewer", jsonContent(book), http.StatusBadRequest, http.StatusNotFound),
+	})
+	s.api(http.MethodPatch, "/return", &openapi.Operation{
+		Tags: []string{"circulation"}, Summary: "Return a copy",
+		Description: "Returns the book's oldest open loan. Superseded by POST /loans/{id}/return.",
+		Parameters:  []openapi.Parameter{id, actor},
+		Responses:   s.responses(http.StatusOK, "Book with one copy more", jsonContent(book), http.StatusBadRequest, http.StatusNotFound),
+	})
+	loan := s.Schema(model.Loan{})
+	s.api(http.MethodGet, "/books/:id/loans", &openapi.Operation{
+		Tags: []string{"circulation"}, Summary: "List the loans of a book",
+		Description: "Open and returned loans, oldest first. OPDS borrow links point here; POST to borrow.",
+		Responses:   s.responses(http.StatusOK, "Loans", jsonContent(s.Schema([]model.Loan{})), http.StatusNotFound),
+	})
+	s.api(http.MethodPost, "/books/:id/loans", &openapi.Operation{
+		Tags: []string{"circulation"}, Summary: "Lend a copy of a book",
+		Parameters: []openapi.Parameter{actor},
+		Responses: withHeaders(s.responses(http.StatusCreated, "New loan", jsonContent(loan), http.StatusBadRequest, http.StatusNotFound),
+			map[string]openapi.Header{"Location": {Description: "URL of the loan", Schema: str()}}),
+	})
+	s.api(http.MethodGet, "/loans/:id", &openapi.Operation{
+		Tags: []string{"circulation"}, Summary: "Get a loan",
+		Responses: s.responses(http.StatusOK, "Loan", jsonContent(loan), http.StatusNotFound),
+	})
+	s.api(http.MethodPost, "/loans/:id/return", &openapi.Operation{
+		Tags: []string{"circulation"}, Summary: "Return a loan",
+		Description: "Returning a loan already returned changes nothing and answers the loan as it is.",
+		Parameters:  []openapi.Parameter{actor},
+		Responses: withHeaders(s.responses(http.StatusOK, "Returned loan", jsonContent(loan), http.StatusNotFound),
+			map[string]openapi.Header{"Content-Location": {Description: "URL of the loan", Schema: str()}}),
+	})
+	s.api(http.MethodPost, "/books/:id/adjustments", &openapi.Operation{
+		Tags: []string{"circulation"}, Summary: "Correct the quantity of a book",
+		Parameters:  []openapi.Parameter{actor},
+		RequestBody: jsonBody(s.Schema(gin_handler.AdjustmentRequest{})),
+		Responses:   s.responses(http.StatusOK, "Adjusted book", jsonContent(book), http.StatusBadRequest, http.StatusNotFound),
+	})
+	s.api(http.MethodGet, "/books/:id/ledger", &openapi.Operation{
+		Tags: []string{"circulation"}, Summary: "Quantity changes of a book",
+		Responses: s.responses(http.StatusOK, "Ledger entries, oldest first", jsonContent(s.Schema([]model.LedgerEntry{})), http.StatusNotFound),
+	})
+
+	s.api(http.MethodGet, "/books/:id/revisions", &openapi.Operation{
+		Tags: []string{"revisions"}, Summary: "Changes made to a book",
+		Responses: s.responses(http.StatusOK, "Audit entries, oldest first", jsonContent(s.Schema([]model.AuditEntry{})), http.StatusNotFound),
+	})
+	s.api(http.MethodPost, "/books/:id/revert", &openapi.Operation{
+		Tags: []string{"revisions"}, Summary: "Restore a book's catalogue data to a revision",
+		Parameters:  []openapi.Parameter{actor},
+		RequestBody: jsonBody(s.Schema(gin_handler.RevertRequest{})),
+		Responses:   s.responses(http.StatusOK, "Reverted book", jsonContent(book), http.StatusBadRequest, http.StatusNotFound, http.StatusConflict),
+	})
+	s.api(http.MethodPost, "/undo", &openapi.Operation{
+		Tags: []string{"revisions"}, Security: []map[string][]string{{"apiKey": {}}}, Summary: "Undo the last catalogue change made with the API key",
+		Description: "Only changes from the past " + service.UndoWindow.String() + " made with the same API key can be undone, so requests without one are refused.",
+		Responses:   s.responses(http.StatusOK, "Restored book", jsonContent(book), http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict),
+	})
+
+	s.api(http.MethodPost, "/books/import", &openapi.Operation{
+		Tags: []string{"marc"}, Summary: "Import MARC21 or MARCXML records",
+		Description: "Records whose 001 matches a book update it and keep its quantity; the rest are created.",
+		Parameters:  []openapi.Parameter{actor},
+		RequestBody: &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+			"application/marc":        {Schema: &openapi.Schema{Type: "string", Format: "binary"}},
+			"application/marcxml+xml": {Schema: str()},
+		}},
+		Responses: s.responses(http.StatusOK, "Imported books", jsonContent(s.Schema(gin_handler.ImportResult{})), http.StatusBadRequest, http.StatusRequestEntityTooLarge),
+	})
+	s.api(http.MethodGet, "/books/export", &openapi.Operation{
+		Tags: []string{"marc"}, Summary: "Export every book as MARCXML",
+		Responses: s.responses(http.StatusOK, "MARCXML collection", content("application/marcxml+xml", str())),
+	})
+
+	formats := query("format", "Citation format", &openapi.Schema{Type: "string", Enum: []string{
+		string(citation.BibTeX), string(citation.RIS), string(citation.CSLJSON),
+	}})
+	citations := map[string]openapi.MediaType{}
+	for _, f := range []citation.Format{citation.BibTeX, citation.RIS, citation.CSLJSON} {
+		citations[f.ContentType()] = openapi.MediaType{Schema: str()}
+	}
+	s.api(http.MethodGet, "/books/:id/citation", &openapi.Operation{
+		Tags: []string{"citations"}, Summary: "Cite a book",
+		Parameters: []openapi.Parameter{formats},
+		Responses:  s.responses(http.StatusOK, "Citation", citations, http.StatusBadRequest, http.StatusNotFound),
+	})
+	s.api(http.MethodGet, "/citations", &openapi.Operation{
+		Tags: []string{"citations"}, Summary: "Cite several books",
+		Parameters: []openapi.Parameter{
+			required(query("ids", "Comma separated book IDs, or the parameter repeated", str())),
+			formats,
+		},
+		Responses: s.responses(http.StatusOK, "Citations", citations, http.StatusBadRequest, http.StatusNotFound),
+	})
+
+	s.api(http.MethodGet, "/books/duplicates", &openapi.Operation{
+		Tags: []string{"merges"}, Summary: "Pairs of books that look like the same title",
+		Description: "Only books that share an ISBN or the first letters of their title are compared.",
+		Parameters: []openapi.Parameter{query("min_score", "Lowest score reported, "+strconv.FormatFloat(service.DefaultMinScore, 'f', -1, 64)+" when left out",
+			&openapi.Schema{Type: "number", Minimum: float(0), Maximum: float(1)})},
+		Responses: s.responses(http.StatusOK, "Candidates, best match first", jsonContent(s.Schema([]service.DuplicateCandidate{})), http.StatusBadRequest),
+	})
+	s.api(http.MethodGet, "/merges", &openapi.Operation{
+		Tags: []string{"merges"}, Summary: "List merges",
+		Responses: s.responses(http.StatusOK, "Merges", jsonContent(s.Schema([]model.BookMerge{}))),
+	})
+	s.api(http.MethodPost, "/merges", &openapi.Operation{
+		Tags: []string{"merges"}, Summary: "Merge a duplicate into the book that survives",
+		Description: "The duplicate's copies, loans and ledger entries move to the survivor.",
+		Parameters:  []openapi.Parameter{actor},
+		RequestBody: jsonBody(s.Schema(gin_handler.MergeRequest{})),
+		Responses:   s.responses(http.StatusCreated, "Merge", jsonContent(s.Schema(model.BookMerge{})), http.StatusBadRequest, http.StatusNotFound),
+	})
+	s.api(http.MethodPost, "/merges/:id/undo", &openapi.Operation{
+		Tags: []string{"merges"}, Summary: "Split a merge again",
+		Parameters: []openapi.Parameter{actor},
+		Responses:  s.responses(http.StatusOK, "Undone merge", jsonContent(s.Schema(model.BookMerge{})), http.StatusNotFound, http.StatusConflict),
+	})
+
+	admin := []map[string][]string{{"adminKey": {}}}
+	s.api(http.MethodGet, "/admin/audit", &openapi.Operation{
+		Tags: []string{"admin"}, Security: admin, Summary: "Audit log, oldest first",
+		Parameters: []openapi.Parameter{
+			query("actor", "", str()),
+			query("action", "", str()),
+			query("entity_type", "", str()),
+			query("entity_id", "", str()),
+			query("request_id", "", str()),
+			query("from", "RFC 3339 timestamp or date", str()),
+			query("to", "RFC 3339 timestamp or date", str()),
+			query("after_id", "Last ID of the previous page", &openapi.Schema{Type: "integer", Minimum: float(0)}),
+			query("limit", "", &openapi.Schema{Type: "integer", Minimum: float(1), Maximum: float(1000)}),
+		},
+		Responses: s.responses(http.StatusOK, "Audit entries", jsonContent(s.Schema(gin_handler.AuditPage{})), http.StatusBadRequest, http.StatusUnauthorized),
+	})
+	s.api(http.MethodGet, "/admin/audit/verify", &openapi.Operation{
+		Tags: []string{"admin"}, Security: admin, Summary: "Check the audit hash chain",
+		Responses: s.responses(http.StatusOK, "Result", jsonContent(s.Schema(service.Verification{})), http.StatusUnauthorized),
+	})
+	s.api(http.MethodGet, "/admin/cache", &openapi.Operation{
+		Tags: []string{"admin"}, Security: admin, Summary: "Hits and misses of the book cache since the server started",
+		Responses: s.responses(http.StatusOK, "Cache statistics", jsonContent(s.Schema(gin_handler.CacheReport{})), http.StatusUnauthorized),
+	})
+	s.api(http.MethodGet, "/admin/deprecations", &openapi.Operation{
+		Tags: []string{"admin"}, Security: admin, Summary: "How often the library used deprecated routes",
+		Responses: s.responses(http.StatusOK, "Use counters", jsonContent(s.Schema([]gin_handler.DeprecatedUse{})), http.StatusUnauthorized),
+	})
+
+	page := query("page", "", &openapi.Schema{Type: "integer", Minimum: float(1)})
+	s.Add(http.MethodGet, "/opds", &openapi.Operation{
+		Tags: []string{"opds"}, Summary: "OPDS navigation feed",
+		Responses: s.responses(http.StatusOK, "Feed", content(opds.NavigationType, str())),
+	})
+	s.Add(http.MethodGet, "/opds/books", &openapi.Operation{
+		Tags: []string{"opds"}, Summary: "OPDS acquisition feed of every book",
+		Parameters: []openapi.Parameter{query("available", "Only books with copies on the shelf", &openapi.Schema{Type: "boolean"}), page},
+		Responses:  s.responses(http.StatusOK, "Feed", content(opds.AcquisitionType, str()), http.StatusNotFound),
+	})
+	s.Add(http.MethodGet, "/opds/books/:id", &openapi.Operation{
+		Tags: []string{"opds"}, Summary: "OPDS entry of a book",
+		Responses: s.responses(http.StatusOK, "Entry", content(opds.EntryType, str()), http.StatusNotFound),
+	})
+	s.Add(http.MethodGet, "/opds/search", &openapi.Operation{
+		Tags: []string{"opds"}, Summary: "Search by title, author or ISBN",
+		Parameters: []openapi.Parameter{query("q", "Search terms", str()), page},
+		Responses:  s.responses(http.StatusOK, "Feed", content(opds.AcquisitionType, str()), http.StatusNotFound),
+	})
+	s.Add(http.MethodGet, "/opds/opensearch.xml", &openapi.Operation{
+		Tags: []string{"opds"}, Summary: "OpenSearch description",
+		Responses: s.responses(http.StatusOK, "Description", content(opds.OpenSearchType, str())),
+	})
+
+	oai := []openapi.Parameter{
+		required(query("verb", "", &openapi.Schema{Type: "string", Enum: []string{
+			"Identify", "ListMetadataFormats", "ListSets", "ListIdentifiers", "ListRecords", "GetRecord",
+		}})),
+		query("identifier", "", str()),
+		query("metadataPrefix", "", str()),
+		query("from", "", str()),
+		query("until", "", str()),
+		query("resumptionToken", "", str()),
+	}
+	s.Add(http.MethodGet, "/oai", &openapi.Operation{
+		Tags: []string{"oai-pmh"}, Summary: "OAI-PMH request",
+		Description: "Datestamps are when a book was last changed. Deleted books stay in lists as deleted records (deletedRecord is persistent).",
+		Parameters:  oai,
+		Responses:   s.responses(http.StatusOK, "OAI-PMH response", content("text/xml", str())),
+	})
+	s.Add(http.MethodPost, "/oai", &openapi.Operation{
+		Tags: []string{"oai-pmh"}, Summary: "OAI-PMH request as a form",
+		RequestBody: &openapi.RequestBody{Required: true, Content: content("application/x-www-form-urlencoded", &openapi.Schema{
+			Type: "object", Properties: map[string]*openapi.Schema{"verb": oai[0].Schema},
+		})},
+		Responses: s.responses(http.StatusOK, "OAI-PMH response", content("text/xml", str()), http.StatusBadRequest),
+	})
+
+	s.Add(http.MethodGet, "/sru", &openapi.Operation{
+		Tags: []string{"sru"}, Summary: "SRU searchRetrieve",
+		Parameters: []openapi.Parameter{
+			query("query", "CQL query", str()),
+			query("version", "", str()),
+			query("operation", "", &openapi.Schema{Type: "string", Enum: []string{"searchRetrieve"}}),
+			query("startRecord", "", &openapi.Schema{Type: "integer", Minimum: float(1)}),
+			query("maximumRecords", "", &openapi.Schema{Type: "integer", Minimum: float(0)}),
+			query("recordSchema", "", str()),
+		},
+		Responses: s.responses(http.StatusOK, "SRU response", content("application/sru+xml", str())),
+	})
+
+	for path, summary := range map[string]string{
+		"/books":     "Book list page",
+		"/books/:id": "Book page",
+		"/create":    "Create book page",
+		"/checkout":  "Checkout and return page",
+	} {
+		s.Add(http.MethodGet, path, &openapi.Operation{
+			Tags: []string{"pages"}, Summary: summary,
+			Responses: s.responses(http.StatusOK, "HTML page", html()),
+		})
+	}
+
+	return s.Document
+}
+
+// api describes a route of the current API version, and the same route
+// under the deprecated unversioned alias.
+func (s *apiSpec) api(method, path string, op *openapi.Operation) {
+	if method != http.MethodGet {
+		op.Parameters = append(op.Parameters, idempotencyKey)
+		for _, status := range []int{http.StatusConflict, http.StatusUnprocessableEntity} {
+			op.Responses[strconv.Itoa(status)] = openapi.Response{Description: http.StatusText(status), Content: s.problem.Content}
+		}
+	}
+	s.Add(method, "/api/"+CurrentAPIVersion+path, op)
+
+	alias := *op
+	alias.Deprecated = true
+	alias.Description = strings.TrimSpace("Deprecated alias of /api/" + CurrentAPIVersion + path + ". " + op.Description)
+	alias.Responses = map[string]openapi.Response{}
+	for status, response := range op.Responses {
+		alias.Responses[status] = response
+	}
+	withHeaders(alias.Responses, deprecationHeaders)
+	alias.Responses[strconv.Itoa(http.StatusGone)] = openapi.Response{Description: "Past the sunset", Content: s.problem.Content}
+	s.Add(method, "/api"+path, &alias)
+}
+
+var idempotencyKey = openapi.Parameter{
+	Name: "Idempotency-Key", In: "header", Schema: &openapi.Schema{Type: "string", MaxLength: length(255)},
+	Description: "Retries sent with the same key get the first response replayed, with Idempotent-Replayed: true. " +
+		"Keys belong to the API key they were sent with, and a retry may go through the unversioned alias. Reusing a key for a different request is a 422.",
+}
+
+var deprecationHeaders = map[string]openapi.Header{
+	"Deprecation": {Description: "When the route was deprecated, as @<unix time> (RFC 9745)", Schema: str()},
+	"Sunset":      {Description: "When the route stops answering (RFC 8594)", Schema: str()},
+	"Link":        {Description: "The successor-version of the route", Schema: str()},
+}
+
+// responses is the success response followed by the problems the operation
+// is known to answer with. Any other failure is a default problem.
+func (s *apiSpec) responses(status int, description string, body map[string]openapi.MediaType, problems ...int) map[string]openapi.Response {
+	responses := map[string]openapi.Response{
+		strconv.Itoa(status): {Description: description, Content: body},
+		"default":            s.problem,
+	}
+	for _, p := range problems {
+		responses[strconv.Itoa(p)] = openapi.Response{Description: http.StatusText(p), Content: s.problem.Content}
+	}
+	return responses
+}
+
+// withHeaders adds headers to the success responses, leaving the original
+// header maps alone.
+func withHeaders(responses map[string]openapi.Response, headers map[string]openapi.Header) map[string]openapi.Response {
+	for status, response := range responses {
+		if !strings.HasPrefix(status, "2") {
+			continue
+		}
+		merged := map[string]openapi.Header{}
+		for name, h := range response.Headers {
+			merged[name] = h
+		}
+		for name, h := range headers {
+			merged[name] = h
+		}
+		response.Headers = merged
+		responses[status] = response
+	}
+	return responses
+}
+
+func query(name, description string, schema *openapi.Schema) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+func required(p openapi.Parameter) openapi.Parameter {
+	p.Required = true
+	return p
+}
+
+func str() *openapi.Schema {
+	return &openapi.Schema{Type: "string"}
+}
+
+func float(n float64) *float64 {
+	return &n
+}
+
+func length(n int) *int {
+	return &n
+}
+
+func content(mediaType string, schema *openapi.Schema) map[string]openapi.MediaType {
+	return map[string]openapi.MediaType{mediaType: {Schema: schema}}
+}
+
+func jsonContent(schema *openapi.Schema) map[string]openapi.MediaType {
+	return content("application/json", schema)
+}
+
+func html() map[string]openapi.MediaType {
+	return content("text/html", str())
+}
+
+func jsonBody(schema *openapi.Schema) *openapi.RequestBody {
+	return &openapi.RequestBody{Required: true, Content: jsonContent(schema)}
+}
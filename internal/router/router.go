@@ -1,36 +1,151 @@
 package router
 
 import (
+	"encoding/json"
+	"fmt"
+	"html/template"
 	"net/http"
+	"os"
+	"time"
 
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+	"github.com/brianantony456/go-doc/internal/infrastructure/cache"
 	"github.com/brianantony456/go-doc/internal/infrastructure/gin_handler"
+	"github.com/brianantony456/go-doc/internal/infrastructure/oaipmh"
 	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+	"github.com/brianantony456/go-doc/internal/infrastructure/sru"
+	"github.com/brianantony456/go-doc/internal/ui/web"
 	"github.com/gin-gonic/gin"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-// SetupRouter initializes the router with all the routes and returns it along with the DB connection
-func SetupRouter() (*gin.Engine, *gorm.DB, error) {
-	db, err := gorm.Open(sqlite.Open("books.db"), &gorm.Config{})
+type Config struct {
+	// Database is the SQLite file the catalogue lives in, books.db when
+	// empty.
+	Database string
+	// QueryTimeout caps every database query; zero means queries only stop
+	// when the client goes away.
+	QueryTimeout time.Duration
+	// CacheTTL is how long book reads are cached; zero disables the cache.
+	CacheTTL  time.Duration
+	CacheSize int
+	// Tenants are the libraries served; with none, everything belongs to
+	// the default tenant.
+	Tenants []gin_handler.Tenant
+	// MaxBodyBytes caps request bodies; zero means no limit.
+	MaxBodyBytes int64
+	// IdempotencyWindow is how long the response to a request sent with an
+	// Idempotency-Key is kept for retries; zero ignores the header.
+	IdempotencyWindow time.Duration
+	// UnversionedSunset is when the unversioned /api alias stops
+	// answering; zero keeps it.
+	UnversionedSunset time.Time
+	// AdminKeys open the /api/admin routes as bearer tokens; with none
+	// the admin routes refuse every request.
+	AdminKeys []string
+}
+
+// CurrentAPIVersion is the version the unversioned /api alias serves.
+const CurrentAPIVersion = "v1"
+
+// unversionedDeprecated is when /api/v1 arrived and the unversioned routes
+// became an alias.
+var unversionedDeprecated = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// LoadTenants reads the tenants from a JSON array of gin_handler.Tenant.
+func LoadTenants(path string) ([]gin_handler.Tenant, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tenants []gin_handler.Tenant
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, t := range tenants {
+		if t.ID == "" {
+			return nil, fmt.Errorf("%s: tenant without an id", path)
+		}
+	}
+	return tenants, nil
+}
+
+// Services are what the routes are built on, for servers that run next to
+// the HTTP API and must share its cache.
+type Services struct {
+	DB          *gorm.DB
+	Books       repository.BookRepository
+	BookService *service.BookService
+	LoanService *service.LoanService
+}
+
+// SetupRouter initializes the router with all the routes and returns it along with the services behind them
+func SetupRouter(config Config) (*gin.Engine, *Services, error) {
+	database := config.Database
+	if database == "" {
+		database = "books.db"
+	}
+	db, err := gorm.Open(persistence.OpenSQLite(database), &gorm.Config{})
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := persistence.MigrateUp(db); err != nil {
+		return nil, nil, err
+	}
 
-	bookRepo := persistence.NewGormBookRepository(db)
-	bookHandler := gin_handler.NewBookHandler(bookRepo)
+	gormBooks := persistence.NewGormBookRepository(db).WithQueryTimeout(config.QueryTimeout)
+	var bookRepo repository.BookRepository = gormBooks
+	var cacheStats func() cache.Stats
+	if config.CacheTTL > 0 {
+		cached := cache.NewBookRepository(bookRepo, cache.Config{TTL: config.CacheTTL, MaxEntries: config.CacheSize})
+		bookRepo, cacheStats = cached, cached.Stats
+	}
+	audit := service.NewAuditService(persistence.NewGormAuditRepository(db))
+	bookService := service.NewBookService(bookRepo, persistence.NewGormLedgerRepository(db), audit, persistence.NewGormUnitOfWork(db))
+	bookHandler := gin_handler.NewBookHandler(bookRepo, bookService, service.NewHistoryService(gormBooks))
 
 	router := gin.Default()
-	apiRoutes := router.Group("/api")
-	{
-		apiRoutes.GET("/books", bookHandler.GetBooks)
-		apiRoutes.POST("/books", bookHandler.CreateBook)
-		apiRoutes.GET("/books/:id", bookHandler.BookById)
-		apiRoutes.PATCH("/checkout", bookHandler.CheckoutBook)
-		apiRoutes.PATCH("/return", bookHandler.ReturnBook)
+	// The API description is public, so it is served before any tenant is
+	// resolved
+	RegisterDocsRoutes(router.Group("/api"), gin_handler.NewOpenAPIHandler(OpenAPI(), web.Explorer))
+	router.Use(gin_handler.TenantScope(config.Tenants))
+	router.NoRoute(gin_handler.RouteNotFound)
+	if config.MaxBodyBytes > 0 {
+		router.Use(gin_handler.LimitBody(config.MaxBodyBytes))
 	}
+	loans := persistence.NewGormLoanRepository(db)
+	mergeHandler := gin_handler.NewMergeHandler(service.NewMergeService(bookService, persistence.NewGormMergeRepository(db), loans))
+	loanService := service.NewLoanService(bookService, loans)
+	loanHandler := gin_handler.NewLoanHandler(loanService)
+	auditHandler := gin_handler.NewAuditHandler(audit)
+	usage := gin_handler.NewDeprecationUsage()
+	idempotency := service.NewIdempotencyService(persistence.NewGormIdempotencyRepository(db), config.IdempotencyWindow)
+	v1 := APIVersion{Name: "v1", Register: func(apiRoutes *gin.RouterGroup) {
+		if config.IdempotencyWindow > 0 {
+			apiRoutes.Use(gin_handler.Idempotency(idempotency, apiRoutes.BasePath()))
+		}
+		RegisterAPIRoutes(apiRoutes, bookHandler)
+		RegisterLoanRoutes(apiRoutes, loanHandler)
+		RegisterMergeRoutes(apiRoutes, mergeHandler)
+		adminRoutes := apiRoutes.Group("/admin")
+		RegisterAdminRoutes(adminRoutes, config.AdminKeys, auditHandler)
+		adminRoutes.GET("/deprecations", gin_handler.ListDeprecatedUses(usage))
+		adminRoutes.GET("/cache", gin_handler.CacheStats(cacheStats))
+	}}
+	MountAPI(router.Group("/api"), []APIVersion{v1}, CurrentAPIVersion, gin_handler.Deprecation{
+		Since:  unversionedDeprecated,
+		Sunset: config.UnversionedSunset,
+	}, usage)
+	RegisterOPDSRoutes(router.Group("/opds"), gin_handler.NewOPDSHandler(bookRepo, "/opds", "/api/"+CurrentAPIVersion))
+	RegisterOAIRoutes(router, gin_handler.NewOAIHandler(oaipmh.NewProvider(bookRepo, gormBooks, oaipmh.Config{
+		RepositoryName:       "go-doc library catalogue",
+		AdminEmail:           "admin@localhost",
+		RepositoryIdentifier: "go-doc",
+	})))
+	RegisterSRURoutes(router, gin_handler.NewSRUHandler(sru.NewServer(bookRepo)))
 
-	router.LoadHTMLGlob("internal/ui/web/templates/*")
+	router.SetHTMLTemplate(template.Must(template.ParseFS(web.Templates, "templates/*.html")))
 	router.GET("/books", gin_handler.ServeBooksPage)
 	router.GET("/create", func(c *gin.Context) {
 		c.HTML(http.StatusOK, "create_book.html", gin.H{})
@@ -42,5 +157,83 @@ func SetupRouter() (*gin.Engine, *gorm.DB, error) {
 		c.HTML(http.StatusOK, "checkout_return.html", gin.H{})
 	})
 
-	return router, db, nil
+	return router, &Services{DB: db, Books: bookRepo, BookService: bookService, LoanService: loanService}, nil
+}
+
+// RegisterAPIRoutes adds the JSON API routes to the /api group
+func RegisterAPIRoutes(apiRoutes *gin.RouterGroup, bookHandler *gin_handler.BookHandler) {
+	apiRoutes.Use(gin_handler.RequestContext())
+
+	apiRoutes.GET("/tenant", gin_handler.CurrentTenant)
+	apiRoutes.GET("/books", bookHandler.GetBooks)
+	apiRoutes.POST("/books", bookHandler.CreateBook)
+	apiRoutes.GET("/books/:id", bookHandler.BookById)
+	apiRoutes.POST("/books/:id/adjustments", bookHandler.AdjustBook)
+	apiRoutes.GET("/books/:id/ledger", bookHandler.BookLedger)
+	apiRoutes.GET("/books/:id/revisions", bookHandler.BookRevisions)
+	apiRoutes.POST("/books/:id/revert", bookHandler.RevertBook)
+	apiRoutes.POST("/undo", bookHandler.UndoLastChange)
+
+	apiRoutes.POST("/books/import", bookHandler.ImportMARC)
+	apiRoutes.GET("/books/export", bookHandler.ExportMARCXML)
+	apiRoutes.GET("/books/diff", bookHandler.BooksDiff)
+
+	apiRoutes.GET("/books/:id/citation", bookHandler.BookCitation)
+	apiRoutes.GET("/citations", bookHandler.BulkCitations)
+}
+
+// RegisterDocsRoutes adds the OpenAPI document and its explorer to the /api
+// group
+func RegisterDocsRoutes(apiRoutes *gin.RouterGroup, openAPIHandler *gin_handler.OpenAPIHandler) {
+	apiRoutes.GET("/openapi.json", openAPIHandler.Spec)
+	apiRoutes.GET("/docs", openAPIHandler.Explorer)
+}
+
+// RegisterLoanRoutes adds circulation to the /api group, after
+// RegisterAPIRoutes. Loans supersede PATCH /checkout and /return, which
+// work on top of them.
+func RegisterLoanRoutes(apiRoutes *gin.RouterGroup, loanHandler *gin_handler.LoanHandler) {
+	apiRoutes.PATCH("/checkout", loanHandler.CheckoutBook)
+	apiRoutes.PATCH("/return", loanHandler.ReturnBook)
+	apiRoutes.GET("/books/:id/loans", loanHandler.ListBookLoans)
+	apiRoutes.POST("/books/:id/loans", loanHandler.LendBook)
+	apiRoutes.GET("/loans/:id", loanHandler.GetLoan)
+	apiRoutes.POST("/loans/:id/return", loanHandler.ReturnLoan)
+}
+
+// RegisterMergeRoutes adds duplicate detection and merging to the /api group
+func RegisterMergeRoutes(apiRoutes *gin.RouterGroup, mergeHandler *gin_handler.MergeHandler) {
+	apiRoutes.GET("/books/duplicates", mergeHandler.Duplicates)
+	apiRoutes.GET("/merges", mergeHandler.ListMerges)
+	apiRoutes.POST("/merges", mergeHandler.MergeBooks)
+	apiRoutes.POST("/merges/:id/undo", mergeHandler.UndoMerge)
+}
+
+// RegisterAdminRoutes puts the /api/admin group behind the admin keys and
+// adds the audit log routes to it. Routes added to the group later are
+// behind the keys too.
+func RegisterAdminRoutes(adminRoutes *gin.RouterGroup, adminKeys []string, auditHandler *gin_handler.AuditHandler) {
+	adminRoutes.Use(gin_handler.RequireAdminKey(adminKeys))
+	adminRoutes.GET("/audit", auditHandler.ListAudit)
+	adminRoutes.GET("/audit/verify", auditHandler.VerifyAudit)
+}
+
+// RegisterOPDSRoutes adds the OPDS catalogue feeds for e-reader apps
+func RegisterOPDSRoutes(opdsRoutes *gin.RouterGroup, opdsHandler *gin_handler.OPDSHandler) {
+	opdsRoutes.GET("", opdsHandler.Root)
+	opdsRoutes.GET("/books", opdsHandler.Books)
+	opdsRoutes.GET("/books/:id", opdsHandler.Book)
+	opdsRoutes.GET("/search", opdsHandler.Search)
+	opdsRoutes.GET("/opensearch.xml", opdsHandler.OpenSearch)
+}
+
+// RegisterOAIRoutes adds the OAI-PMH endpoint used by harvesters
+func RegisterOAIRoutes(router *gin.Engine, oaiHandler *gin_handler.OAIHandler) {
+	router.GET("/oai", oaiHandler.Handle)
+	router.POST("/oai", oaiHandler.Handle)
+}
+
+// RegisterSRURoutes adds the SRU searchRetrieve endpoint
+func RegisterSRURoutes(router *gin.Engine, sruHandler *gin_handler.SRUHandler) {
+	router.GET("/sru", sruHandler.SearchRetrieve)
 }
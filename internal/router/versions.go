@@ -0,0 +1,38 @@
+package router
+
+import (
+	"github.com/brianantony456/go-doc/internal/infrastructure/gin_handler"
+	"github.com/gin-gonic/gin"
+)
+
+// APIVersion is one version of the JSON API, served under /api/<Name>.
+// Versions run side by side: a new version registers its own handlers for
+// the routes whose shape changed and reuses the Register functions of the
+// rest.
+type APIVersion struct {
+	Name     string
+	Register func(apiRoutes *gin.RouterGroup)
+	// Deprecation is set once clients should move to a later version.
+	Deprecation *gin_handler.Deprecation
+}
+
+// MountAPI adds every version to the /api group. The current version is
+// also served under /api itself for clients that predate versioning; that
+// alias is deprecated in favour of /api/<current>.
+func MountAPI(apiRoutes *gin.RouterGroup, versions []APIVersion, current string, alias gin_handler.Deprecation, usage *gin_handler.DeprecationUsage) {
+	for _, version := range versions {
+		prefix := apiRoutes.BasePath() + "/" + version.Name
+		group := apiRoutes.Group("/" + version.Name)
+		if version.Deprecation != nil {
+			group.Use(gin_handler.Deprecated(prefix, *version.Deprecation, usage))
+		}
+		version.Register(group)
+
+		if version.Name == current {
+			alias.Successor = prefix
+			unversioned := apiRoutes.Group("")
+			unversioned.Use(gin_handler.Deprecated(apiRoutes.BasePath(), alias, usage))
+			version.Register(unversioned)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+// Package web holds the HTML pages served next to the API, embedded so the
+// server runs from any directory.
+package web
+
+import "embed"
+
+// Templates are the catalogue pages, rendered with html/template.
+//
+//go:embed templates/*.html
+var Templates embed.FS
+
+// Explorer is a static page that lists the operations of the OpenAPI
+// document next to it and sends requests to them.
+//
+//go:embed explorer.html
+var Explorer []byte
@@ -0,0 +1,174 @@
+package integrationtests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+	"github.com/brianantony456/go-doc/internal/infrastructure/gin_handler"
+	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+	appRouter "github.com/brianantony456/go-doc/internal/router"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupAuditRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	db := setupInMemoryDB(t)
+	repo := persistence.NewGormBookRepository(db)
+	audit := service.NewAuditService(persistence.NewGormAuditRepository(db))
+	books := service.NewBookService(repo, persistence.NewGormLedgerRepository(db), audit, persistence.NewGormUnitOfWork(db))
+
+	router := gin.New()
+	apiRoutes := router.Group("/api")
+	appRouter.RegisterAPIRoutes(apiRoutes, gin_handler.NewBookHandler(repo, books, service.NewHistoryService(repo)))
+	appRouter.RegisterLoanRoutes(apiRoutes, gin_handler.NewLoanHandler(service.NewLoanService(books, persistence.NewGormLoanRepository(db))))
+	appRouter.RegisterAdminRoutes(router.Group("/api/admin"), []string{testAdminKey}, gin_handler.NewAuditHandler(audit))
+	return router, db
+}
+
+const testAdminKey = "test-admin-key"
+
+// adminRequest is a request to the /api/admin routes with the admin key.
+func adminRequest(method, path string) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+testAdminKey)
+	return req
+}
+
+func auditEntries(t *testing.T, router *gin.Engine, query string) []model.AuditEntry {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest(http.MethodGet, "/api/admin/audit"+query))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		Entries []model.AuditEntry `json:"entries"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return body.Entries
+}
+
+func auditVerification(t *testing.T, router *gin.Engine) service.Verification {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest(http.MethodGet, "/api/admin/audit/verify"))
+	require.Equal(t, http.StatusOK, w.Code)
+	var result service.Verification
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	return result
+}
+
+func TestAuditRecordsMutatingCalls(t *testing.T) {
+	router, _ := setupAuditRouter(t)
+	send := func(method, path, body, actor string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Actor", actor)
+		req.Header.Set("X-Request-ID", "req-"+actor)
+		req.RemoteAddr = "192.0.2.7:5000"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Less(t, w.Code, 300, w.Body.String())
+	}
+	send(http.MethodPost, "/api/books", `{"id": "1", "title": "Dune", "author": "Frank Herbert", "quantity": 2}`, "alice")
+	send(http.MethodPatch, "/api/checkout?id=1", "", "bob")
+	send(http.MethodGet, "/api/books/1", "", "carol")
+
+	entries := auditEntries(t, router, "")
+	require.Len(t, entries, 2, "reads are not audited")
+
+	created := entries[0]
+	assert.Equal(t, service.ActionBookCreate, created.Action)
+	assert.Equal(t, "alice", created.Actor)
+	assert.Equal(t, "req-alice", created.RequestID)
+	assert.Equal(t, "192.0.2.7", created.ClientIP)
+	assert.Equal(t, service.EntityBook, created.EntityType)
+	assert.Equal(t, "1", created.EntityID)
+	assert.Empty(t, created.Before)
+	var after model.Book
+	require.NoError(t, json.Unmarshal([]byte(created.After), &after))
+	assert.Equal(t, "Dune", after.Title)
+
+	checkout := entries[1]
+	assert.Equal(t, service.ActionBookCheckout, checkout.Action)
+	assert.Equal(t, created.Hash, checkout.PrevHash)
+	assert.JSONEq(t, `{"quantity": {"before": 2, "after": 1}}`, string(checkout.Diff))
+
+	filtered := auditEntries(t, router, "?actor=bob&entity_type=book&entity_id=1")
+	require.Len(t, filtered, 1)
+	assert.Equal(t, checkout.ID, filtered[0].ID)
+	assert.Len(t, auditEntries(t, router, "?action=book.create"), 1)
+	assert.Empty(t, auditEntries(t, router, "?from=2999-01-01"))
+	assert.Len(t, auditEntries(t, router, "?after_id=1&limit=1"), 1)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest(http.MethodGet, "/api/admin/audit?limit=0"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAuditVerifyDetectsTampering(t *testing.T) {
+	router, db := setupAuditRouter(t)
+	createOneRow(router)
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/api/checkout?id=1", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	assert.Equal(t, service.Verification{Valid: true, Entries: 4}, auditVerification(t, router))
+
+	require.NoError(t, db.Exec("UPDATE audit_entries SET actor = 'someone-else' WHERE id = 3").Error)
+	assert.Equal(t, service.Verification{Valid: false, Entries: 2, BrokenAt: 3}, auditVerification(t, router))
+}
+
+func TestAuditRollsBackWithTheChange(t *testing.T) {
+	repo := persistence.NewMemoryBookRepository()
+	ledger := persistence.NewMemoryLedgerRepository()
+	auditLog := persistence.NewMemoryAuditRepository()
+	books := service.NewBookService(repo, ledger, service.NewAuditService(auditLog), persistence.NewMemoryUnitOfWork(repo, ledger, auditLog))
+	ctx := context.Background()
+
+	require.NoError(t, books.Create(ctx, model.Book{ID: "1", Quantity: 0}))
+	_, err := books.Checkout(ctx, "1")
+	assert.ErrorIs(t, err, service.ErrBookNotAvailable)
+
+	entries, err := auditLog.List(ctx, repository.AuditFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "failed changes leave no audit entries")
+	assert.Equal(t, service.ActionBookCreate, entries[0].Action)
+}
+
+func TestAdminRoutesNeedTheAdminKey(t *testing.T) {
+	router, _ := setupAuditRouter(t)
+	for name, authorization := range map[string]string{
+		"no key":     "",
+		"wrong key":  "Bearer guess",
+		"not bearer": "Basic " + testAdminKey,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/audit", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, name)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"), name)
+		assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"), name)
+	}
+
+	// Without admin keys configured the admin routes are closed
+	closed, _, err := appRouter.SetupRouter(appRouter.Config{Database: filepath.Join(t.TempDir(), "books.db")})
+	require.NoError(t, err)
+	for _, path := range []string{"/api/v1/admin/audit", "/api/v1/admin/audit/verify", "/api/v1/admin/cache", "/api/v1/admin/deprecations"} {
+		w := httptest.NewRecorder()
+		closed.ServeHTTP(w, adminRequest(http.MethodGet, path))
+		assert.Equal(t, http.StatusUnauthorized, w.Code, path)
+	}
+}
@@ -8,29 +8,39 @@ import (
 	"testing"
 
 	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/service"
 	"github.com/brianantony456/go-doc/internal/infrastructure/gin_handler"
+	"github.com/brianantony456/go-doc/internal/infrastructure/oaipmh"
 	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+	"github.com/brianantony456/go-doc/internal/infrastructure/sru"
+	appRouter "github.com/brianantony456/go-doc/internal/router"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 func setupRouter() *gin.Engine {
-	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db, _ := gorm.Open(persistence.OpenSQLite(":memory:"), &gorm.Config{})
+	if err := persistence.MigrateUp(db); err != nil {
+		panic(err)
+	}
 	bookRepo := persistence.NewGormBookRepository(db)
-	bookHandler := gin_handler.NewBookHandler(bookRepo)
+	audit := service.NewAuditService(persistence.NewGormAuditRepository(db))
+	bookService := service.NewBookService(bookRepo, persistence.NewGormLedgerRepository(db), audit, persistence.NewGormUnitOfWork(db))
+	bookHandler := gin_handler.NewBookHandler(bookRepo, bookService, service.NewHistoryService(bookRepo))
 
 	router := gin.Default()
 	apiRoutes := router.Group("/api")
-	{
-		apiRoutes.GET("/books", bookHandler.GetBooks)
-		apiRoutes.POST("/books", bookHandler.CreateBook)
-		apiRoutes.GET("/books/:id", bookHandler.BookById)
-		apiRoutes.PATCH("/checkout", bookHandler.CheckoutBook)
-		apiRoutes.PATCH("/return", bookHandler.ReturnBook)
-	}
+	appRouter.RegisterAPIRoutes(apiRoutes, bookHandler)
+	appRouter.RegisterLoanRoutes(apiRoutes, gin_handler.NewLoanHandler(service.NewLoanService(bookService, persistence.NewGormLoanRepository(db))))
+	appRouter.RegisterAdminRoutes(router.Group("/api/admin"), []string{testAdminKey}, gin_handler.NewAuditHandler(audit))
+	appRouter.RegisterOPDSRoutes(router.Group("/opds"), gin_handler.NewOPDSHandler(bookRepo, "/opds", "/api"))
+	appRouter.RegisterOAIRoutes(router, gin_handler.NewOAIHandler(oaipmh.NewProvider(bookRepo, bookRepo, oaipmh.Config{
+		RepositoryName:       "Test catalogue",
+		RepositoryIdentifier: "go-doc",
+	})))
+	appRouter.RegisterSRURoutes(router, gin_handler.NewSRUHandler(sru.NewServer(bookRepo)))
 
 	return router
 }
@@ -0,0 +1,46 @@
+package integrationtests
+
+import (
+	"testing"
+
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/domain/repository/repositorytest"
+	"github.com/brianantony456/go-doc/internal/infrastructure/cache"
+	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+)
+
+func TestGormBookRepositoryConformance(t *testing.T) {
+	repositorytest.TestBookRepository(t, func(t *testing.T) repository.BookRepository {
+		return persistence.NewGormBookRepository(setupInMemoryDB(t))
+	})
+}
+
+func TestMemoryBookRepositoryConformance(t *testing.T) {
+	repositorytest.TestBookRepository(t, func(t *testing.T) repository.BookRepository {
+		return persistence.NewMemoryBookRepository()
+	})
+}
+
+func TestGormUnitOfWorkConformance(t *testing.T) {
+	repositorytest.TestUnitOfWork(t, func(t *testing.T) (repository.UnitOfWork, repository.BookRepository) {
+		db := setupInMemoryDB(t)
+		return persistence.NewGormUnitOfWork(db), persistence.NewGormBookRepository(db)
+	})
+}
+
+func TestMemoryUnitOfWorkConformance(t *testing.T) {
+	repositorytest.TestUnitOfWork(t, func(t *testing.T) (repository.UnitOfWork, repository.BookRepository) {
+		repo := persistence.NewMemoryBookRepository()
+		return persistence.NewMemoryUnitOfWork(repo), repo
+	})
+}
+
+func TestCachedBookRepositoryConformance(t *testing.T) {
+	repositorytest.TestBookRepository(t, func(t *testing.T) repository.BookRepository {
+		return cache.NewBookRepository(persistence.NewGormBookRepository(setupInMemoryDB(t)), cache.Config{})
+	})
+	repositorytest.TestUnitOfWork(t, func(t *testing.T) (repository.UnitOfWork, repository.BookRepository) {
+		repo := persistence.NewMemoryBookRepository()
+		return persistence.NewMemoryUnitOfWork(repo), cache.NewBookRepository(repo, cache.Config{})
+	})
+}
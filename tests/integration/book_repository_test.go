@@ -1,21 +1,49 @@
 package integrationtests
 
 import (
+	"context"
+	"path/filepath"
 	"testing"
 
 	"github.com/brianantony456/go-doc/internal/domain/model"
 	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
 
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 func setupInMemoryDB(t *testing.T) *gorm.DB {
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db, err := gorm.Open(persistence.OpenSQLite(":memory:"), &gorm.Config{})
 	if err != nil {
 		t.Fatalf("Failed to connect to the in-memory database: %v", err)
 	}
+	// Every connection to ":memory:" opens a separate, empty database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to get the in-memory database handle: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := persistence.MigrateUp(db); err != nil {
+		t.Fatalf("Failed to migrate the in-memory database: %v", err)
+	}
+	return db
+}
+
+// setupFileDB opens a database file with a pool of connections, as the API
+// does, so concurrent requests really run side by side.
+func setupFileDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(persistence.OpenSQLite(filepath.Join(t.TempDir(), "books.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open the database file: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to get the database handle: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := persistence.MigrateUp(db); err != nil {
+		t.Fatalf("Failed to migrate the database file: %v", err)
+	}
 	return db
 }
 
@@ -30,10 +58,10 @@ func TestRepositoryCreateBook(t *testing.T) {
 		Quantity: 3,
 	}
 
-	err := repo.Create(newBook)
+	err := repo.Create(context.Background(), newBook)
 	assert.NoError(t, err)
 
-	createdBook, err := repo.GetByID("1")
+	createdBook, err := repo.GetByID(context.Background(), "1")
 	assert.NoError(t, err)
 	assert.Equal(t, newBook.Title, createdBook.Title)
 	assert.Equal(t, newBook.Author, createdBook.Author)
@@ -50,10 +78,10 @@ func TestRepositoryGetAllBooks(t *testing.T) {
 	}
 
 	for _, book := range books {
-		_ = repo.Create(book)
+		_ = repo.Create(context.Background(), book)
 	}
 
-	allBooks, err := repo.GetAll()
+	allBooks, err := repo.GetAll(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, allBooks, 2)
 }
@@ -63,9 +91,9 @@ func TestRepositoryGetBookByID(t *testing.T) {
 	repo := persistence.NewGormBookRepository(db)
 
 	book := model.Book{ID: "1", Title: "A New Book", Author: "John Doe", Quantity: 3}
-	_ = repo.Create(book)
+	_ = repo.Create(context.Background(), book)
 
-	foundBook, err := repo.GetByID("1")
+	foundBook, err := repo.GetByID(context.Background(), "1")
 	assert.NoError(t, err)
 	assert.Equal(t, book.Title, foundBook.Title)
 	assert.Equal(t, book.Author, foundBook.Author)
@@ -77,13 +105,13 @@ func TestRepositoryUpdateBook(t *testing.T) {
 	repo := persistence.NewGormBookRepository(db)
 
 	book := model.Book{ID: "1", Title: "A New Book", Author: "John Doe", Quantity: 3}
-	_ = repo.Create(book)
+	_ = repo.Create(context.Background(), book)
 
 	book.Quantity = 5
-	err := repo.Update(book)
+	err := repo.Update(context.Background(), book)
 	assert.NoError(t, err)
 
-	updatedBook, err := repo.GetByID("1")
+	updatedBook, err := repo.GetByID(context.Background(), "1")
 	assert.NoError(t, err)
 	assert.Equal(t, 5, updatedBook.Quantity)
 }
@@ -92,7 +120,7 @@ func TestRepositoryGetNonExistentBookByID(t *testing.T) {
 	db := setupInMemoryDB(t)
 	repo := persistence.NewGormBookRepository(db)
 
-	_, err := repo.GetByID("non-existent")
+	_, err := repo.GetByID(context.Background(), "non-existent")
 	assert.Error(t, err)
 	assert.Equal(t, "book not found", err.Error())
 }
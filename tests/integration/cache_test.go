@@ -0,0 +1,200 @@
+package integrationtests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/infrastructure/cache"
+	"github.com/brianantony456/go-doc/internal/infrastructure/gin_handler"
+	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+	appRouter "github.com/brianantony456/go-doc/internal/router"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingRepository counts the reads that reach the wrapped repository and
+// can hold them until release is closed.
+type countingRepository struct {
+	repository.BookRepository
+	reads   atomic.Int32
+	release chan struct{}
+}
+
+func (r *countingRepository) GetByID(ctx context.Context, id string) (*model.Book, error) {
+	r.reads.Add(1)
+	if r.release != nil {
+		<-r.release
+	}
+	return r.BookRepository.GetByID(ctx, id)
+}
+
+func (r *countingRepository) GetAll(ctx context.Context) ([]model.Book, error) {
+	r.reads.Add(1)
+	return r.BookRepository.GetAll(ctx)
+}
+
+func newCountingRepository(t *testing.T, ids ...string) *countingRepository {
+	repo := persistence.NewMemoryBookRepository()
+	for _, id := range ids {
+		require.NoError(t, repo.Create(context.Background(), model.Book{ID: id, Title: "Book " + id, Quantity: 1}))
+	}
+	return &countingRepository{BookRepository: repo}
+}
+
+func TestCacheHitsAndInvalidation(t *testing.T) {
+	ctx := context.Background()
+	backend := newCountingRepository(t, "1", "2")
+	repo := cache.NewBookRepository(backend, cache.Config{TTL: time.Minute})
+
+	for i := 0; i < 3; i++ {
+		_, err := repo.GetByID(ctx, "1")
+		require.NoError(t, err)
+		_, err = repo.GetAll(ctx)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), backend.reads.Load())
+	assert.Equal(t, cache.Stats{Hits: 4, Misses: 2, Entries: 3}, repo.Stats(), "the list counts the books it holds")
+
+	book, _ := repo.GetByID(ctx, "1")
+	book.Quantity = 0
+	require.NoError(t, repo.Update(ctx, *book))
+
+	book, _ = repo.GetByID(ctx, "1")
+	assert.Equal(t, 0, book.Quantity, "writes invalidate the cached book")
+	books, _ := repo.GetAll(ctx)
+	assert.Equal(t, 0, books[0].Quantity, "writes invalidate the cached list")
+
+	require.NoError(t, repo.Create(ctx, model.Book{ID: "3"}))
+	books, _ = repo.GetAll(ctx)
+	assert.Len(t, books, 3)
+
+	// Reads inside a unit of work skip the cache
+	reads := backend.reads.Load()
+	_, err := repo.GetByID(repository.WithinUnit(ctx), "1")
+	require.NoError(t, err)
+	assert.Equal(t, reads+1, backend.reads.Load())
+}
+
+func TestCacheTTLAndSizeBounds(t *testing.T) {
+	ctx := context.Background()
+	backend := newCountingRepository(t, "1", "2", "3")
+	repo := cache.NewBookRepository(backend, cache.Config{TTL: 20 * time.Millisecond, MaxEntries: 2})
+
+	for _, id := range []string{"1", "2", "3"} {
+		_, err := repo.GetByID(ctx, id)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, repo.Stats().Entries)
+
+	// "1" was the least recently used, so it was evicted
+	_, _ = repo.GetByID(ctx, "1")
+	assert.Equal(t, int32(4), backend.reads.Load())
+	_, _ = repo.GetByID(ctx, "3")
+	assert.Equal(t, int32(4), backend.reads.Load())
+
+	time.Sleep(30 * time.Millisecond)
+	_, _ = repo.GetByID(ctx, "3")
+	assert.Equal(t, int32(5), backend.reads.Load(), "expired entries are reloaded")
+}
+
+func TestCacheCountsListedBooksAgainstTheSizeBound(t *testing.T) {
+	ctx := context.Background()
+	backend := newCountingRepository(t, "1", "2", "3")
+	repo := cache.NewBookRepository(backend, cache.Config{TTL: time.Minute, MaxEntries: 3})
+
+	_, err := repo.GetByID(ctx, "1")
+	require.NoError(t, err)
+	_, err = repo.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, repo.Stats().Entries, "the list pushed out the book cached before it")
+	_, _ = repo.GetAll(ctx)
+	assert.Equal(t, int32(2), backend.reads.Load())
+	_, _ = repo.GetByID(ctx, "1")
+	assert.Equal(t, int32(3), backend.reads.Load())
+	assert.Equal(t, 1, repo.Stats().Entries, "and the book then pushed out the list")
+
+	require.NoError(t, repo.Create(ctx, model.Book{ID: "4"}))
+	for i := 0; i < 2; i++ {
+		books, err := repo.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Len(t, books, 4)
+	}
+	assert.Equal(t, int32(5), backend.reads.Load(), "a list larger than the cache isn't cached")
+	assert.LessOrEqual(t, repo.Stats().Entries, 3)
+}
+
+func TestCacheCollapsesConcurrentMisses(t *testing.T) {
+	backend := newCountingRepository(t, "1")
+	backend.release = make(chan struct{})
+	repo := cache.NewBookRepository(backend, cache.Config{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			book, err := repo.GetByID(context.Background(), "1")
+			assert.NoError(t, err)
+			assert.Equal(t, "Book 1", book.Title)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(backend.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), backend.reads.Load())
+	assert.Equal(t, uint64(10), repo.Stats().Misses)
+}
+
+func TestCacheInvalidatesAfterCommit(t *testing.T) {
+	db := setupFileDB(t)
+	ctx := context.Background()
+	repo := cache.NewBookRepository(persistence.NewGormBookRepository(db), cache.Config{TTL: time.Minute})
+	require.NoError(t, repo.Create(ctx, model.Book{ID: "1", Title: "Dune", Quantity: 1}))
+
+	err := persistence.NewGormUnitOfWork(db).Do(ctx, func(ctx context.Context) error {
+		if err := repo.Update(ctx, model.Book{ID: "1", Title: "Dune", Quantity: 0}); err != nil {
+			return err
+		}
+		// A read from outside the unit still sees the committed book
+		book, err := repo.GetByID(context.Background(), "1")
+		require.NoError(t, err)
+		assert.Equal(t, 1, book.Quantity)
+		return nil
+	})
+	require.NoError(t, err)
+
+	book, err := repo.GetByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, book.Quantity, "the book read before the commit is not served after it")
+}
+
+func TestCacheStatsEndpoint(t *testing.T) {
+	router, _, err := appRouter.SetupRouter(appRouter.Config{Database: filepath.Join(t.TempDir(), "books.db"), CacheTTL: time.Minute, AdminKeys: []string{testAdminKey}})
+	require.NoError(t, err)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, adminRequest(http.MethodGet, path))
+		return w
+	}
+	get("/api/v1/books")
+	get("/api/v1/books")
+
+	w := get("/api/v1/admin/cache")
+	require.Equal(t, http.StatusOK, w.Code)
+	var report gin_handler.CacheReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.True(t, report.Enabled)
+	assert.Equal(t, uint64(1), report.Hits)
+	assert.Equal(t, uint64(1), report.Misses)
+}
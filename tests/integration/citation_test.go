@@ -0,0 +1,99 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/infrastructure/citation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createBook(t *testing.T, router *gin.Engine, book model.Book) {
+	t.Helper()
+	jsonValue, _ := json.Marshal(book)
+	req, _ := http.NewRequest("POST", "/api/books", strings.NewReader(string(jsonValue)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestCitationKeyIsStable(t *testing.T) {
+	book := model.Book{ID: "1", Title: "The Go Programming Language", Author: "Donovan, Alan", Year: "2015"}
+	assert.Equal(t, "donovan2015go_356a19", citation.Key(book))
+
+	// The key doesn't depend on which other books are cited with it
+	twin := model.Book{ID: "2", Title: "The Go Programming Language", Author: "Alan Donovan", Year: "2015"}
+	keys := citation.Keys([]model.Book{book, twin})
+	assert.Equal(t, []string{"donovan2015go_356a19", "donovan2015go_da4b92"}, keys)
+	assert.Equal(t, citation.Key(book), citation.Keys([]model.Book{book})[0])
+}
+
+func TestBibTeXEscaping(t *testing.T) {
+	out, err := citation.Render(citation.BibTeX, []model.Book{
+		{ID: "1", Title: "100% R&D_{notes}", Author: "Brontë, Émily", Year: "1847"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "@book{bronte1847100_356a19,\n"+
+		"  author = {Brontë, Émily},\n"+
+		`  title = {100\% R\&D\_\{notes\}},`+"\n"+
+		"  year = {1847},\n"+
+		"}\n", string(out))
+}
+
+func TestBookCitationFormats(t *testing.T) {
+	router := setupRouter()
+	createBook(t, router, model.Book{ID: "1", Title: "Pride and Prejudice", Author: "Jane Austen", Publisher: "Penguin", Year: "2003", ISBN: "9780141439518"})
+
+	req, _ := http.NewRequest("GET", "/api/books/1/citation?format=ris", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "TY  - BOOK\r\nID  - austen2003pride_356a19\r\nAU  - Jane Austen\r\nTI  - Pride and Prejudice\r\n"+
+		"PB  - Penguin\r\nPY  - 2003\r\nSN  - 9780141439518\r\nER  - \r\n", w.Body.String())
+
+	req, _ = http.NewRequest("GET", "/api/books/1/citation?format=csl-json", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var items []map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+	require.Len(t, items, 1)
+	assert.Equal(t, "austen2003pride_356a19", items[0]["id"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"family": "Austen", "given": "Jane"}}, items[0]["author"])
+
+	req, _ = http.NewRequest("GET", "/api/books/1/citation?format=mla", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/books/missing/citation", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestBulkCitations(t *testing.T) {
+	router := setupRouter()
+	createBook(t, router, model.Book{ID: "1", Title: "Emma", Author: "Austen, Jane", Year: "1815"})
+	createBook(t, router, model.Book{ID: "2", Title: "Persuasion", Author: "Austen, Jane", Year: "1817"})
+
+	req, _ := http.NewRequest("GET", "/api/citations?ids=1,2&format=bibtex", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "@book{austen1815emma_356a19,")
+	assert.Contains(t, w.Body.String(), "@book{austen1817persuasion_da4b92,")
+
+	req, _ = http.NewRequest("GET", "/api/citations?ids=1&ids=3", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
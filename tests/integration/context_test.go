@@ -0,0 +1,55 @@
+package integrationtests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/service"
+	"github.com/brianantony456/go-doc/internal/infrastructure/gin_handler"
+	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+	appRouter "github.com/brianantony456/go-doc/internal/router"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryTimeoutReturnsGatewayTimeout(t *testing.T) {
+	db := setupInMemoryDB(t)
+	repo := persistence.NewGormBookRepository(db).WithQueryTimeout(time.Nanosecond)
+	router := gin.New()
+	appRouter.RegisterAPIRoutes(router.Group("/api"), gin_handler.NewBookHandler(repo, service.NewBookService(repo, persistence.NewGormLedgerRepository(db), service.NewAuditService(persistence.NewGormAuditRepository(db)), persistence.NewGormUnitOfWork(db)), service.NewHistoryService(repo)))
+
+	for _, path := range []string{"/api/books", "/api/books/1"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusGatewayTimeout, w.Code, path)
+		assert.Contains(t, w.Body.String(), "Request timed out")
+	}
+
+	_, err := repo.GetAll(context.Background())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestCancelledRequestReturnsServiceUnavailable(t *testing.T) {
+	router := setupRouter()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/checkout?id=1", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/opds/books", nil).WithContext(ctx)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var problem gin_handler.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, "Request cancelled", problem.Title)
+}
@@ -0,0 +1,124 @@
+package integrationtests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// historyRepository is a book repository that also keeps its history.
+type historyRepository interface {
+	repository.BookRepository
+	repository.BookHistoryRepository
+}
+
+func TestBookHistory(t *testing.T) {
+	for name, newRepo := range map[string]func(t *testing.T) historyRepository{
+		"gorm":   func(t *testing.T) historyRepository { return persistence.NewGormBookRepository(setupInMemoryDB(t)) },
+		"memory": func(t *testing.T) historyRepository { return persistence.NewMemoryBookRepository() },
+	} {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo(t)
+			history := service.NewHistoryService(repo)
+			ctx := context.Background()
+			tick := func() time.Time {
+				time.Sleep(5 * time.Millisecond)
+				defer time.Sleep(5 * time.Millisecond)
+				return time.Now()
+			}
+
+			beforeAll := tick()
+			require.NoError(t, repo.Create(ctx, model.Book{ID: "1", Title: "Dune", Quantity: 3}))
+			require.NoError(t, repo.Create(ctx, model.Book{ID: "2", Title: "Emma", Quantity: 1}))
+			created := tick()
+			book, _ := repo.GetByID(ctx, "1")
+			book.Quantity = 2
+			require.NoError(t, repo.Update(ctx, *book))
+			require.NoError(t, repo.Create(ctx, model.Book{ID: "3", Title: "Ulysses", Quantity: 1}))
+			now := tick()
+
+			books, err := history.BooksAsOf(ctx, beforeAll)
+			require.NoError(t, err)
+			assert.Empty(t, books)
+
+			books, err = history.BooksAsOf(ctx, created)
+			require.NoError(t, err)
+			require.Len(t, books, 2)
+			assert.Equal(t, "1", books[0].ID)
+			assert.Equal(t, 3, books[0].Quantity)
+
+			old, err := history.BookAsOf(ctx, "1", created)
+			require.NoError(t, err)
+			assert.Equal(t, 3, old.Quantity)
+			current, err := history.BookAsOf(ctx, "1", now)
+			require.NoError(t, err)
+			assert.Equal(t, 2, current.Quantity)
+			_, err = history.BookAsOf(ctx, "3", created)
+			assert.ErrorIs(t, err, repository.ErrBookNotFound)
+
+			changes, err := history.Diff(ctx, created, now)
+			require.NoError(t, err)
+			require.Len(t, changes, 2)
+			assert.Equal(t, service.Change{ID: "1", Change: service.ChangeChanged, Fields: []string{"quantity"},
+				Before: old, After: current}, changes[0])
+			assert.Equal(t, "3", changes[1].ID)
+			assert.Equal(t, service.ChangeAdded, changes[1].Change)
+			assert.Nil(t, changes[1].Before)
+
+			// Deletions list books deleted and not created again
+			require.NoError(t, repo.Delete(ctx, "2"))
+			require.NoError(t, repo.Delete(ctx, "3"))
+			require.NoError(t, repo.Create(ctx, model.Book{ID: "3", Title: "Ulysses", Quantity: 1}))
+			deletions, err := repo.Deletions(ctx)
+			require.NoError(t, err)
+			require.Len(t, deletions, 1)
+			assert.Equal(t, "2", deletions[0].BookID)
+			assert.True(t, deletions[0].DeletedAt.After(now))
+		})
+	}
+}
+
+func TestBooksAsOfEndpoints(t *testing.T) {
+	router := setupRouter()
+	before := time.Now().UTC().Format(time.RFC3339Nano)
+	time.Sleep(5 * time.Millisecond)
+	createOneRow(router)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/api/books?as_of=" + url.QueryEscape(before))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, "[]", w.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, get("/api/books/1?as_of="+url.QueryEscape(before)).Code)
+	assert.Equal(t, http.StatusOK, get("/api/books/1?as_of=2999-01-01").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/books?as_of=yesterday").Code)
+
+	w = get("/api/books/diff?from=" + url.QueryEscape(before))
+	require.Equal(t, http.StatusOK, w.Code)
+	var diff struct {
+		Changes []service.Change `json:"changes"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &diff))
+	require.Len(t, diff.Changes, 1)
+	assert.Equal(t, service.ChangeAdded, diff.Changes[0].Change)
+	assert.Equal(t, "New Book", diff.Changes[0].After.Title)
+
+	assert.Equal(t, http.StatusBadRequest, get("/api/books/diff").Code)
+}
@@ -0,0 +1,206 @@
+package integrationtests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+	"github.com/brianantony456/go-doc/internal/infrastructure/gin_handler"
+	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+	appRouter "github.com/brianantony456/go-doc/internal/router"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupIdempotentRouter(t *testing.T, window time.Duration) (*gin.Engine, *persistence.GormBookRepository) {
+	db := setupInMemoryDB(t)
+	repo := persistence.NewGormBookRepository(db)
+	books := service.NewBookService(repo, persistence.NewGormLedgerRepository(db),
+		service.NewAuditService(persistence.NewGormAuditRepository(db)), persistence.NewGormUnitOfWork(db))
+
+	router := gin.New()
+	apiRoutes := router.Group("/api/v1")
+	apiRoutes.Use(gin_handler.Idempotency(service.NewIdempotencyService(persistence.NewGormIdempotencyRepository(db), window), "/api/v1"))
+	appRouter.RegisterAPIRoutes(apiRoutes, gin_handler.NewBookHandler(repo, books, service.NewHistoryService(repo)))
+	appRouter.RegisterLoanRoutes(apiRoutes, gin_handler.NewLoanHandler(service.NewLoanService(books, persistence.NewGormLoanRepository(db))))
+
+	require.NoError(t, books.Create(context.Background(), model.Book{ID: "1", Title: "Dune", Author: "Frank Herbert", Quantity: 3}))
+	return router, repo
+}
+
+func sendWithKey(router *gin.Engine, method, target, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotentCreateIsReplayed(t *testing.T) {
+	router, repo := setupIdempotentRouter(t, time.Hour)
+	body := `{"title": "Emma", "author": "Jane Austen", "quantity": 1}`
+
+	first := sendWithKey(router, http.MethodPost, "/api/v1/books", "create-emma", body)
+	require.Equal(t, http.StatusCreated, first.Code, first.Body.String())
+	retry := sendWithKey(router, http.MethodPost, "/api/v1/books", "create-emma", body)
+	require.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+
+	books, err := repo.GetAll(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, books, 2)
+
+	// Without a key every request runs
+	sendWithKey(router, http.MethodPost, "/api/v1/books", "", body)
+	books, err = repo.GetAll(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, books, 3)
+}
+
+func TestIdempotentCheckoutDecrementsOnce(t *testing.T) {
+	router, repo := setupIdempotentRouter(t, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		w := sendWithKey(router, http.MethodPatch, "/api/v1/checkout?id=1", "checkout-1", "")
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+	first := sendWithKey(router, http.MethodPost, "/api/v1/books/1/loans", "loan-1", "")
+	retry := sendWithKey(router, http.MethodPost, "/api/v1/books/1/loans", "loan-1", "")
+	require.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Header().Get("Location"), retry.Header().Get("Location"))
+
+	book, err := repo.GetByID(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, book.Quantity)
+}
+
+func TestIdempotencyKeyReusedForAnotherRequest(t *testing.T) {
+	router, _ := setupIdempotentRouter(t, time.Hour)
+
+	w := sendWithKey(router, http.MethodPost, "/api/v1/books", "key", `{"title": "Emma", "author": "Jane Austen"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	for _, req := range []struct{ method, target, body string }{
+		{http.MethodPost, "/api/v1/books", `{"title": "Persuasion", "author": "Jane Austen"}`},
+		{http.MethodPatch, "/api/v1/checkout?id=1", ""},
+	} {
+		w = sendWithKey(router, req.method, req.target, "key", req.body)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code, req.target)
+		var problem gin_handler.Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Contains(t, problem.Type, "#idempotency-key-reused")
+	}
+}
+
+func TestIdempotencyKeyExpires(t *testing.T) {
+	router, repo := setupIdempotentRouter(t, time.Nanosecond)
+
+	for i := 0; i < 2; i++ {
+		w := sendWithKey(router, http.MethodPatch, "/api/v1/checkout?id=1", "checkout-1", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+	}
+	book, err := repo.GetByID(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, book.Quantity)
+}
+
+func TestIdempotencyKeyFreedWhenRequestPanics(t *testing.T) {
+	db := setupInMemoryDB(t)
+	router := gin.New()
+	router.Use(gin.RecoveryWithWriter(io.Discard))
+	apiRoutes := router.Group("/api/v1")
+	apiRoutes.Use(gin_handler.Idempotency(service.NewIdempotencyService(persistence.NewGormIdempotencyRepository(db), time.Hour), "/api/v1"))
+	panics := true
+	apiRoutes.POST("/boom", func(c *gin.Context) {
+		if panics {
+			panic("boom")
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, sendWithKey(router, http.MethodPost, "/api/v1/boom", "boom", "").Code)
+	panics = false
+	assert.Equal(t, http.StatusNoContent, sendWithKey(router, http.MethodPost, "/api/v1/boom", "boom", "").Code, "the retry runs")
+}
+
+func TestIdempotencyClaimExpiresAfterLease(t *testing.T) {
+	db := setupInMemoryDB(t)
+	ctx := context.Background()
+	keys := service.NewIdempotencyService(persistence.NewGormIdempotencyRepository(db), time.Hour).WithLease(20 * time.Millisecond)
+
+	// The first request claims the key and dies without settling it
+	stored, err := keys.Begin(ctx, "key", "request")
+	require.NoError(t, err)
+	require.Nil(t, stored)
+	_, err = keys.Begin(ctx, "key", "request")
+	assert.ErrorIs(t, err, service.ErrIdempotencyKeyInFlight)
+
+	time.Sleep(30 * time.Millisecond)
+	stored, err = keys.Begin(ctx, "key", "request")
+	require.NoError(t, err)
+	assert.Nil(t, stored, "the retry claims the key again")
+
+	// A stored response is kept for the window, well past the lease
+	require.NoError(t, keys.Finish(ctx, model.IdempotencyKey{Key: "key", Status: http.StatusCreated}))
+	time.Sleep(30 * time.Millisecond)
+	stored, err = keys.Begin(ctx, "key", "request")
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, http.StatusCreated, stored.Status)
+}
+
+func TestIdempotentRetryThroughTheOtherMount(t *testing.T) {
+	router, _, err := appRouter.SetupRouter(appRouter.Config{Database: filepath.Join(t.TempDir(), "books.db"), IdempotencyWindow: time.Hour})
+	require.NoError(t, err)
+	body := `{"id": "1", "title": "Dune", "author": "Frank Herbert", "quantity": 1}`
+
+	require.Equal(t, http.StatusCreated, sendWithKey(router, http.MethodPost, "/api/books", "create-1", body).Code)
+	w := sendWithKey(router, http.MethodPost, "/api/v1/books", "create-1", body)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"), "the unversioned alias is the same API")
+
+	require.Equal(t, http.StatusOK, sendWithKey(router, http.MethodPatch, "/api/v1/checkout?id=1", "checkout", "").Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, sendWithKey(router, http.MethodPatch, "/api/checkout?id=2", "checkout", "").Code,
+		"the query is part of the request")
+}
+
+func TestIdempotencyKeysBelongToTheAPIKey(t *testing.T) {
+	router, _, err := appRouter.SetupRouter(appRouter.Config{
+		Database:          filepath.Join(t.TempDir(), "books.db"),
+		IdempotencyWindow: time.Hour,
+		Tenants:           []gin_handler.Tenant{{ID: "north", Name: "North Library", APIKeys: []string{"north-key", "north-desk-key"}}},
+	})
+	require.NoError(t, err)
+	send := func(apiKey, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/books", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "create")
+		req.Header.Set("X-API-Key", apiKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	require.Equal(t, http.StatusCreated, send("north-key", `{"id": "1", "title": "Dune", "author": "Frank Herbert", "quantity": 1}`).Code)
+	w := send("north-desk-key", `{"id": "2", "title": "Emma", "author": "Jane Austen", "quantity": 1}`)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Empty(t, w.Header().Get("Idempotent-Replayed"), "another API key's request isn't a retry")
+	w = send("north-desk-key", `{"id": "2", "title": "Emma", "author": "Jane Austen", "quantity": 1}`)
+	assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+}
@@ -0,0 +1,152 @@
+package integrationtests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLedgerRecordsQuantityChanges(t *testing.T) {
+	router := setupRouter()
+	createOneRow(router) // 10 copies
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Actor", "desk-1")
+		req.Header.Set("X-Request-ID", "req-"+method)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	assert.Equal(t, http.StatusOK, send(http.MethodPatch, "/api/checkout?id=1", "").Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/api/books/1/adjustments", `{"delta": -4}`).Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/api/books/1/adjustments", `{"delta": -6}`).Code)
+
+	w := send(http.MethodGet, "/api/books/1/ledger", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "req-GET", w.Header().Get("X-Request-ID"))
+	var entries []model.LedgerEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, model.ReasonCreate, entries[0].Reason)
+	assert.Equal(t, 10, entries[0].Delta)
+	assert.Equal(t, "api", entries[0].Actor)
+	assert.NotEmpty(t, entries[0].RequestID)
+
+	assert.Equal(t, model.ReasonCheckout, entries[1].Reason)
+	assert.Equal(t, -1, entries[1].Delta)
+	assert.Equal(t, "desk-1", entries[1].Actor)
+	assert.Equal(t, "req-PATCH", entries[1].RequestID)
+
+	assert.Equal(t, model.ReasonAdjustment, entries[2].Reason)
+	assert.Equal(t, -4, entries[2].Delta)
+
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/api/books/missing/ledger", "").Code)
+}
+
+func TestLedgerRollsBackWithTheBook(t *testing.T) {
+	repo := persistence.NewMemoryBookRepository()
+	ledger := persistence.NewMemoryLedgerRepository()
+	auditLog := persistence.NewMemoryAuditRepository()
+	books := service.NewBookService(repo, ledger, service.NewAuditService(auditLog), persistence.NewMemoryUnitOfWork(repo, ledger, auditLog))
+	ctx := context.Background()
+
+	require.NoError(t, books.Create(ctx, model.Book{ID: "1", Quantity: 1}))
+	_, err := books.Checkout(ctx, "1")
+	require.NoError(t, err)
+	_, err = books.Checkout(ctx, "1")
+	assert.ErrorIs(t, err, service.ErrBookNotAvailable)
+	assert.ErrorIs(t, books.Create(ctx, model.Book{ID: "1", Quantity: 5}), repository.ErrBookExists)
+
+	entries, err := books.Ledger(ctx, "1")
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "failed changes leave no ledger entries")
+}
+
+func TestConcurrentCheckoutsOnFileDatabase(t *testing.T) {
+	db := setupFileDB(t)
+	repo := persistence.NewGormBookRepository(db)
+	books := service.NewBookService(repo, persistence.NewGormLedgerRepository(db),
+		service.NewAuditService(persistence.NewGormAuditRepository(db)), persistence.NewGormUnitOfWork(db))
+	ctx := context.Background()
+	require.NoError(t, books.Create(ctx, model.Book{ID: "1", Title: "Dune", Author: "Frank Herbert", Quantity: 30}))
+
+	const checkouts = 20
+	errs := make(chan error, checkouts)
+	var wg sync.WaitGroup
+	for i := 0; i < checkouts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := books.Checkout(ctx, "1")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	book, err := repo.GetByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, 30-checkouts, book.Quantity)
+	entries, err := books.Ledger(ctx, "1")
+	require.NoError(t, err)
+	assert.Len(t, entries, 1+checkouts)
+}
+
+func TestReconcileFindsAndFixesDrift(t *testing.T) {
+	db := setupInMemoryDB(t)
+	repo := persistence.NewGormBookRepository(db)
+	books := service.NewBookService(repo, persistence.NewGormLedgerRepository(db), service.NewAuditService(persistence.NewGormAuditRepository(db)), persistence.NewGormUnitOfWork(db))
+	ctx := context.Background()
+
+	require.NoError(t, books.Create(ctx, model.Book{ID: "1", Quantity: 3}))
+	require.NoError(t, books.Create(ctx, model.Book{ID: "2", Quantity: 2}))
+	_, err := books.Return(ctx, "2")
+	require.NoError(t, err)
+
+	drifts, err := books.Reconcile(ctx, false)
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+
+	// A write that bypasses the service and so the ledger
+	book, _ := repo.GetByID(ctx, "1")
+	book.Quantity = 7
+	require.NoError(t, repo.Update(ctx, *book))
+
+	drifts, err = books.Reconcile(ctx, true)
+	require.NoError(t, err)
+	assert.Equal(t, []service.Drift{{BookID: "1", Quantity: 7, LedgerBalance: 3}}, drifts)
+
+	book, _ = repo.GetByID(ctx, "1")
+	assert.Equal(t, 3, book.Quantity)
+	drifts, _ = books.Reconcile(ctx, false)
+	assert.Empty(t, drifts)
+}
+
+func TestLedgerMigrationOpensBalances(t *testing.T) {
+	db := openUnmigratedDB(t)
+	require.NoError(t, db.Exec("CREATE TABLE books (id text PRIMARY KEY, title text, author text, quantity integer)").Error)
+	require.NoError(t, db.Exec("INSERT INTO books VALUES ('1', 'Dune', 'Frank Herbert', 2), ('2', 'Emma', 'Jane Austen', 0)").Error)
+	require.NoError(t, persistence.MigrateUp(db))
+
+	balances, err := persistence.NewGormLedgerRepository(db).Balances(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"1": 2}, balances)
+}
@@ -0,0 +1,133 @@
+package integrationtests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+	"github.com/brianantony456/go-doc/internal/infrastructure/gin_handler"
+	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+	appRouter "github.com/brianantony456/go-doc/internal/router"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupLoanRouter(t *testing.T) (*gin.Engine, *persistence.GormBookRepository) {
+	db := setupInMemoryDB(t)
+	repo := persistence.NewGormBookRepository(db)
+	books := service.NewBookService(repo, persistence.NewGormLedgerRepository(db),
+		service.NewAuditService(persistence.NewGormAuditRepository(db)), persistence.NewGormUnitOfWork(db))
+
+	router := gin.New()
+	apiRoutes := router.Group("/api/v1")
+	appRouter.RegisterAPIRoutes(apiRoutes, gin_handler.NewBookHandler(repo, books, service.NewHistoryService(repo)))
+	appRouter.RegisterLoanRoutes(apiRoutes, gin_handler.NewLoanHandler(service.NewLoanService(books, persistence.NewGormLoanRepository(db))))
+
+	require.NoError(t, books.Create(context.Background(), model.Book{ID: "1", Title: "Dune", Author: "Frank Herbert", Quantity: 1}))
+	return router, repo
+}
+
+func TestLoanLifecycle(t *testing.T) {
+	router, repo := setupLoanRouter(t)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/books/1/loans", nil)
+	req.Header.Set("X-Actor", "alice")
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var loan model.Loan
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &loan))
+	assert.Equal(t, "1", loan.BookID)
+	assert.Equal(t, "alice", loan.Actor)
+	assert.Nil(t, loan.ReturnedAt)
+	location := w.Header().Get("Location")
+	assert.Equal(t, "/api/v1/loans/1", location)
+
+	book, err := repo.GetByID(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, book.Quantity)
+
+	// The last copy is out
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/books/1/loans", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, location, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Returning twice brings back one copy
+	var returned model.Loan
+	for i := 0; i < 2; i++ {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, location+"/return", nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, location, w.Header().Get("Content-Location"))
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &returned))
+		require.NotNil(t, returned.ReturnedAt)
+	}
+	book, err = repo.GetByID(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, book.Quantity)
+}
+
+func TestLoanNotFound(t *testing.T) {
+	router, _ := setupLoanRouter(t)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/v1/loans/7", nil),
+		httptest.NewRequest(http.MethodPost, "/api/v1/loans/7/return", nil),
+		httptest.NewRequest(http.MethodGet, "/api/v1/loans/abc", nil),
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code, req.URL.Path)
+		var problem gin_handler.Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Contains(t, problem.Type, "#loan-not-found")
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/books/nope/loans", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestLegacyCirculationRoutesUseLoans(t *testing.T) {
+	router, repo := setupLoanRouter(t)
+	send := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+	quantity := func() int {
+		book, err := repo.GetByID(context.Background(), "1")
+		require.NoError(t, err)
+		return book.Quantity
+	}
+
+	// PATCH /return closes the loan, so returning the loan again is a no-op
+	location := send(http.MethodPost, "/api/v1/books/1/loans").Header().Get("Location")
+	require.Equal(t, http.StatusOK, send(http.MethodPatch, "/api/v1/return?id=1").Code)
+	require.Equal(t, http.StatusOK, send(http.MethodPost, location+"/return").Code)
+	assert.Equal(t, 1, quantity())
+
+	// PATCH /checkout opens a loan that can be returned by ID
+	w := send(http.MethodPatch, "/api/v1/checkout?id=1")
+	require.Equal(t, http.StatusOK, w.Code)
+	var book model.Book
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &book))
+	assert.Equal(t, 0, book.Quantity, "the legacy route still answers with the book")
+	w = send(http.MethodPost, "/api/v1/loans/2/return")
+	require.Equal(t, http.StatusOK, w.Code)
+	var loan model.Loan
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &loan))
+	assert.Equal(t, "1", loan.BookID)
+	require.Equal(t, http.StatusOK, send(http.MethodPatch, "/api/v1/return?id=1").Code, "copies lent before loans can still come back")
+	assert.Equal(t, 2, quantity())
+}
@@ -0,0 +1,161 @@
+package integrationtests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/infrastructure/marc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleMARCXML = `<?xml version="1.0" encoding="UTF-8"?>
+<marc:collection xmlns:marc="http://www.loc.gov/MARC21/slim">
+  <marc:record>
+    <marc:leader>00000cam a2200000 a 4500</marc:leader>
+    <marc:controlfield tag="001">pp-1813</marc:controlfield>
+    <marc:controlfield tag="008">850101s1813    enk           000 1 eng d</marc:controlfield>
+    <marc:datafield tag="020" ind1=" " ind2=" ">
+      <marc:subfield code="a">978-0-14-143951-8 (pbk.)</marc:subfield>
+    </marc:datafield>
+    <marc:datafield tag="100" ind1="1" ind2=" ">
+      <marc:subfield code="a">Austen, Jane,</marc:subfield>
+      <marc:subfield code="d">1775-1817.</marc:subfield>
+    </marc:datafield>
+    <marc:datafield tag="245" ind1="1" ind2="0">
+      <marc:subfield code="a">Pride and prejudice /</marc:subfield>
+      <marc:subfield code="c">Jane Austen.</marc:subfield>
+    </marc:datafield>
+    <marc:datafield tag="264" ind1=" " ind2="1">
+      <marc:subfield code="a">London :</marc:subfield>
+      <marc:subfield code="b">Penguin,</marc:subfield>
+      <marc:subfield code="c">2003.</marc:subfield>
+    </marc:datafield>
+    <marc:datafield tag="650" ind1=" " ind2="0">
+      <marc:subfield code="a">Courtship</marc:subfield>
+      <marc:subfield code="v">Fiction.</marc:subfield>
+    </marc:datafield>
+  </marc:record>
+</marc:collection>`
+
+func TestMARCToBook(t *testing.T) {
+	records, err := marc.ReadXML(strings.NewReader(sampleMARCXML))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	book, err := marc.ToBook(records[0])
+	require.NoError(t, err)
+	assert.Equal(t, "pp-1813", book.ID)
+	assert.Equal(t, "9780141439518", book.ISBN)
+	assert.Equal(t, "Austen, Jane", book.Author)
+	assert.Equal(t, "Pride and prejudice", book.Title)
+	assert.Equal(t, "Penguin", book.Publisher)
+	assert.Equal(t, "2003", book.Year)
+}
+
+func TestMARCBinaryRoundTrip(t *testing.T) {
+	records, err := marc.ReadXML(strings.NewReader(sampleMARCXML))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, marc.WriteBinary(&buf, records))
+	decoded, err := marc.ReadBinary(&buf)
+	require.NoError(t, err)
+	require.Len(t, decoded, 1)
+
+	assert.Equal(t, records[0].ControlFields, decoded[0].ControlFields)
+	assert.Equal(t, records[0].DataFields, decoded[0].DataFields)
+}
+
+func TestMARCReadBinaryRejectsTruncatedRecord(t *testing.T) {
+	_, err := marc.ReadBinary(strings.NewReader("00120nam"))
+	assert.ErrorIs(t, err, marc.ErrInvalidRecord)
+}
+
+func TestFromBookKeepsUnmappedFields(t *testing.T) {
+	records, err := marc.ReadXML(strings.NewReader(sampleMARCXML))
+	require.NoError(t, err)
+	book, err := marc.ToBook(records[0])
+	require.NoError(t, err)
+
+	unchanged, err := marc.FromBook(book)
+	require.NoError(t, err)
+	assert.Equal(t, records[0], unchanged)
+
+	book.Title = "Pride & Prejudice"
+	edited, err := marc.FromBook(book)
+	require.NoError(t, err)
+	assert.Equal(t, "Pride & Prejudice", edited.SubfieldValue("245", "a"))
+	assert.Equal(t, "Jane Austen.", edited.SubfieldValue("245", "c"))
+	assert.Equal(t, "Courtship", edited.SubfieldValue("650", "a"))
+}
+
+func TestImportAndExportMARCXML(t *testing.T) {
+	router := setupRouter()
+
+	req, _ := http.NewRequest("POST", "/api/books/import", strings.NewReader(sampleMARCXML))
+	req.Header.Set("Content-Type", "application/marcxml+xml")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/books/pp-1813", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var book model.Book
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &book))
+	assert.Equal(t, "Pride and prejudice", book.Title)
+
+	req, _ = http.NewRequest("GET", "/api/books/export", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	exported, err := marc.ReadXML(w.Body)
+	require.NoError(t, err)
+	original, _ := marc.ReadXML(strings.NewReader(sampleMARCXML))
+	assert.Equal(t, original, exported)
+}
+
+func TestImportMARCBinary(t *testing.T) {
+	router := setupRouter()
+
+	record := marc.NewRecord()
+	record.SetField(marc.DataField{Tag: "100", Ind1: "1", Ind2: " ", Subfields: []marc.Subfield{{Code: "a", Value: "Tolstoy, Leo,"}}})
+	record.SetField(marc.DataField{Tag: "245", Ind1: "1", Ind2: "0", Subfields: []marc.Subfield{{Code: "a", Value: "War and peace"}}})
+	var buf bytes.Buffer
+	require.NoError(t, marc.WriteBinary(&buf, []marc.Record{*record}))
+
+	req, _ := http.NewRequest("POST", "/api/books/import", &buf)
+	req.Header.Set("Content-Type", "application/marc")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Imported int          `json:"imported"`
+		Books    []model.Book `json:"books"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Equal(t, 1, body.Imported)
+	assert.NotEmpty(t, body.Books[0].ID)
+	assert.Equal(t, "Tolstoy, Leo", body.Books[0].Author)
+}
+
+func TestImportMARCInvalid(t *testing.T) {
+	router := setupRouter()
+
+	req, _ := http.NewRequest("POST", "/api/books/import", strings.NewReader("not marc"))
+	req.Header.Set("Content-Type", "application/marc")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,97 @@
+package integrationtests
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRepositoryCreateAndGet(t *testing.T) {
+	repo := persistence.NewMemoryBookRepository()
+
+	book := model.Book{ID: "1", Title: "A New Book", Author: "John Doe", Quantity: 3}
+	require.NoError(t, repo.Create(context.Background(), book))
+
+	found, err := repo.GetByID(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, book.Title, found.Title)
+	assert.False(t, found.CreatedAt.IsZero())
+	assert.Equal(t, found.CreatedAt, found.UpdatedAt)
+
+	assert.ErrorIs(t, repo.Create(context.Background(), book), repository.ErrBookExists)
+
+	_, err = repo.GetByID(context.Background(), "non-existent")
+	assert.ErrorIs(t, err, repository.ErrBookNotFound)
+	assert.Equal(t, "book not found", err.Error())
+}
+
+func TestMemoryRepositoryOrderAndCopies(t *testing.T) {
+	repo := persistence.NewMemoryBookRepository()
+	for _, id := range []string{"b", "c", "a"} {
+		require.NoError(t, repo.Create(context.Background(), model.Book{ID: id, Title: "Book " + id}))
+	}
+
+	books, err := repo.GetAll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, books, 3)
+	assert.Equal(t, []string{"b", "c", "a"}, []string{books[0].ID, books[1].ID, books[2].ID})
+
+	books[0].Title = "changed"
+	found, _ := repo.GetByID(context.Background(), "b")
+	found.Quantity = 99
+	again, _ := repo.GetByID(context.Background(), "b")
+	assert.Equal(t, "Book b", again.Title)
+	assert.Equal(t, 0, again.Quantity)
+
+	empty, err := persistence.NewMemoryBookRepository().GetAll(context.Background())
+	require.NoError(t, err)
+	assert.NotNil(t, empty)
+	assert.Empty(t, empty)
+}
+
+func TestMemoryRepositoryUpdate(t *testing.T) {
+	repo := persistence.NewMemoryBookRepository()
+	require.NoError(t, repo.Create(context.Background(), model.Book{ID: "1", Title: "Old", Quantity: 1}))
+	created, _ := repo.GetByID(context.Background(), "1")
+
+	created.Title = "New"
+	require.NoError(t, repo.Update(context.Background(), *created))
+	updated, _ := repo.GetByID(context.Background(), "1")
+	assert.Equal(t, "New", updated.Title)
+	assert.Equal(t, created.CreatedAt, updated.CreatedAt)
+	assert.False(t, updated.UpdatedAt.Before(created.UpdatedAt))
+
+	// Like GORM's Save, updating an unknown book inserts it
+	require.NoError(t, repo.Update(context.Background(), model.Book{ID: "2", Title: "Upserted"}))
+	books, _ := repo.GetAll(context.Background())
+	assert.Len(t, books, 2)
+}
+
+func TestMemoryRepositoryConcurrentAccess(t *testing.T) {
+	repo := persistence.NewMemoryBookRepository()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprint(i)
+			assert.NoError(t, repo.Create(context.Background(), model.Book{ID: id, Quantity: i}))
+			_, err := repo.GetByID(context.Background(), id)
+			assert.NoError(t, err)
+			_, err = repo.GetAll(context.Background())
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	books, _ := repo.GetAll(context.Background())
+	assert.Len(t, books, 50)
+}
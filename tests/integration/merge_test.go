@@ -0,0 +1,250 @@
+package integrationtests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+	"github.com/brianantony456/go-doc/internal/infrastructure/gin_handler"
+	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+	appRouter "github.com/brianantony456/go-doc/internal/router"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupMergeRouter(t *testing.T) (*gin.Engine, *service.BookService) {
+	db := setupInMemoryDB(t)
+	repo := persistence.NewGormBookRepository(db)
+	books := service.NewBookService(repo, persistence.NewGormLedgerRepository(db),
+		service.NewAuditService(persistence.NewGormAuditRepository(db)), persistence.NewGormUnitOfWork(db))
+
+	router := gin.New()
+	apiRoutes := router.Group("/api")
+	appRouter.RegisterAPIRoutes(apiRoutes, gin_handler.NewBookHandler(repo, books, service.NewHistoryService(repo)))
+	loans := persistence.NewGormLoanRepository(db)
+	appRouter.RegisterLoanRoutes(apiRoutes, gin_handler.NewLoanHandler(service.NewLoanService(books, loans)))
+	appRouter.RegisterMergeRoutes(apiRoutes, gin_handler.NewMergeHandler(service.NewMergeService(books, persistence.NewGormMergeRepository(db), loans)))
+
+	ctx := context.Background()
+	for _, book := range []model.Book{
+		{ID: "a", Title: "Pride and Prejudice", Author: "Jane Austen", ISBN: "978-0-14-143951-8", Quantity: 2},
+		{ID: "b", Title: "Pride and Prejudice (Penguin Classics)", Author: "Austen", ISBN: "9780141439518", Quantity: 1},
+		{ID: "c", Title: "Pride & prejudice.", Author: "Austen, Jane", Quantity: 3},
+		{ID: "d", Title: "Dune", Author: "Frank Herbert", ISBN: "9780441013593", Quantity: 1},
+	} {
+		require.NoError(t, books.Create(ctx, book))
+	}
+	return router, books
+}
+
+func TestDuplicateCandidates(t *testing.T) {
+	router, _ := setupMergeRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/books/duplicates", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var candidates []service.DuplicateCandidate
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &candidates))
+
+	pairs := map[string]service.DuplicateCandidate{}
+	for _, candidate := range candidates {
+		pairs[candidate.Books[0].ID+candidate.Books[1].ID] = candidate
+	}
+	require.Contains(t, pairs, "ab")
+	assert.Equal(t, 1.0, pairs["ab"].Score)
+	assert.Equal(t, service.MatchISBN, pairs["ab"].Reason)
+	assert.Equal(t, "ab", candidates[0].Books[0].ID+candidates[0].Books[1].ID, "best match first")
+	require.Contains(t, pairs, "ac")
+	assert.Equal(t, service.MatchTitleAuthor, pairs["ac"].Reason)
+	assert.NotContains(t, pairs, "ad")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/books/duplicates?min_score=0", nil))
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &candidates))
+	assert.Len(t, candidates, 3, "books with different ISBNs never match")
+	for _, candidate := range candidates {
+		assert.NotEqual(t, "d", candidate.Books[1].ID, "books whose titles start differently aren't compared")
+	}
+}
+
+func TestMergeAndUndo(t *testing.T) {
+	router, books := setupMergeRouter(t)
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+	quantity := func(id string) int {
+		w := send(http.MethodGet, "/api/books/"+id, "")
+		if w.Code != http.StatusOK {
+			return -1
+		}
+		var book model.Book
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &book))
+		return book.Quantity
+	}
+	beforeMerge := time.Now()
+
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/api/merges", `{"survivor_id": "a", "duplicate_id": "a"}`).Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodPost, "/api/merges", `{"survivor_id": "a", "duplicate_id": "missing"}`).Code)
+
+	w := send(http.MethodPost, "/api/merges", `{"survivor_id": "a", "duplicate_id": "c"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var merge model.BookMerge
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &merge))
+	assert.Equal(t, 3, merge.Copies)
+	assert.Equal(t, 5, quantity("a"), "copies are summed")
+	assert.Equal(t, -1, quantity("c"))
+
+	// The duplicate is still there in the history
+	w = send(http.MethodGet, "/api/books/c?as_of="+url.QueryEscape(beforeMerge.Format(time.RFC3339Nano)), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	entries, err := books.Ledger(context.Background(), "a")
+	require.NoError(t, err)
+	assert.Equal(t, model.ReasonMerge, entries[len(entries)-1].Reason)
+	drifts, err := books.Reconcile(context.Background(), false)
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+
+	undo := fmt.Sprintf("/api/merges/%d/undo", merge.ID)
+	w = send(http.MethodPost, undo, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &merge))
+	assert.NotNil(t, merge.UndoneAt)
+	assert.Equal(t, 2, quantity("a"))
+	assert.Equal(t, 3, quantity("c"))
+	assert.Equal(t, http.StatusConflict, send(http.MethodPost, undo, "").Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodPost, "/api/merges/99/undo", "").Code)
+
+	w = send(http.MethodGet, "/api/merges", "")
+	var merges []model.BookMerge
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &merges))
+	assert.Len(t, merges, 1)
+}
+
+func TestUndoMergeNeedsCopiesOnShelf(t *testing.T) {
+	router, books := setupMergeRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/merges", strings.NewReader(`{"survivor_id": "a", "duplicate_id": "c"}`)))
+	require.Equal(t, http.StatusCreated, w.Code)
+	for i := 0; i < 3; i++ {
+		_, err := books.Checkout(context.Background(), "a")
+		require.NoError(t, err)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/merges/1/undo", nil))
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestMergeMovesOpenLoans(t *testing.T) {
+	router, books := setupMergeRouter(t)
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+	quantity := func(id string) int {
+		var book model.Book
+		require.NoError(t, json.Unmarshal(send(http.MethodGet, "/api/books/"+id, "").Body.Bytes(), &book))
+		return book.Quantity
+	}
+	lend := func(id string) string {
+		w := send(http.MethodPost, "/api/books/"+id+"/loans", "")
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		return w.Header().Get("Location")
+	}
+	loanBook := func(location string) string {
+		var loan model.Loan
+		require.NoError(t, json.Unmarshal(send(http.MethodGet, location, "").Body.Bytes(), &loan))
+		return loan.BookID
+	}
+
+	returned, open := lend("c"), lend("c") // c: 3 copies, 1 left on the shelf
+	w := send(http.MethodPost, "/api/merges", `{"survivor_id": "a", "duplicate_id": "c"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var merge model.BookMerge
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &merge))
+	assert.Equal(t, 3, quantity("a"))
+	assert.Equal(t, "a", loanBook(returned))
+
+	require.Equal(t, http.StatusOK, send(http.MethodPost, returned+"/return", "").Code)
+	assert.Equal(t, 4, quantity("a"), "the copy comes back to the survivor")
+
+	w = send(http.MethodPost, fmt.Sprintf("/api/merges/%d/undo", merge.ID), "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, 2, quantity("a"))
+	assert.Equal(t, 2, quantity("c"), "the duplicate gets back its shelf copy and the returned one")
+	assert.Equal(t, "c", loanBook(returned))
+	assert.Equal(t, "c", loanBook(open))
+
+	require.Equal(t, http.StatusOK, send(http.MethodPost, open+"/return", "").Code)
+	assert.Equal(t, 3, quantity("c"))
+	drifts, err := books.Reconcile(context.Background(), false)
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+}
+
+func TestMergeMovesReturnedLoansAndLedgerEntries(t *testing.T) {
+	router, books := setupMergeRouter(t)
+	ctx := context.Background()
+	send := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+	loanBook := func(location string) string {
+		var loan model.Loan
+		require.NoError(t, json.Unmarshal(send(http.MethodGet, location).Body.Bytes(), &loan))
+		return loan.BookID
+	}
+	reasons := func(id string) []string {
+		entries, err := books.Ledger(ctx, id)
+		require.NoError(t, err)
+		reasons := []string{}
+		for _, entry := range entries {
+			reasons = append(reasons, entry.Reason)
+		}
+		return reasons
+	}
+
+	w := send(http.MethodPost, "/api/books/c/loans")
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	returned := w.Header().Get("Location")
+	require.Equal(t, http.StatusOK, send(http.MethodPost, returned+"/return").Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/merges", strings.NewReader(`{"survivor_id": "a", "duplicate_id": "c"}`)))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var merge model.BookMerge
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &merge))
+	assert.Equal(t, "a", loanBook(returned), "returned loans move too")
+	assert.Equal(t, []string{model.ReasonCreate, model.ReasonCreate, model.ReasonCheckout, model.ReasonReturn, model.ReasonMerge, model.ReasonMerge}, reasons("a"),
+		"the duplicate's ledger entries move to the survivor")
+	drifts, err := books.Reconcile(ctx, false)
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+
+	require.Equal(t, http.StatusOK, send(http.MethodPost, fmt.Sprintf("/api/merges/%d/undo", merge.ID)).Code)
+	assert.Equal(t, "c", loanBook(returned))
+	assert.Equal(t, []string{model.ReasonCreate, model.ReasonMerge, model.ReasonUnmerge}, reasons("a"))
+	assert.Equal(t, []string{model.ReasonCreate, model.ReasonCheckout, model.ReasonReturn, model.ReasonMerge, model.ReasonUnmerge}, reasons("c"))
+	book, err := books.Checkout(ctx, "c")
+	require.NoError(t, err)
+	assert.Equal(t, 2, book.Quantity, "a loan returned before the merge brings no copy back")
+	drifts, err = books.Reconcile(ctx, false)
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+}
@@ -0,0 +1,82 @@
+package integrationtests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func openUnmigratedDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(persistence.OpenSQLite(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	return db
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	db := openUnmigratedDB(t)
+
+	version, err := persistence.SchemaVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+
+	require.NoError(t, persistence.MigrateUp(db))
+	version, _ = persistence.SchemaVersion(db)
+	assert.Equal(t, persistence.LatestSchemaVersion(), version)
+	assert.True(t, db.Migrator().HasColumn("books", "updated_at"))
+
+	// Running again is a no-op
+	require.NoError(t, persistence.MigrateUp(db))
+
+	require.NoError(t, persistence.MigrateDown(db, 1))
+	version, _ = persistence.SchemaVersion(db)
+	assert.Equal(t, persistence.LatestSchemaVersion()-1, version)
+
+	// Back to version 2, before the timestamps
+	require.NoError(t, persistence.MigrateDown(db, version-2))
+	assert.False(t, db.Migrator().HasColumn("books", "updated_at"))
+	assert.True(t, db.Migrator().HasColumn("books", "isbn"))
+
+	statuses, err := persistence.MigrationStatuses(db)
+	require.NoError(t, err)
+	require.Len(t, statuses, persistence.LatestSchemaVersion())
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[len(statuses)-1].AppliedAt)
+
+	require.NoError(t, persistence.MigrateDown(db, persistence.LatestSchemaVersion()))
+	assert.False(t, db.Migrator().HasTable("books"))
+}
+
+func TestMigrateAdoptsAutoMigratedDatabase(t *testing.T) {
+	db := openUnmigratedDB(t)
+	// The schema AutoMigrate created before migrations existed
+	require.NoError(t, db.Exec("CREATE TABLE books (id text PRIMARY KEY, title text, author text, quantity integer)").Error)
+	require.NoError(t, db.Exec("INSERT INTO books VALUES ('1', 'Dune', 'Frank Herbert', 2)").Error)
+
+	require.NoError(t, persistence.MigrateUp(db))
+
+	book, err := persistence.NewGormBookRepository(db).GetByID(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, "Dune", book.Title)
+	assert.Equal(t, "", book.ISBN)
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	db := setupInMemoryDB(t)
+	repo := persistence.NewGormBookRepository(db)
+	require.NoError(t, repo.Create(context.Background(), model.Book{ID: "1", Title: "Dune"}))
+	require.NoError(t, db.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, 'from_the_future')",
+		persistence.LatestSchemaVersion()+1).Error)
+
+	assert.ErrorIs(t, persistence.MigrateUp(db), persistence.ErrSchemaTooNew)
+	assert.ErrorIs(t, persistence.MigrateDown(db, 1), persistence.ErrSchemaTooNew)
+	assert.True(t, db.Migrator().HasColumn("books", "updated_at"), "nothing is reverted")
+}
@@ -0,0 +1,224 @@
+package integrationtests
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/infrastructure/oaipmh"
+	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupOAIProvider(t *testing.T, pageSize int) *oaipmh.Provider {
+	repo := seedOAIBooks(t)
+	return oaipmh.NewProvider(repo, repo, oaipmh.Config{RepositoryName: "Test", RepositoryIdentifier: "test", PageSize: pageSize})
+}
+
+// seedOAIBooks creates books 1 to 5, changed at noon on the first five days
+// of March 2024. The repository stamps books itself, so the datestamps are
+// written to the table directly.
+func seedOAIBooks(t *testing.T) *persistence.GormBookRepository {
+	db := setupInMemoryDB(t)
+	repo := persistence.NewGormBookRepository(db)
+	for day := 1; day <= 5; day++ {
+		id := fmt.Sprint(day)
+		require.NoError(t, repo.Create(context.Background(), model.Book{ID: id, Title: fmt.Sprintf("Book %d", day), Author: "Author"}))
+		require.NoError(t, db.Model(&model.Book{}).Where("id = ?", id).
+			UpdateColumn("updated_at", time.Date(2024, 3, day, 12, 0, 0, 0, time.UTC)).Error)
+	}
+	return repo
+}
+
+func oaiRequest(t *testing.T, p *oaipmh.Provider, query string) *oaipmh.Response {
+	t.Helper()
+	args, err := url.ParseQuery(query)
+	require.NoError(t, err)
+	resp, err := p.Handle(context.Background(), args, "http://example.org/oai")
+	require.NoError(t, err)
+	return resp
+}
+
+func errorCode(resp *oaipmh.Response) string {
+	if len(resp.Errors) == 0 {
+		return ""
+	}
+	return resp.Errors[0].Code
+}
+
+func TestOAIIdentify(t *testing.T) {
+	p := setupOAIProvider(t, 10)
+
+	resp := oaiRequest(t, p, "verb=Identify")
+	require.NotNil(t, resp.Identify)
+	assert.Equal(t, "2024-03-01T12:00:00Z", resp.Identify.EarliestDatestamp)
+	assert.Equal(t, "http://example.org/oai", resp.Identify.BaseURL)
+}
+
+func TestOAIArgumentErrors(t *testing.T) {
+	p := setupOAIProvider(t, 10)
+
+	tests := []struct {
+		query string
+		code  string
+	}{
+		{"verb=Harvest", oaipmh.BadVerb},
+		{"verb=Identify&extra=1", oaipmh.BadArgument},
+		{"verb=ListRecords", oaipmh.BadArgument},
+		{"verb=ListRecords&metadataPrefix=marc21", oaipmh.CannotDisseminateFormat},
+		{"verb=ListRecords&metadataPrefix=oai_dc&from=2024-03-01&until=2024-03-02T00:00:00Z", oaipmh.BadArgument},
+		{"verb=ListRecords&metadataPrefix=oai_dc&from=2025-01-01", oaipmh.NoRecordsMatch},
+		{"verb=ListRecords&resumptionToken=bogus", oaipmh.BadResumptionToken},
+		{"verb=ListSets", oaipmh.NoSetHierarchy},
+		{"verb=GetRecord&metadataPrefix=oai_dc&identifier=oai:test:missing", oaipmh.IDDoesNotExist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.code, errorCode(oaiRequest(t, p, tt.query)))
+		})
+	}
+}
+
+func TestOAISelectiveHarvesting(t *testing.T) {
+	p := setupOAIProvider(t, 10)
+
+	resp := oaiRequest(t, p, "verb=ListIdentifiers&metadataPrefix=oai_dc&from=2024-03-02&until=2024-03-04")
+	require.NotNil(t, resp.ListIdentifiers)
+	var ids []string
+	for _, h := range resp.ListIdentifiers.Headers {
+		ids = append(ids, h.Identifier)
+	}
+	assert.Equal(t, []string{"oai:test:2", "oai:test:3", "oai:test:4"}, ids)
+	assert.Nil(t, resp.ListIdentifiers.ResumptionToken)
+}
+
+func TestOAIResumptionTokens(t *testing.T) {
+	p := setupOAIProvider(t, 2)
+
+	var titles []string
+	resp := oaiRequest(t, p, "verb=ListRecords&metadataPrefix=oai_dc")
+	for pages := 1; ; pages++ {
+		require.Empty(t, resp.Errors)
+		for _, r := range resp.ListRecords.Records {
+			titles = append(titles, r.Metadata.DC.Titles[0])
+		}
+		token := resp.ListRecords.ResumptionToken
+		require.NotNil(t, token)
+		assert.Equal(t, 5, token.CompleteListSize)
+		if token.Token == "" {
+			assert.Equal(t, 3, pages)
+			break
+		}
+		resp = oaiRequest(t, p, "verb=ListRecords&resumptionToken="+url.QueryEscape(token.Token))
+	}
+	assert.Equal(t, []string{"Book 1", "Book 2", "Book 3", "Book 4", "Book 5"}, titles)
+}
+
+func TestOAIResumptionSurvivesUpdates(t *testing.T) {
+	repo := seedOAIBooks(t)
+	p := oaipmh.NewProvider(repo, repo, oaipmh.Config{RepositoryName: "Test", RepositoryIdentifier: "test", PageSize: 2})
+
+	resp := oaiRequest(t, p, "verb=ListIdentifiers&metadataPrefix=oai_dc")
+	require.Empty(t, resp.Errors)
+	var ids []string
+	for _, h := range resp.ListIdentifiers.Headers {
+		ids = append(ids, h.Identifier)
+	}
+
+	// Touching a record that was already harvested moves it to the end of
+	// the datestamp order; the records behind it must not shift onto the
+	// page that was already served.
+	book, err := repo.GetByID(context.Background(), "1")
+	require.NoError(t, err)
+	book.Title = "Book 1, revised"
+	require.NoError(t, repo.Update(context.Background(), *book))
+
+	for token := resp.ListIdentifiers.ResumptionToken; token != nil && token.Token != ""; token = resp.ListIdentifiers.ResumptionToken {
+		resp = oaiRequest(t, p, "verb=ListIdentifiers&resumptionToken="+url.QueryEscape(token.Token))
+		require.Empty(t, resp.Errors)
+		for _, h := range resp.ListIdentifiers.Headers {
+			ids = append(ids, h.Identifier)
+		}
+	}
+	assert.Subset(t, ids, []string{"oai:test:1", "oai:test:2", "oai:test:3", "oai:test:4", "oai:test:5"})
+}
+
+func TestOAIDatestampsCannotBeBackdated(t *testing.T) {
+	router := setupRouter()
+	body := `{"id": "old", "title": "Backdated", "author": "Anon", "quantity": 1,` +
+		`"created_at": "2001-01-01T00:00:00Z", "updated_at": "2001-01-01T00:00:00Z"}`
+	req, _ := http.NewRequest("POST", "/api/books", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	from := time.Now().UTC().Add(-time.Minute).Format("2006-01-02T15:04:05Z")
+	req, _ = http.NewRequest("GET", "/oai?verb=ListIdentifiers&metadataPrefix=oai_dc&from="+from, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "<identifier>oai:go-doc:old</identifier>", "incremental harvests see the new book")
+	assert.NotContains(t, w.Body.String(), "2001-01-01")
+}
+
+func TestOAIDeletedRecords(t *testing.T) {
+	repo := seedOAIBooks(t)
+	p := oaipmh.NewProvider(repo, repo, oaipmh.Config{RepositoryName: "Test", RepositoryIdentifier: "test"})
+	require.NoError(t, repo.Delete(context.Background(), "2"))
+
+	assert.Equal(t, "persistent", oaiRequest(t, p, "verb=Identify").Identify.DeletedRecord)
+
+	from := time.Now().UTC().Add(-time.Minute).Format("2006-01-02T15:04:05Z")
+	resp := oaiRequest(t, p, "verb=ListIdentifiers&metadataPrefix=oai_dc&from="+from)
+	require.Empty(t, resp.Errors)
+	require.Len(t, resp.ListIdentifiers.Headers, 1, "the deletion is a change harvesters pick up")
+	assert.Equal(t, "oai:test:2", resp.ListIdentifiers.Headers[0].Identifier)
+	assert.Equal(t, "deleted", resp.ListIdentifiers.Headers[0].Status)
+
+	resp = oaiRequest(t, p, "verb=GetRecord&metadataPrefix=oai_dc&identifier=oai:test:2")
+	require.Empty(t, resp.Errors)
+	assert.Equal(t, "deleted", resp.GetRecord.Record.Header.Status)
+	assert.Nil(t, resp.GetRecord.Record.Metadata)
+
+	// A book created again under the same ID is live again
+	require.NoError(t, repo.Create(context.Background(), model.Book{ID: "2", Title: "Book 2", Author: "Author"}))
+	resp = oaiRequest(t, p, "verb=GetRecord&metadataPrefix=oai_dc&identifier=oai:test:2")
+	require.Empty(t, resp.Errors)
+	assert.Empty(t, resp.GetRecord.Record.Header.Status)
+	assert.NotNil(t, resp.GetRecord.Record.Metadata)
+}
+
+func TestOAIEndpoint(t *testing.T) {
+	router := setupRouter()
+	createOneRow(router)
+
+	req, _ := http.NewRequest("GET", "/oai?verb=GetRecord&metadataPrefix=oai_dc&identifier=oai:go-doc:1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "<dc:title>New Book</dc:title>")
+
+	req, _ = http.NewRequest("POST", "/oai", strings.NewReader("verb=Identify"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Identify struct {
+			RepositoryName string `xml:"repositoryName"`
+		} `xml:"Identify"`
+	}
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.Identify.RepositoryName)
+}
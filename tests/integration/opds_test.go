@@ -0,0 +1,175 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type opdsFeed struct {
+	Title        string `xml:"title"`
+	Updated      string `xml:"updated"`
+	TotalResults int    `xml:"totalResults"`
+	Links        []struct {
+		Rel  string `xml:"rel,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"link"`
+	Entries []struct {
+		ID      string `xml:"id"`
+		Title   string `xml:"title"`
+		Updated string `xml:"updated"`
+		Links   []struct {
+			Rel          string `xml:"rel,attr"`
+			Href         string `xml:"href,attr"`
+			Type         string `xml:"type,attr"`
+			Availability struct {
+				Status string `xml:"status,attr"`
+			} `xml:"availability"`
+		} `xml:"link"`
+	} `xml:"entry"`
+}
+
+func getOPDSFeed(t *testing.T, handler http.Handler, path string) opdsFeed {
+	t.Helper()
+	req, _ := http.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var feed opdsFeed
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &feed))
+	return feed
+}
+
+func (f opdsFeed) link(rel string) string {
+	for _, l := range f.Links {
+		if l.Rel == rel {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+func TestOPDSNavigationFeed(t *testing.T) {
+	router := setupRouter()
+
+	feed := getOPDSFeed(t, router, "/opds")
+	assert.Equal(t, "/opds/opensearch.xml", feed.link("search"))
+	assert.Len(t, feed.Entries, 2)
+}
+
+func TestOPDSAcquisitionFeedPagination(t *testing.T) {
+	router := setupRouter()
+	for i := 1; i <= 25; i++ {
+		createBook(t, router, model.Book{ID: fmt.Sprint(i), Title: fmt.Sprintf("Book %d", i), Author: "Author", Quantity: i % 2})
+	}
+
+	first := getOPDSFeed(t, router, "/opds/books")
+	assert.Equal(t, 25, first.TotalResults)
+	assert.Len(t, first.Entries, 20)
+	assert.Equal(t, "/opds/books?page=2", first.link("next"))
+	assert.Empty(t, first.link("previous"))
+
+	second := getOPDSFeed(t, router, first.link("next"))
+	assert.Len(t, second.Entries, 5)
+	assert.Empty(t, second.link("next"))
+
+	available := getOPDSFeed(t, router, "/opds/books?available=true")
+	assert.Equal(t, 13, available.TotalResults)
+
+	req, _ := http.NewRequest("GET", "/opds/books?page=3", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestOPDSSearchAndAvailability(t *testing.T) {
+	router := setupRouter()
+	createBook(t, router, model.Book{ID: "1", Title: "Dune", Author: "Frank Herbert", Quantity: 0})
+	createBook(t, router, model.Book{ID: "2", Title: "Emma", Author: "Jane Austen", Quantity: 2})
+
+	feed := getOPDSFeed(t, router, "/opds/search?q=herbert")
+	require.Len(t, feed.Entries, 1)
+	assert.Equal(t, "urn:go-doc:book:1", feed.Entries[0].ID)
+
+	var status string
+	for _, l := range feed.Entries[0].Links {
+		if l.Rel == "http://opds-spec.org/acquisition/borrow" {
+			status = l.Availability.Status
+		}
+	}
+	assert.Equal(t, "unavailable", status)
+
+	req, _ := http.NewRequest("GET", "/opds/opensearch.xml", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `template="/opds/search?q={searchTerms}"`)
+}
+
+func TestOPDSBorrowLinksAndDatestamps(t *testing.T) {
+	router := setupRouter()
+	createBook(t, router, model.Book{ID: "1", Title: "Dune", Author: "Frank Herbert", Quantity: 1})
+
+	req, _ := http.NewRequest("GET", "/api/books/1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var book model.Book
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &book))
+	updated := book.UpdatedAt.UTC().Format(time.RFC3339)
+
+	feed := getOPDSFeed(t, router, "/opds/books")
+	require.Len(t, feed.Entries, 1)
+	assert.Equal(t, updated, feed.Updated)
+	assert.Equal(t, updated, feed.Entries[0].Updated)
+	assert.Equal(t, updated, getOPDSFeed(t, router, "/opds").Updated)
+
+	var borrow, borrowType string
+	for _, l := range feed.Entries[0].Links {
+		if l.Rel == "http://opds-spec.org/acquisition/borrow" {
+			borrow, borrowType = l.Href, l.Type
+		}
+	}
+	assert.Equal(t, "/api/books/1/loans", borrow)
+
+	// The link resolves to the type it advertises, and POSTing to it
+	// borrows the book
+	mediaType := func(w *httptest.ResponseRecorder) string {
+		return strings.TrimSpace(strings.Split(w.Header().Get("Content-Type"), ";")[0])
+	}
+	req, _ = http.NewRequest("GET", borrow, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, "[]", w.Body.String())
+	assert.Equal(t, borrowType, mediaType(w))
+
+	req, _ = http.NewRequest("POST", borrow, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, borrowType, mediaType(w))
+}
+
+func TestOPDSNotFoundIsAProblem(t *testing.T) {
+	router := setupRouter()
+	createBook(t, router, model.Book{ID: "1", Title: "Dune", Author: "Frank Herbert", Quantity: 1})
+
+	for _, path := range []string{"/opds/books/missing", "/opds/books?page=2", "/opds/search?q=dune&page=0"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"), path)
+	}
+}
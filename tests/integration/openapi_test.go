@@ -0,0 +1,82 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	appRouter "github.com/brianantony456/go-doc/internal/router"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIDescribesEveryRoute(t *testing.T) {
+	router, _, err := appRouter.SetupRouter(appRouter.Config{Database: filepath.Join(t.TempDir(), "books.db")})
+	require.NoError(t, err)
+	doc := appRouter.OpenAPI()
+
+	registered := map[string]bool{}
+	for _, route := range router.Routes() {
+		registered[route.Method+" "+route.Path] = true
+		assert.True(t, doc.Has(route.Method, route.Path), "%s %s is missing from the OpenAPI document", route.Method, route.Path)
+	}
+	for path, item := range doc.Paths {
+		for method := range item {
+			ginPath := path
+			for _, segment := range strings.Split(path, "/") {
+				if strings.HasPrefix(segment, "{") {
+					ginPath = strings.Replace(ginPath, segment, ":"+strings.Trim(segment, "{}"), 1)
+				}
+			}
+			assert.True(t, registered[strings.ToUpper(method)+" "+ginPath], "%s %s is described but not routed", method, path)
+		}
+	}
+}
+
+func TestOpenAPIDocumentIsServed(t *testing.T) {
+	router, _, err := appRouter.SetupRouter(appRouter.Config{Database: filepath.Join(t.TempDir(), "books.db")})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var doc struct {
+		OpenAPI    string                     `json:"openapi"`
+		Paths      map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Required   []string `json:"required"`
+				Properties map[string]struct {
+					Type      string   `json:"type"`
+					Format    string   `json:"format"`
+					Minimum   *float64 `json:"minimum"`
+					MaxLength *int     `json:"maxLength"`
+				} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, "3.1.0", doc.OpenAPI)
+	assert.Contains(t, doc.Paths, "/api/books/{id}")
+
+	book := doc.Components.Schemas["Book"]
+	assert.ElementsMatch(t, []string{"title", "author"}, book.Required)
+	assert.NotContains(t, book.Properties, "TenantID", "fields hidden from JSON stay out of the schema")
+	assert.NotContains(t, book.Properties, "MarcRecord")
+	require.NotNil(t, book.Properties["quantity"].Minimum)
+	assert.Equal(t, 0.0, *book.Properties["quantity"].Minimum)
+	require.NotNil(t, book.Properties["title"].MaxLength)
+	assert.Equal(t, 500, *book.Properties["title"].MaxLength)
+	assert.Equal(t, "date-time", book.Properties["created_at"].Format)
+	assert.Contains(t, doc.Components.Schemas, "Problem")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), "openapi.json")
+}
@@ -0,0 +1,157 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+	"github.com/brianantony456/go-doc/internal/infrastructure/gin_handler"
+	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+	appRouter "github.com/brianantony456/go-doc/internal/router"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateDuplicateBookIsConflict(t *testing.T) {
+	router := setupRouter()
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/books", strings.NewReader(`{"id": "7", "title": "Emma", "author": "Jane Austen", "quantity": 1}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post()
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var book model.Book
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &book))
+	assert.False(t, book.CreatedAt.IsZero(), "the stored book is answered")
+	assert.False(t, book.UpdatedAt.IsZero())
+
+	w = post()
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	var problem gin_handler.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.True(t, strings.HasSuffix(problem.Type, "#book-exists"), problem.Type)
+}
+
+func TestErrorsAreProblemDetails(t *testing.T) {
+	router := setupRouter()
+	router.NoRoute(gin_handler.RouteNotFound)
+	createOneRow(router)
+
+	problemFor := func(method, path, body string) gin_handler.Problem {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Request-ID", "req-42")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.GreaterOrEqual(t, w.Code, 400, path)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"), path)
+
+		var problem gin_handler.Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem), path)
+		assert.Equal(t, w.Code, problem.Status, path)
+		assert.Equal(t, "req-42", problem.RequestID, path)
+		assert.Equal(t, path, problem.Instance)
+		return problem
+	}
+
+	notFound := problemFor(http.MethodGet, "/api/books/missing", "")
+	assert.True(t, strings.HasSuffix(notFound.Type, "#book-not-found"), notFound.Type)
+	assert.Equal(t, "Book not found", notFound.Title)
+	checkout := problemFor(http.MethodPatch, "/api/checkout?id=missing", "")
+	assert.Equal(t, notFound.Type, checkout.Type, "the same failure has the same type everywhere")
+	assert.Equal(t, notFound.Title, checkout.Title)
+
+	invalid := problemFor(http.MethodPost, "/api/books", `{"title": "Emma", "author": "Jane Austen", "quantity": "two"}`)
+	assert.True(t, strings.HasSuffix(invalid.Type, "#validation-failed"), invalid.Type)
+	assert.Equal(t, []gin_handler.FieldError{{Field: "quantity", Message: "must be an integer"}}, invalid.Errors)
+
+	malformed := problemFor(http.MethodPost, "/api/books", `{"title": `)
+	assert.True(t, strings.HasSuffix(malformed.Type, "#invalid-request"), malformed.Type)
+	assert.Empty(t, malformed.Errors)
+
+	asOf := problemFor(http.MethodGet, "/api/books?as_of=yesterday", "")
+	require.Len(t, asOf.Errors, 1)
+	assert.Equal(t, "as_of", asOf.Errors[0].Field)
+
+	missing := problemFor(http.MethodGet, "/api/citations?ids=1,2,3", "")
+	assert.Equal(t, []string{"2", "3"}, missing.IDs)
+
+	route := problemFor(http.MethodGet, "/api/nothing-here", "")
+	assert.Equal(t, http.StatusNotFound, route.Status)
+	assert.True(t, strings.HasSuffix(route.Type, "#route-not-found"), route.Type)
+}
+
+func TestCreateBookReportsEveryBrokenRule(t *testing.T) {
+	router := setupRouter()
+	post := func(body string) (int, gin_handler.Problem) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/books", strings.NewReader(body)))
+		var problem gin_handler.Problem
+		if w.Code >= 400 {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		}
+		return w.Code, problem
+	}
+
+	status, problem := post(`{"title": "", "quantity": -1, "isbn": "978-0-14-143951-9", "year": "18xx", "shelf": "B2"}`)
+	require.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, []gin_handler.FieldError{
+		{Field: "title", Message: "is required"},
+		{Field: "author", Message: "is required"},
+		{Field: "quantity", Message: "must be at least 0"},
+		{Field: "isbn", Message: "must be a valid ISBN-10 or ISBN-13"},
+		{Field: "year", Message: "must contain only digits"},
+		{Field: "shelf", Message: "is not a known field"},
+	}, problem.Errors)
+
+	_, problem = post(`{"title": "` + strings.Repeat("a", 501) + `", "author": "Anon", "quantity": "1"}`)
+	assert.Equal(t, []gin_handler.FieldError{
+		{Field: "title", Message: "must be at most 500 characters"},
+		{Field: "quantity", Message: "must be an integer"},
+	}, problem.Errors)
+
+	status, _ = post(`{"title": "Emma", "author": "Jane Austen", "quantity": 0, "isbn": "978-0-14-143951-8", "year": "1815"}`)
+	assert.Equal(t, http.StatusCreated, status)
+	status, _ = post(`{"title": "Emma", "author": "Jane Austen", "isbn": "0 14 143951 3"}`)
+	assert.Equal(t, http.StatusCreated, status, "spaced ISBN-10")
+}
+
+func TestRequestBodyLimit(t *testing.T) {
+	db := setupInMemoryDB(t)
+	repo := persistence.NewGormBookRepository(db)
+	books := service.NewBookService(repo, persistence.NewGormLedgerRepository(db),
+		service.NewAuditService(persistence.NewGormAuditRepository(db)), persistence.NewGormUnitOfWork(db))
+	router := gin.New()
+	router.Use(gin_handler.LimitBody(64))
+	appRouter.RegisterAPIRoutes(router.Group("/api"), gin_handler.NewBookHandler(repo, books, service.NewHistoryService(repo)))
+
+	for name, send := range map[string]func(body string) *http.Request{
+		"declared length": func(body string) *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/api/books", strings.NewReader(body))
+		},
+		"chunked": func(body string) *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/api/books", strings.NewReader(body))
+			req.ContentLength = -1
+			return req
+		},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, send(`{"title": "`+strings.Repeat("a", 100)+`", "author": "Anon"}`))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code, name)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"), name)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, send(`{"title": "Emma", "author": "Anon"}`))
+		assert.Equal(t, http.StatusCreated, w.Code, name)
+	}
+}
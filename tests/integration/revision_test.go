@@ -0,0 +1,133 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sendAs(router *gin.Engine, actor, method, path, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Actor", actor)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// sendWithAPIKey sends a request to the north library of setupTenantRouter
+// with apiKey.
+func sendWithAPIKey(router *gin.Engine, apiKey, method, path, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Host = "north.example.org"
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-API-Key", apiKey)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func bookTitle(t *testing.T, w *httptest.ResponseRecorder) string {
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var book model.Book
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &book))
+	return book.Title
+}
+
+func TestUndoAndRevertCatalogueEdits(t *testing.T) {
+	router := setupTenantRouter(t)
+	// Undo tells callers apart by API key, so alice and bob each have one
+	alice, bob := "north-key", "north-desk-key"
+	typo := strings.Replace(sampleMARCXML, "Pride and prejudice /", "Pride and prejudize /", 1)
+
+	require.Equal(t, http.StatusOK, sendWithAPIKey(router, alice, http.MethodPost, "/api/books/import", "application/marcxml+xml", sampleMARCXML).Code)
+	require.Equal(t, http.StatusOK, sendWithAPIKey(router, alice, http.MethodPost, "/api/books/pp-1813/adjustments", "application/json", `{"delta": 3}`).Code)
+	require.Equal(t, http.StatusOK, sendWithAPIKey(router, alice, http.MethodPost, "/api/books/import", "application/marcxml+xml", typo).Code)
+
+	assert.Equal(t, http.StatusNotFound, sendWithAPIKey(router, bob, http.MethodPost, "/api/undo", "application/json", "").Code,
+		"only the actor's own changes can be undone")
+
+	w := sendWithAPIKey(router, alice, http.MethodPost, "/api/undo", "application/json", "")
+	assert.Equal(t, "Pride and prejudice", bookTitle(t, w))
+	var book model.Book
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &book))
+	assert.Equal(t, 3, book.Quantity, "quantities are not reverted")
+
+	// Undoing the undo redoes the edit
+	w = sendWithAPIKey(router, alice, http.MethodPost, "/api/undo", "application/json", "")
+	assert.Equal(t, "Pride and prejudize", bookTitle(t, w))
+
+	w = sendWithAPIKey(router, alice, http.MethodGet, "/api/books/pp-1813/revisions", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var revisions []model.AuditEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &revisions))
+	require.Len(t, revisions, 5)
+	first, latest := revisions[0], revisions[len(revisions)-1]
+	assert.Equal(t, service.ActionBookImport, first.Action)
+	assert.Equal(t, service.ActionBookUndo, latest.Action)
+
+	w = sendWithAPIKey(router, bob, http.MethodPost, "/api/books/pp-1813/revert", "application/json",
+		fmt.Sprintf(`{"revision": %d, "expected_revision": %d}`, first.ID, latest.ID-1))
+	assert.Equal(t, http.StatusConflict, w.Code, "the client hasn't seen the latest revision")
+
+	w = sendWithAPIKey(router, bob, http.MethodPost, "/api/books/pp-1813/revert", "application/json",
+		fmt.Sprintf(`{"revision": %d, "expected_revision": %d}`, first.ID, latest.ID))
+	assert.Equal(t, "Pride and prejudice", bookTitle(t, w))
+
+	// Bob changed the book after Alice's last edit, so her undo would lose his
+	assert.Equal(t, http.StatusConflict, sendWithAPIKey(router, alice, http.MethodPost, "/api/undo", "application/json", "").Code)
+
+	assert.Equal(t, http.StatusNotFound, sendWithAPIKey(router, bob, http.MethodPost, "/api/books/pp-1813/revert", "application/json", `{"revision": 999}`).Code)
+	assert.Equal(t, http.StatusNotFound, sendWithAPIKey(router, bob, http.MethodGet, "/api/books/missing/revisions", "", "").Code)
+}
+
+func TestUndoNeedsAnAPIKey(t *testing.T) {
+	router, _ := setupAuditRouter(t)
+	typo := strings.Replace(sampleMARCXML, "Pride and prejudice /", "Pride and prejudize /", 1)
+	require.Equal(t, http.StatusOK, sendAs(router, "alice", http.MethodPost, "/api/books/import", "application/marcxml+xml", sampleMARCXML).Code)
+	require.Equal(t, http.StatusOK, sendAs(router, "alice", http.MethodPost, "/api/books/import", "application/marcxml+xml", typo).Code)
+
+	// Without keys X-Actor is all there is, and anyone can send it
+	for _, actor := range []string{"bob", "alice"} {
+		w := sendAs(router, actor, http.MethodPost, "/api/undo", "application/json", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code, actor)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"), actor)
+	}
+	assert.Equal(t, "Pride and prejudize", bookTitle(t, sendAs(router, "alice", http.MethodGet, "/api/books/pp-1813", "", "")))
+}
+
+func TestRevertIgnoresCirculationSinceExpectedRevision(t *testing.T) {
+	router, _ := setupAuditRouter(t)
+	typo := strings.Replace(sampleMARCXML, "Pride and prejudice /", "Pride and prejudize /", 1)
+
+	require.Equal(t, http.StatusOK, sendAs(router, "alice", http.MethodPost, "/api/books/import", "application/marcxml+xml", sampleMARCXML).Code)
+	require.Equal(t, http.StatusOK, sendAs(router, "alice", http.MethodPost, "/api/books/import", "application/marcxml+xml", typo).Code)
+	w := sendAs(router, "alice", http.MethodGet, "/api/books/pp-1813/revisions", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var revisions []model.AuditEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &revisions))
+	require.Len(t, revisions, 2)
+	first, edited := revisions[0], revisions[1]
+
+	// Copies coming and going don't change the catalogue data
+	require.Equal(t, http.StatusOK, sendAs(router, "desk", http.MethodPost, "/api/books/pp-1813/adjustments", "application/json", `{"delta": 2}`).Code)
+	require.Equal(t, http.StatusOK, sendAs(router, "desk", http.MethodPatch, "/api/checkout?id=pp-1813", "", "").Code)
+
+	w = sendAs(router, "bob", http.MethodPost, "/api/books/pp-1813/revert", "application/json",
+		fmt.Sprintf(`{"revision": %d, "expected_revision": %d}`, first.ID, first.ID))
+	assert.Equal(t, http.StatusConflict, w.Code, "the edit came after the expected revision")
+
+	w = sendAs(router, "bob", http.MethodPost, "/api/books/pp-1813/revert", "application/json",
+		fmt.Sprintf(`{"revision": %d, "expected_revision": %d}`, first.ID, edited.ID))
+	assert.Equal(t, "Pride and prejudice", bookTitle(t, w))
+}
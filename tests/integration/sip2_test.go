@@ -0,0 +1,199 @@
+package integrationtests
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+	"github.com/brianantony456/go-doc/internal/infrastructure/sip2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sipDate = "20240301    120000"
+
+func setupSIP2Server(t *testing.T, config sip2.Config) (*sip2.Server, *persistence.GormBookRepository) {
+	db := setupInMemoryDB(t)
+	repo := persistence.NewGormBookRepository(db)
+	require.NoError(t, repo.Create(context.Background(), model.Book{ID: "item-1", Title: "Dune", Author: "Frank Herbert", Quantity: 1}))
+	books := service.NewBookService(repo, persistence.NewGormLedgerRepository(db), service.NewAuditService(persistence.NewGormAuditRepository(db)), persistence.NewGormUnitOfWork(db))
+	return sip2.NewServer(repo, service.NewLoanService(books, persistence.NewGormLoanRepository(db)), config), repo
+}
+
+// withChecksum appends the sequence number and checksum to a request.
+func withChecksum(msg string, seq int) string {
+	msg += "AY" + string(rune('0'+seq)) + "AZ"
+	return msg + sip2.Checksum(msg)
+}
+
+func TestSIP2Checksum(t *testing.T) {
+	// 'A' + 'Z' = 155, and the checksum is its 16-bit two's complement
+	assert.Equal(t, "FF65", sip2.Checksum("AZ"))
+
+	msg, err := sip2.Parse(withChecksum("9900302.00", 3))
+	require.NoError(t, err)
+	assert.Equal(t, 3, msg.Sequence)
+
+	_, err = sip2.Parse("9900302.00AY3AZ0000")
+	assert.ErrorIs(t, err, sip2.ErrChecksum)
+}
+
+func TestSIP2CirculationSession(t *testing.T) {
+	server, repo := setupSIP2Server(t, sip2.Config{Username: "kiosk", Password: "secret", InstitutionID: "inst"})
+	sess := server.NewSession()
+
+	_, ok := sess.Handle(context.Background(), "9900302.00")
+	assert.False(t, ok, "messages before login drop the connection")
+
+	resp, ok := sess.Handle(context.Background(), withChecksum("9300CNkiosk|COwrong|", 0))
+	require.True(t, ok)
+	assert.True(t, strings.HasPrefix(resp, "940AY0AZ"))
+
+	resp, _ = sess.Handle(context.Background(), withChecksum("9300CNkiosk|COsecret|", 1))
+	assert.True(t, strings.HasPrefix(resp, "941AY1AZ"))
+	msg, err := sip2.Parse(resp)
+	require.NoError(t, err, "responses carry a valid checksum")
+	assert.Equal(t, 1, msg.Sequence)
+
+	resp, _ = sess.Handle(context.Background(), withChecksum("23000"+sipDate+"AOinst|AApatron-7|", 2))
+	assert.Contains(t, resp, "|BLY|")
+
+	resp, _ = sess.Handle(context.Background(), withChecksum("17"+sipDate+"AOinst|ABitem-1|", 3))
+	assert.True(t, strings.HasPrefix(resp, "18030001"), resp)
+	assert.Contains(t, resp, "AJDune|")
+
+	resp, _ = sess.Handle(context.Background(), withChecksum("11YN"+sipDate+sipDate+"AOinst|AApatron-7|ABitem-1|ACpw|", 4))
+	assert.True(t, strings.HasPrefix(resp, "121YNY"), resp)
+	assert.Contains(t, resp, "|AH")
+	book, _ := repo.GetByID(context.Background(), "item-1")
+	assert.Equal(t, 0, book.Quantity)
+
+	resp, _ = sess.Handle(context.Background(), withChecksum("11YN"+sipDate+sipDate+"AOinst|AApatron-8|ABitem-1|ACpw|", 5))
+	assert.True(t, strings.HasPrefix(resp, "120NN"), resp)
+	assert.Contains(t, resp, "AFNo copies available|")
+
+	resent, _ := sess.Handle(context.Background(), "97")
+	assert.Equal(t, resp, resent)
+
+	resp, _ = sess.Handle(context.Background(), withChecksum("29NN"+sipDate+sipDate+"AOinst|AApatron-7|ABitem-1|", 6))
+	assert.True(t, strings.HasPrefix(resp, "301Y"), resp)
+
+	resp, _ = sess.Handle(context.Background(), withChecksum("09N"+sipDate+sipDate+"APMain|AOinst|ABitem-1|ACpw|", 7))
+	assert.True(t, strings.HasPrefix(resp, "101Y"), resp)
+	book, _ = repo.GetByID(context.Background(), "item-1")
+	assert.Equal(t, 1, book.Quantity)
+
+	resp, _ = sess.Handle(context.Background(), "09N"+sipDate+sipDate+"ABitem-1|AY8AZFFFF")
+	assert.Equal(t, "96", resp, "a bad checksum asks for a resend")
+}
+
+func TestSIP2OverTCP(t *testing.T) {
+	server, _ := setupSIP2Server(t, sip2.Config{InstitutionID: "inst", LibraryName: "Test Library"})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(l)
+	defer l.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	_, err = conn.Write([]byte(withChecksum("9900302.00", 0) + "\r"))
+	require.NoError(t, err)
+	resp, err := r.ReadString('\r')
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(resp, "98YYYYNN030003"), resp)
+	assert.Contains(t, resp, "AMTest Library|")
+}
+
+func TestSIP2CirculationOpensAndClosesLoans(t *testing.T) {
+	db := setupInMemoryDB(t)
+	ctx := context.Background()
+	repo := persistence.NewGormBookRepository(db)
+	require.NoError(t, repo.Create(ctx, model.Book{ID: "item-1", Title: "Dune", Author: "Frank Herbert", Quantity: 1}))
+	books := service.NewBookService(repo, persistence.NewGormLedgerRepository(db), service.NewAuditService(persistence.NewGormAuditRepository(db)), persistence.NewGormUnitOfWork(db))
+	loans := persistence.NewGormLoanRepository(db)
+	sess := sip2.NewServer(repo, service.NewLoanService(books, loans), sip2.Config{InstitutionID: "inst"}).NewSession()
+
+	resp, _ := sess.Handle(ctx, withChecksum("11YN"+sipDate+sipDate+"AOinst|AApatron-7|ABitem-1|ACpw|", 0))
+	require.True(t, strings.HasPrefix(resp, "121YNY"), resp)
+	open, err := loans.ListByBook(ctx, "item-1")
+	require.NoError(t, err)
+	require.Len(t, open, 1)
+	assert.Nil(t, open[0].ReturnedAt)
+
+	resp, _ = sess.Handle(ctx, withChecksum("09N"+sipDate+sipDate+"APinst|AOinst|ABitem-1|ACpw|", 1))
+	require.True(t, strings.HasPrefix(resp, "101YN"), resp)
+	closed, err := loans.GetByID(ctx, open[0].ID)
+	require.NoError(t, err)
+	assert.NotNil(t, closed.ReturnedAt, "the kiosk's return closes the loan")
+}
+
+func TestSIP2RenewNeedsThePatronsOpenLoan(t *testing.T) {
+	db := setupInMemoryDB(t)
+	ctx := context.Background()
+	repo := persistence.NewGormBookRepository(db)
+	require.NoError(t, repo.Create(ctx, model.Book{ID: "item-1", Title: "Dune", Author: "Frank Herbert", Quantity: 2}))
+	books := service.NewBookService(repo, persistence.NewGormLedgerRepository(db), service.NewAuditService(persistence.NewGormAuditRepository(db)), persistence.NewGormUnitOfWork(db))
+	loans := persistence.NewGormLoanRepository(db)
+	sess := sip2.NewServer(repo, service.NewLoanService(books, loans), sip2.Config{InstitutionID: "inst"}).NewSession()
+
+	renew := func(patron string, seq int) string {
+		resp, _ := sess.Handle(ctx, withChecksum("29NN"+sipDate+sipDate+"AOinst|AA"+patron+"|ABitem-1|", seq))
+		return resp
+	}
+	resp := renew("patron-7", 0)
+	assert.True(t, strings.HasPrefix(resp, "300N"), resp)
+	assert.Contains(t, resp, "AFItem not checked out to this patron|")
+
+	resp, _ = sess.Handle(ctx, withChecksum("11YN"+sipDate+sipDate+"AOinst|AApatron-7|ABitem-1|ACpw|", 1))
+	require.True(t, strings.HasPrefix(resp, "121YNY"), resp)
+
+	resp = renew("patron-8", 2)
+	assert.True(t, strings.HasPrefix(resp, "300N"), "another patron can't renew the loan: %s", resp)
+
+	resp = renew("patron-7", 3)
+	assert.True(t, strings.HasPrefix(resp, "301Y"), resp)
+	assert.Contains(t, resp, "|AH")
+	open, err := loans.ListByBook(ctx, "item-1")
+	require.NoError(t, err)
+	require.Len(t, open, 1)
+	assert.Equal(t, "patron-7", open[0].Patron)
+	assert.NotNil(t, open[0].RenewedAt)
+
+	resp, _ = sess.Handle(ctx, withChecksum("09N"+sipDate+sipDate+"APinst|AOinst|ABitem-1|ACpw|", 4))
+	require.True(t, strings.HasPrefix(resp, "101Y"), resp)
+	resp = renew("patron-7", 5)
+	assert.True(t, strings.HasPrefix(resp, "300N"), "a returned loan can't be renewed: %s", resp)
+}
+
+func TestSIP2IgnoresUnsupportedMessages(t *testing.T) {
+	server, _ := setupSIP2Server(t, sip2.Config{InstitutionID: "inst"})
+	sess := server.NewSession()
+
+	// 35 is End Patron Session, which the server doesn't support
+	resp, ok := sess.Handle(context.Background(), withChecksum("35"+sipDate+"AOinst|AApatron-7|", 0))
+	assert.True(t, ok)
+	assert.Empty(t, resp, "only bad checksums ask for a resend")
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(l)
+	defer l.Close()
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(withChecksum("35"+sipDate+"AOinst|AApatron-7|", 0) + "\r" + withChecksum("9900302.00", 1) + "\r"))
+	require.NoError(t, err)
+	resp, err = bufio.NewReader(conn).ReadString('\r')
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(resp, "98"), "the next message is answered: %s", resp)
+}
@@ -0,0 +1,132 @@
+package integrationtests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+	"github.com/brianantony456/go-doc/internal/infrastructure/sru"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSRUServer(t *testing.T) *sru.Server {
+	repo := persistence.NewGormBookRepository(setupInMemoryDB(t))
+	books := []model.Book{
+		{ID: "1", Title: "Pride and Prejudice", Author: "Austen, Jane", ISBN: "9780141439518"},
+		{ID: "2", Title: "Sense and Sensibility", Author: "Austen, Jane"},
+		{ID: "3", Title: "War and Peace", Author: "Tolstoy, Leo"},
+		{ID: "4", Title: "Anna Karenina", Author: "Tolstoy, Leo"},
+	}
+	for _, book := range books {
+		require.NoError(t, repo.Create(context.Background(), book))
+	}
+	return sru.NewServer(repo)
+}
+
+func sruSearch(t *testing.T, s *sru.Server, params string) *sru.Response {
+	t.Helper()
+	values, err := url.ParseQuery(params)
+	require.NoError(t, err)
+	resp, err := s.SearchRetrieve(context.Background(), values)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestSRUQueries(t *testing.T) {
+	s := setupSRUServer(t)
+
+	tests := []struct {
+		query string
+		hits  int
+	}{
+		{`austen`, 2},
+		{`title = "pride and prejudice"`, 1},
+		{`title = "prejudice pride"`, 0},
+		{`title all "peace war"`, 1},
+		{`title any "emma peace"`, 1},
+		{`dc.creator = tolstoy and title = anna`, 1},
+		{`author = austen or author = tolstoy`, 4},
+		{`author = austen not title = sense`, 1},
+		{`(title = war or title = pride) and author = tolstoy`, 1},
+		{`title = sens*`, 1},
+		{`isbn = 978-0-14-143951-8`, 1},
+		{`title exact "Anna Karenina"`, 1},
+		{`cql.allRecords = 1`, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			resp := sruSearch(t, s, "version=1.2&operation=searchRetrieve&query="+url.QueryEscape(tt.query))
+			require.Nil(t, resp.Diagnostics)
+			assert.Equal(t, tt.hits, resp.NumberOfRecords)
+		})
+	}
+}
+
+func TestSRUDiagnostics(t *testing.T) {
+	s := setupSRUServer(t)
+
+	tests := []struct {
+		params string
+		uri    string
+	}{
+		{"version=2.0", "info:srw/diagnostic/1/7"},
+		{"version=1.1&query=austen", "info:srw/diagnostic/1/5"},
+		{"operation=scan&query=austen", "info:srw/diagnostic/1/4"},
+		{"query=" + url.QueryEscape(`title = "unterminated`), "info:srw/diagnostic/1/10"},
+		{"query=" + url.QueryEscape(`(title = war`), "info:srw/diagnostic/1/10"},
+		{"query=" + url.QueryEscape(`subject = history`), "info:srw/diagnostic/1/16"},
+		{"query=" + url.QueryEscape(`title < war`), "info:srw/diagnostic/1/19"},
+		{"query=" + url.QueryEscape(`title =/stem war`), "info:srw/diagnostic/1/20"},
+		{"query=" + url.QueryEscape(`war prox peace`), "info:srw/diagnostic/1/37"},
+		{"query=" + url.QueryEscape(`war sortBy title`), "info:srw/diagnostic/1/80"},
+		{"query=austen&recordSchema=mods", "info:srw/diagnostic/1/66"},
+		{"query=austen&startRecord=5", "info:srw/diagnostic/1/61"},
+		{"query=austen&maximumRecords=-1", "info:srw/diagnostic/1/6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.params, func(t *testing.T) {
+			resp := sruSearch(t, s, tt.params)
+			require.NotNil(t, resp.Diagnostics)
+			assert.Equal(t, tt.uri, resp.Diagnostics.Diagnostics[0].URI)
+		})
+	}
+}
+
+func TestSRUPagingAndSchemas(t *testing.T) {
+	s := setupSRUServer(t)
+
+	resp := sruSearch(t, s, "query=cql.allRecords%3D1&maximumRecords=3")
+	assert.Equal(t, 4, resp.NumberOfRecords)
+	require.Len(t, resp.Records.Records, 3)
+	assert.Equal(t, 4, resp.NextRecordPosition)
+	assert.Equal(t, "xml", resp.Records.Records[0].RecordXMLEscaping)
+
+	resp = sruSearch(t, s, "query=cql.allRecords%3D1&startRecord=4")
+	require.Len(t, resp.Records.Records, 1)
+	assert.Equal(t, 4, resp.Records.Records[0].RecordPosition)
+	assert.Zero(t, resp.NextRecordPosition)
+
+	resp = sruSearch(t, s, "query=title%3Danna&recordSchema=marcxml")
+	require.Len(t, resp.Records.Records, 1)
+	assert.Equal(t, sru.SchemaMARCXML, resp.Records.Records[0].RecordSchema)
+	assert.Contains(t, resp.Records.Records[0].RecordData.XML, `<subfield code="a">Anna Karenina</subfield>`)
+}
+
+func TestSRUEndpoint(t *testing.T) {
+	router := setupRouter()
+	createOneRow(router)
+
+	req, _ := http.NewRequest("GET", "/sru?version=1.2&operation=searchRetrieve&query=title%3D%22new+book%22", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `<searchRetrieveResponse xmlns="http://www.loc.gov/zing/srw/">`)
+	assert.Contains(t, w.Body.String(), "<numberOfRecords>1</numberOfRecords>")
+	assert.Contains(t, w.Body.String(), "<dc:title>New Book</dc:title>")
+}
@@ -0,0 +1,170 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brianantony456/go-doc/internal/domain/model"
+	"github.com/brianantony456/go-doc/internal/domain/service"
+	"github.com/brianantony456/go-doc/internal/infrastructure/cache"
+	"github.com/brianantony456/go-doc/internal/infrastructure/gin_handler"
+	"github.com/brianantony456/go-doc/internal/infrastructure/persistence"
+	appRouter "github.com/brianantony456/go-doc/internal/router"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTenantRouter(t *testing.T) *gin.Engine {
+	db := setupInMemoryDB(t)
+	gormBooks := persistence.NewGormBookRepository(db)
+	repo := cache.NewBookRepository(gormBooks, cache.Config{})
+	audit := service.NewAuditService(persistence.NewGormAuditRepository(db))
+	books := service.NewBookService(repo, persistence.NewGormLedgerRepository(db), audit, persistence.NewGormUnitOfWork(db))
+
+	router := gin.New()
+	router.Use(gin_handler.TenantScope([]gin_handler.Tenant{
+		{ID: "north", Name: "North Library", Hosts: []string{"north.example.org"}, APIKeys: []string{"north-key", "north-desk-key"}},
+		{ID: "south", Name: "South Library", Hosts: []string{"south.example.org"}, APIKeys: []string{"south-key"}},
+	}))
+	apiRoutes := router.Group("/api")
+	appRouter.RegisterAPIRoutes(apiRoutes, gin_handler.NewBookHandler(repo, books, service.NewHistoryService(gormBooks)))
+	appRouter.RegisterLoanRoutes(apiRoutes, gin_handler.NewLoanHandler(service.NewLoanService(books, persistence.NewGormLoanRepository(db))))
+	appRouter.RegisterAdminRoutes(router.Group("/api/admin"), []string{testAdminKey}, gin_handler.NewAuditHandler(audit))
+	appRouter.RegisterOPDSRoutes(router.Group("/opds"), gin_handler.NewOPDSHandler(repo, "/opds", "/api"))
+	return router
+}
+
+type tenantRequest struct {
+	host, apiKey, tenant string
+}
+
+func (r tenantRequest) send(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Host = r.host
+	req.Header.Set("Content-Type", "application/json")
+	if r.apiKey != "" {
+		req.Header.Set("X-API-Key", r.apiKey)
+	}
+	if r.tenant != "" {
+		req.Header.Set("X-Tenant", r.tenant)
+	}
+	if strings.HasPrefix(path, "/api/admin/") {
+		req.Header.Set("Authorization", "Bearer "+testAdminKey)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestTenantsCannotSeeOrChangeEachOthersData(t *testing.T) {
+	router := setupTenantRouter(t)
+	north := tenantRequest{host: "north.example.org:8000"}
+	south := tenantRequest{host: "api.example.org", apiKey: "south-key"}
+
+	require.Equal(t, http.StatusCreated, north.send(router, http.MethodPost, "/api/books", `{"id": "1", "title": "Northern Lights", "author": "Philip Pullman", "quantity": 2}`).Code)
+	require.Equal(t, http.StatusCreated, north.send(router, http.MethodPost, "/api/books", `{"id": "n", "title": "North only", "author": "Anon", "quantity": 1}`).Code)
+	require.Equal(t, http.StatusCreated, south.send(router, http.MethodPost, "/api/books", `{"id": "1", "title": "Southern Cross", "author": "Anon", "quantity": 5}`).Code,
+		"each tenant has its own IDs")
+
+	var books []model.Book
+	require.NoError(t, json.Unmarshal(south.send(router, http.MethodGet, "/api/books", "").Body.Bytes(), &books))
+	require.Len(t, books, 1)
+	assert.Equal(t, "Southern Cross", books[0].Title)
+	assert.NotContains(t, south.send(router, http.MethodGet, "/opds/books", "").Body.String(), "Northern Lights")
+
+	// Reads and writes of the other tenant's books find nothing
+	assert.Equal(t, http.StatusNotFound, south.send(router, http.MethodGet, "/api/books/n", "").Code)
+	assert.Equal(t, http.StatusNotFound, south.send(router, http.MethodPatch, "/api/checkout?id=n", "").Code)
+	assert.Equal(t, http.StatusNotFound, south.send(router, http.MethodGet, "/api/books/n/ledger", "").Code)
+
+	require.Equal(t, http.StatusOK, south.send(router, http.MethodPatch, "/api/checkout?id=1", "").Code)
+	var book model.Book
+	require.NoError(t, json.Unmarshal(north.send(router, http.MethodGet, "/api/books/1", "").Body.Bytes(), &book))
+	assert.Equal(t, "Northern Lights", book.Title)
+	assert.Equal(t, 2, book.Quantity)
+
+	var ledger []model.LedgerEntry
+	require.NoError(t, json.Unmarshal(north.send(router, http.MethodGet, "/api/books/1/ledger", "").Body.Bytes(), &ledger))
+	assert.Len(t, ledger, 1)
+
+	var audit struct {
+		Entries []model.AuditEntry `json:"entries"`
+	}
+	require.NoError(t, json.Unmarshal(north.send(router, http.MethodGet, "/api/admin/audit", "").Body.Bytes(), &audit))
+	assert.Len(t, audit.Entries, 2)
+	for _, tenant := range []tenantRequest{north, south} {
+		var result service.Verification
+		require.NoError(t, json.Unmarshal(tenant.send(router, http.MethodGet, "/api/admin/audit/verify", "").Body.Bytes(), &result))
+		assert.True(t, result.Valid, "each tenant has an intact chain of its own")
+	}
+}
+
+func TestTenantResolution(t *testing.T) {
+	router := setupTenantRouter(t)
+	tenantName := func(r tenantRequest) string {
+		w := r.send(router, http.MethodGet, "/api/tenant", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var body struct {
+			Name string `json:"name"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return body.Name
+	}
+
+	assert.Equal(t, "North Library", tenantName(tenantRequest{host: "NORTH.example.org"}))
+	assert.Equal(t, "South Library", tenantName(tenantRequest{host: "api.example.org", tenant: "south"}))
+	assert.Equal(t, "South Library", tenantName(tenantRequest{host: "south.example.org", apiKey: "south-key", tenant: "south"}))
+
+	for name, r := range map[string]struct {
+		request tenantRequest
+		status  int
+	}{
+		"unknown host":             {tenantRequest{host: "api.example.org"}, http.StatusNotFound},
+		"unknown tenant":           {tenantRequest{host: "api.example.org", tenant: "east"}, http.StatusNotFound},
+		"bad API key":              {tenantRequest{host: "north.example.org", apiKey: "guess"}, http.StatusUnauthorized},
+		"header for another host":  {tenantRequest{host: "north.example.org", tenant: "south"}, http.StatusForbidden},
+		"API key for another host": {tenantRequest{host: "north.example.org", apiKey: "south-key"}, http.StatusForbidden},
+	} {
+		assert.Equal(t, r.status, r.request.send(router, http.MethodGet, "/api/books", "").Code, name)
+	}
+}
+
+func TestUndoNeedsTheCredentialOfTheChange(t *testing.T) {
+	router := setupTenantRouter(t)
+	send := func(apiKey, method, path, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Host = "north.example.org"
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("X-API-Key", apiKey)
+		req.Header.Set("X-Actor", "alice")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	typo := strings.Replace(sampleMARCXML, "Pride and prejudice /", "Pride and prejudize /", 1)
+	require.Equal(t, http.StatusOK, send("north-key", http.MethodPost, "/api/books/import", "application/marcxml+xml", sampleMARCXML).Code)
+	require.Equal(t, http.StatusOK, send("north-key", http.MethodPost, "/api/books/import", "application/marcxml+xml", typo).Code)
+
+	var audit struct {
+		Entries []model.AuditEntry `json:"entries"`
+	}
+	req := adminRequest(http.MethodGet, "/api/admin/audit")
+	req.Host = "north.example.org"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &audit))
+	require.NotEmpty(t, audit.Entries)
+	credential := audit.Entries[0].Credential
+	assert.True(t, strings.HasPrefix(credential, "api-key:"), credential)
+	assert.NotContains(t, credential, "north-key", "the key itself is not stored")
+	assert.Equal(t, credential, audit.Entries[0].Actor, "X-Actor is not taken when an API key authenticates the request")
+
+	assert.Equal(t, http.StatusNotFound, send("north-desk-key", http.MethodPost, "/api/undo", "application/json", "").Code,
+		"claiming to be alice with another key undoes nothing")
+	assert.Equal(t, "Pride and prejudice", bookTitle(t, send("north-key", http.MethodPost, "/api/undo", "application/json", "")))
+}
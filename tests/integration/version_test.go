@@ -0,0 +1,109 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brianantony456/go-doc/internal/domain/repository"
+	"github.com/brianantony456/go-doc/internal/infrastructure/gin_handler"
+	appRouter "github.com/brianantony456/go-doc/internal/router"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnversionedAPIIsADeprecatedAlias(t *testing.T) {
+	router, _, err := appRouter.SetupRouter(appRouter.Config{Database: filepath.Join(t.TempDir(), "books.db"), AdminKeys: []string{testAdminKey}})
+	require.NoError(t, err)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, adminRequest(http.MethodGet, path))
+		return w
+	}
+
+	w := get("/api/v1/books")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Deprecation"))
+
+	for i := 0; i < 2; i++ {
+		w = get("/api/books/missing")
+		assert.Equal(t, http.StatusNotFound, w.Code, "the alias answers like v1")
+		assert.Regexp(t, `^@\d+$`, w.Header().Get("Deprecation"))
+		assert.Equal(t, `</api/v1/books/missing>; rel="successor-version"`, w.Header().Get("Link"))
+		assert.Empty(t, w.Header().Get("Sunset"))
+	}
+
+	var uses []gin_handler.DeprecatedUse
+	require.NoError(t, json.Unmarshal(get("/api/v1/admin/deprecations").Body.Bytes(), &uses))
+	require.Len(t, uses, 1)
+	assert.Equal(t, "/api/books/:id", uses[0].Route)
+	assert.Equal(t, http.MethodGet, uses[0].Method)
+	assert.EqualValues(t, 2, uses[0].Count)
+}
+
+func TestUnversionedAPIPastSunset(t *testing.T) {
+	sunset := time.Now().Add(-time.Hour)
+	router, _, err := appRouter.SetupRouter(appRouter.Config{
+		Database:          filepath.Join(t.TempDir(), "books.db"),
+		UnversionedSunset: sunset,
+	})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/books", nil))
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Equal(t, sunset.UTC().Format(http.TimeFormat), w.Header().Get("Sunset"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/books", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAPIVersionsSideBySide(t *testing.T) {
+	usage := gin_handler.NewDeprecationUsage()
+	shared := func(c *gin.Context) { c.String(http.StatusOK, "shared") }
+	router := gin.New()
+	appRouter.MountAPI(router.Group("/api"), []appRouter.APIVersion{
+		{
+			Name: "v1",
+			Register: func(api *gin.RouterGroup) {
+				api.GET("/books", func(c *gin.Context) { c.String(http.StatusOK, "v1 books") })
+				api.GET("/tenant", shared)
+			},
+			Deprecation: &gin_handler.Deprecation{Since: time.Now(), Sunset: time.Now().Add(24 * time.Hour), Successor: "/api/v2"},
+		},
+		{
+			Name: "v2",
+			Register: func(api *gin.RouterGroup) {
+				api.GET("/books", func(c *gin.Context) { c.String(http.StatusOK, "v2 books") })
+				api.GET("/tenant", shared)
+			},
+		},
+	}, "v2", gin_handler.Deprecation{Since: time.Now()}, usage)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+	w := get("/api/v1/books")
+	assert.Equal(t, "v1 books", w.Body.String())
+	assert.NotEmpty(t, w.Header().Get("Sunset"))
+	assert.Equal(t, `</api/v2/books>; rel="successor-version"`, w.Header().Get("Link"))
+
+	w = get("/api/v2/books")
+	assert.Equal(t, "v2 books", w.Body.String())
+	assert.Empty(t, w.Header().Get("Deprecation"))
+
+	w = get("/api/books")
+	assert.Equal(t, "v2 books", w.Body.String(), "the alias follows the current version")
+	assert.Equal(t, `</api/v2/books>; rel="successor-version"`, w.Header().Get("Link"))
+	assert.Equal(t, "shared", get("/api/v1/tenant").Body.String())
+
+	assert.Len(t, usage.Uses(repository.DefaultTenant), 3)
+}